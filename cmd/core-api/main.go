@@ -18,6 +18,9 @@ import (
 // @securityDefinitions.apikey oauthAccessToken
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey provisionerToken
+// @in header
+// @name Authorization
 // @securitydefinitions.oauth2.accessCode OAuth2AccessCode
 // @tokenUrl https://api.awanku.id/v1/auth/token
 // @authorizationUrl https://api.awanku.id/v1/auth/{provider}/connect
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/awanku/awanku/internal/coreapi"
+	"github.com/awanku/awanku/pkg/envelope"
+)
+
+// secrets-key manages master keys in SECRETS_KEY_FILE.
+// To rotate, generate a new key then rewrap all secrets with it, old keys can be removed from the file afterwards.
+func main() {
+	generate := flag.Bool("generate", false, "append a new master key to key file, it becomes the current key")
+	rotate := flag.Bool("rotate", false, "rewrap data keys of all secrets with the current master key")
+	flag.Parse()
+
+	if !*generate && !*rotate {
+		flag.Usage()
+		return
+	}
+
+	conf := &coreapi.Config{}
+	if err := conf.Load(); err != nil {
+		log.Panicln("failed to parse config from environment variable:", err)
+	}
+
+	if *generate {
+		id, err := envelope.GenerateLocalKey(conf.SecretsKeyFile)
+		if err != nil {
+			log.Panicln("failed to generate master key:", err)
+		}
+		log.Println("generated master key", id)
+	}
+
+	if *rotate {
+		rotated, err := coreapi.RotateSecretsMasterKey(conf)
+		if err != nil {
+			log.Panicln("failed to rotate master key:", err)
+		}
+		log.Println("rewrapped", rotated, "secrets")
+	}
+}
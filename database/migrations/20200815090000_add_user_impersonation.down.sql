@@ -0,0 +1,3 @@
+drop table user_impersonation_logs;
+alter table oauth_tokens drop column impersonator_user_id;
+alter table users drop column is_admin;
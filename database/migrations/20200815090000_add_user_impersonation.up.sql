@@ -0,0 +1,16 @@
+alter table users add column is_admin boolean not null default false;
+
+alter table oauth_tokens add column impersonator_user_id integer references users(id);
+
+create table user_impersonation_logs (
+    id serial4 primary key,
+    oauth_token_id integer not null,
+    actor_user_id integer not null references users(id),
+    user_id integer not null references users(id),
+    method varchar(10) not null,
+    path varchar(2000) not null,
+    requester_ip inet not null,
+    created_at timestamp with time zone not null default 'now()'
+);
+
+create index order_on_user_impersonation_logs on user_impersonation_logs(user_id, created_at desc);
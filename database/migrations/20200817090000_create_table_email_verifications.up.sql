@@ -0,0 +1,13 @@
+create table email_verifications (
+    id serial4 primary key,
+    purpose varchar(50) not null,
+    email varchar(500) not null,
+    code_hash bytea not null,
+    payload jsonb not null,
+    attempts integer not null default 0,
+    expires_at timestamp with time zone not null,
+    verified_at timestamp with time zone,
+    created_at timestamp with time zone not null default 'now()'
+);
+
+create index expires_at_on_email_verifications on email_verifications(expires_at);
@@ -0,0 +1,2 @@
+alter table users add column preferences jsonb not null default '{}';
+alter table users add column pending_email varchar(500);
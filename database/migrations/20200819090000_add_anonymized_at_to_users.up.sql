@@ -0,0 +1 @@
+alter table users add column anonymized_at timestamp with time zone;
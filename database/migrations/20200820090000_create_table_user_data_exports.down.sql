@@ -0,0 +1,2 @@
+drop table user_data_exports;
+drop type data_export_status;
@@ -0,0 +1,20 @@
+create type data_export_status as enum ('pending', 'running', 'completed', 'failed');
+
+create table user_data_exports (
+    id serial4 primary key,
+    user_id integer not null references users(id),
+    format varchar(10) not null,
+    status data_export_status not null default 'pending',
+    progress integer not null default 0,
+    archive bytea,
+    error varchar(2000),
+    expires_at timestamp with time zone,
+    created_at timestamp with time zone not null default 'now()',
+    started_at timestamp with time zone,
+    completed_at timestamp with time zone
+);
+
+create index order_on_user_data_exports on user_data_exports(user_id, created_at desc);
+create index pending_user_data_exports on user_data_exports(created_at) where status = 'pending';
+-- only one unfinished export is allowed per user
+create unique index unique_unfinished_on_user_data_exports on user_data_exports(user_id) where status in ('pending', 'running');
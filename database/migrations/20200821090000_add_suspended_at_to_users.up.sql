@@ -0,0 +1 @@
+alter table users add column suspended_at timestamp with time zone;
@@ -0,0 +1,2 @@
+drop index project_on_resources;
+alter table resources drop column project_id;
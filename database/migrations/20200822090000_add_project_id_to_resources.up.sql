@@ -0,0 +1,3 @@
+alter table resources add column project_id integer references projects(id);
+
+create index project_on_resources on resources(project_id) where deleted_at is null;
@@ -0,0 +1,3 @@
+drop index unique_user_on_workspace;
+
+create unique index unique_user_on_workspace on workspace_users(workspace_id, user_id) where deleted_at is null;
@@ -0,0 +1,2 @@
+drop table workspace_invitations;
+drop type workspace_invitation_status;
@@ -0,0 +1,20 @@
+create type workspace_invitation_status as enum ('pending', 'accepted', 'revoked');
+
+create table workspace_invitations (
+    id serial4 primary key,
+    workspace_id integer not null references workspaces(id),
+    inviter_user_id integer not null references users(id),
+    email varchar(500) not null,
+    access_level workspace_access_level not null,
+    token_hash bytea not null,
+    status workspace_invitation_status not null default 'pending',
+    expires_at timestamp with time zone not null,
+    accepted_user_id integer references users(id),
+    accepted_at timestamp with time zone,
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone
+);
+
+create unique index unique_token_hash_on_workspace_invitations on workspace_invitations(token_hash);
+create unique index unique_pending_email_on_workspace_invitations on workspace_invitations(workspace_id, lower(email)) where status = 'pending';
+create index pending_email_on_workspace_invitations on workspace_invitations(lower(email)) where status = 'pending';
@@ -0,0 +1,2 @@
+drop table workspace_ownership_transfers;
+drop type workspace_ownership_transfer_status;
@@ -0,0 +1,14 @@
+create type workspace_ownership_transfer_status as enum ('pending', 'accepted', 'cancelled');
+
+create table workspace_ownership_transfers (
+    id serial4 primary key,
+    workspace_id integer not null references workspaces(id),
+    from_user_id integer not null references users(id),
+    to_user_id integer not null references users(id),
+    status workspace_ownership_transfer_status not null default 'pending',
+    accepted_at timestamp with time zone,
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone
+);
+
+create unique index unique_pending_on_workspace_ownership_transfers on workspace_ownership_transfers(workspace_id) where status = 'pending';
@@ -0,0 +1,23 @@
+drop index target_on_workspace_activity_logs;
+drop index actor_on_workspace_activity_logs;
+drop index order_on_workspace_activity_logs;
+
+alter table workspace_activity_logs add column payload varchar(1000);
+
+update workspace_activity_logs
+set payload = left((metadata || jsonb_build_object(
+    'action', verb,
+    'actor_id', actor_user_id,
+    'target_type', target_type,
+    'target_id', target_id
+))::text, 1000);
+
+alter table workspace_activity_logs alter column payload set not null;
+alter table workspace_activity_logs drop column metadata;
+alter table workspace_activity_logs drop column target_id;
+alter table workspace_activity_logs drop column target_type;
+alter table workspace_activity_logs drop column verb;
+alter table workspace_activity_logs drop column actor_user_id;
+alter table workspace_activity_logs drop column id;
+
+create index order_on_workspace_activity_logs on workspace_activity_logs(workspace_id, created_at desc);
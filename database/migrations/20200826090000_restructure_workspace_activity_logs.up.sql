@@ -0,0 +1,25 @@
+alter table workspace_activity_logs add column id bigserial primary key;
+alter table workspace_activity_logs add column actor_user_id integer references users(id);
+alter table workspace_activity_logs add column verb varchar(100);
+alter table workspace_activity_logs add column target_type varchar(100);
+alter table workspace_activity_logs add column target_id bigint;
+alter table workspace_activity_logs add column metadata jsonb not null default '{}';
+
+update workspace_activity_logs
+set
+    verb = coalesce(payload::jsonb->>'action', 'unknown'),
+    actor_user_id = (payload::jsonb->>'actor_id')::integer,
+    metadata = payload::jsonb - 'action' - 'actor_id'
+where payload like '{%';
+
+update workspace_activity_logs
+set verb = 'unknown', metadata = jsonb_build_object('payload', payload)
+where verb is null;
+
+alter table workspace_activity_logs alter column verb set not null;
+alter table workspace_activity_logs drop column payload;
+
+drop index order_on_workspace_activity_logs;
+create index order_on_workspace_activity_logs on workspace_activity_logs(workspace_id, id desc);
+create index actor_on_workspace_activity_logs on workspace_activity_logs(actor_user_id, id desc);
+create index target_on_workspace_activity_logs on workspace_activity_logs(workspace_id, target_type, target_id, id desc);
@@ -0,0 +1,6 @@
+alter table resources drop column memory_mb;
+alter table resources drop column cpu_millicores;
+
+alter table workspaces drop column plan;
+
+drop table plans;
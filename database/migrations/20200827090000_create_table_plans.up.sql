@@ -0,0 +1,24 @@
+-- null limit means unlimited
+create table plans (
+    code varchar(50) primary key,
+    name varchar(100) not null,
+    max_members integer,
+    max_projects integer,
+    max_resources_per_type integer,
+    max_repository_connections integer,
+    max_cpu_millicores integer,
+    max_memory_mb integer,
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone
+);
+
+insert into plans (code, name, max_members, max_projects, max_resources_per_type, max_repository_connections, max_cpu_millicores, max_memory_mb)
+values
+    ('free', 'Free', 3, 2, 2, 1, 1000, 1024),
+    ('pro', 'Pro', 25, 20, 20, 10, 16000, 32768),
+    ('enterprise', 'Enterprise', null, null, null, null, null, null);
+
+alter table workspaces add column plan varchar(50) not null default 'free' references plans(code);
+
+alter table resources add column cpu_millicores integer not null default 0;
+alter table resources add column memory_mb integer not null default 0;
@@ -0,0 +1,5 @@
+drop table workspace_slug_redirects;
+
+drop index unique_slug_on_workspaces;
+
+alter table workspaces drop column slug;
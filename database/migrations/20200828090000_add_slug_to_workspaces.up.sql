@@ -0,0 +1,18 @@
+alter table workspaces add column slug varchar(63);
+
+update workspaces
+set slug = rtrim(left(coalesce(nullif(trim(both '-' from regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g')), ''), 'workspace'), 50), '-') || '-' || id;
+
+alter table workspaces alter column slug set not null;
+
+create unique index unique_slug_on_workspaces on workspaces(slug) where deleted_at is null;
+
+-- previous slugs keep redirecting to their workspace until expired
+create table workspace_slug_redirects (
+    slug varchar(63) primary key,
+    workspace_id integer not null references workspaces(id),
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone not null default 'now()'
+);
+
+create index workspace_id_on_workspace_slug_redirects on workspace_slug_redirects(workspace_id);
@@ -0,0 +1,21 @@
+-- value is encrypted with its own data key, data key is wrapped by master key master_key_id
+create table secrets (
+    id serial4 primary key,
+    workspace_id integer not null references workspaces(id),
+    project_id integer references projects(id),
+    name varchar(255) not null,
+    ciphertext bytea not null,
+    nonce bytea not null,
+    wrapped_key bytea not null,
+    master_key_id varchar(100) not null,
+    version integer not null default 1,
+    created_by_user_id integer references users(id),
+    updated_by_user_id integer references users(id),
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone
+);
+
+create unique index unique_name_on_workspace_secrets on secrets(workspace_id, name) where project_id is null and deleted_at is null;
+create unique index unique_name_on_project_secrets on secrets(project_id, name) where project_id is not null and deleted_at is null;
+create index master_key_id_on_secrets on secrets(master_key_id);
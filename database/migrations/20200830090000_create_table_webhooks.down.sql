@@ -0,0 +1,7 @@
+drop table webhook_delivery_attempts;
+
+drop table webhook_deliveries;
+
+drop type webhook_delivery_status;
+
+drop table webhooks;
@@ -0,0 +1,51 @@
+-- signing secret is encrypted the same way as secrets
+create table webhooks (
+    id serial4 primary key,
+    workspace_id integer not null references workspaces(id),
+    url varchar(2000) not null,
+    event_types text[] not null,
+    active boolean not null default true,
+    secret_ciphertext bytea not null,
+    secret_nonce bytea not null,
+    secret_wrapped_key bytea not null,
+    secret_master_key_id varchar(100) not null,
+    created_by_user_id integer references users(id),
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone
+);
+
+create index workspace_id_on_webhooks on webhooks(workspace_id) where deleted_at is null;
+create index secret_master_key_id_on_webhooks on webhooks(secret_master_key_id);
+
+create type webhook_delivery_status as enum ('pending', 'succeeded', 'failed');
+
+create table webhook_deliveries (
+    id bigserial primary key,
+    webhook_id integer not null references webhooks(id),
+    event_type varchar(100) not null,
+    payload jsonb not null,
+    status webhook_delivery_status not null default 'pending',
+    attempts integer not null default 0,
+    next_attempt_at timestamp with time zone,
+    last_attempt_at timestamp with time zone,
+    last_response_status integer,
+    redelivery_of bigint references webhook_deliveries(id) on delete set null,
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone
+);
+
+create index webhook_id_on_webhook_deliveries on webhook_deliveries(webhook_id, id desc);
+create index next_attempt_at_on_pending_webhook_deliveries on webhook_deliveries(next_attempt_at) where status = 'pending';
+
+create table webhook_delivery_attempts (
+    id bigserial primary key,
+    delivery_id bigint not null references webhook_deliveries(id) on delete cascade,
+    response_status integer,
+    response_body text,
+    error text,
+    duration_ms integer not null,
+    created_at timestamp with time zone not null default 'now()'
+);
+
+create index delivery_id_on_webhook_delivery_attempts on webhook_delivery_attempts(delivery_id);
@@ -0,0 +1,6 @@
+drop table invoices;
+
+drop table resource_metering_events;
+
+alter table plans drop column resource_hour_price;
+alter table plans drop column currency;
@@ -0,0 +1,34 @@
+-- price is in smallest unit of currency per started resource hour
+alter table plans add column currency varchar(3) not null default 'IDR';
+alter table plans add column resource_hour_price bigint not null default 0;
+
+update plans set resource_hour_price = 150 where code = 'pro';
+update plans set resource_hour_price = 100 where code = 'enterprise';
+
+-- workspace is kept on the event so usage stays billed to workspace owning the resource at that time
+create table resource_metering_events (
+    id bigserial primary key,
+    resource_id integer not null references resources(id),
+    workspace_id integer not null references workspaces(id),
+    previous_state resource_state not null,
+    state resource_state not null,
+    occurred_at timestamp with time zone not null default 'now()'
+);
+
+create index workspace_id_on_resource_metering_events on resource_metering_events(workspace_id, occurred_at);
+
+create table invoices (
+    id serial4 primary key,
+    workspace_id integer not null references workspaces(id),
+    number varchar(50) not null,
+    period_start date not null,
+    period_end date not null,
+    plan varchar(50) not null references plans(code),
+    currency varchar(3) not null,
+    line_items jsonb not null,
+    total bigint not null,
+    created_at timestamp with time zone not null default 'now()'
+);
+
+create unique index unique_number_on_invoices on invoices(number);
+create unique index unique_period_on_invoices on invoices(workspace_id, period_start);
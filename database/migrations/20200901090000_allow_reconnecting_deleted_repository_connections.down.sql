@@ -0,0 +1,2 @@
+drop index unique_repository_provider_per_workspace;
+create unique index unique_repository_provider_per_workspace on workspace_repository_connections(workspace_id, provider, identifier);
@@ -0,0 +1,3 @@
+-- deleted connections stay in trash, the same repository can be connected again meanwhile
+drop index unique_repository_provider_per_workspace;
+create unique index unique_repository_provider_per_workspace on workspace_repository_connections(workspace_id, provider, identifier) where deleted_at is null;
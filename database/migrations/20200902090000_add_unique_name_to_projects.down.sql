@@ -0,0 +1,2 @@
+drop index workspace_id_on_projects;
+drop index unique_name_on_projects;
@@ -0,0 +1,15 @@
+-- rename existing duplicates before enforcing unique names
+update projects
+set name = left(name, 180) || '-' || id
+where id in (
+    select id
+    from (
+        select id, row_number() over (partition by workspace_id, lower(name) order by id) as position
+        from projects
+        where deleted_at is null
+    ) ranked
+    where position > 1
+);
+
+create unique index unique_name_on_projects on projects(workspace_id, lower(name)) where deleted_at is null;
+create index workspace_id_on_projects on projects(workspace_id) where deleted_at is null;
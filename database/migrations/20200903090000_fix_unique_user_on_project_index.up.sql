@@ -0,0 +1,4 @@
+drop index unique_user_on_project;
+
+create unique index unique_user_on_project on project_users(project_id, user_id) where deleted_at is null;
+create index user_id_on_project_users on project_users(user_id) where deleted_at is null;
@@ -0,0 +1,7 @@
+drop index repository_connection_id_on_projects;
+
+alter table projects
+    drop column repository_connection_id,
+    drop column repository_name,
+    drop column repository_branch,
+    drop column repository_root_dir;
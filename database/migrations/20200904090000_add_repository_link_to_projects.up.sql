@@ -0,0 +1,7 @@
+alter table projects
+    add column repository_connection_id integer references workspace_repository_connections(id),
+    add column repository_name varchar(200),
+    add column repository_branch varchar(255),
+    add column repository_root_dir varchar(500);
+
+create index repository_connection_id_on_projects on projects(repository_connection_id) where repository_connection_id is not null;
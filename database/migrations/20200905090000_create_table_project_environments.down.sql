@@ -0,0 +1,14 @@
+drop index environment_on_resources;
+
+alter table resources drop constraint resources_environment_id_fkey;
+alter table resources drop column environment_id;
+
+alter table resources alter column project_id drop not null;
+
+insert into resources select * from quarantined_resources;
+insert into resource_logs select * from quarantined_resource_logs;
+drop table quarantined_resource_logs;
+drop table quarantined_resources;
+
+drop table project_environments;
+drop type project_environment_type;
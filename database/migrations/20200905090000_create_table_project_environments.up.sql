@@ -0,0 +1,48 @@
+create type project_environment_type as enum ('production', 'staging', 'preview');
+
+create table project_environments (
+    id serial4 primary key,
+    project_id integer not null references projects(id),
+    name varchar(100) not null,
+    type project_environment_type not null,
+    protected boolean not null default false,
+    branch varchar(255),
+    created_at timestamp with time zone not null default 'now()',
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone
+);
+
+create unique index unique_name_on_project_environments on project_environments(project_id, lower(name)) where deleted_at is null;
+create unique index unique_branch_on_project_environments on project_environments(project_id, branch) where branch is not null and deleted_at is null;
+-- referenced by resources so their environment always belongs to their project
+create unique index unique_project_on_project_environments on project_environments(id, project_id);
+
+insert into project_environments (project_id, name, type, protected, created_at)
+select id, 'production', 'production', true, created_at
+from projects;
+
+-- resources created before projects existed belong to no workspace, they are kept aside until an operator assigns them to a project
+create table quarantined_resources as select * from resources where project_id is null;
+create table quarantined_resource_logs as
+select * from resource_logs where resource_id in (select id from quarantined_resources);
+
+delete from resource_logs where resource_id in (select id from quarantined_resources);
+delete from resources where id in (select id from quarantined_resources);
+
+-- environment foreign key below is only checked when project is set
+alter table resources alter column project_id set not null;
+
+alter table resources add column environment_id integer;
+
+update resources
+set environment_id = project_environments.id
+from project_environments
+where project_environments.project_id = resources.project_id;
+
+alter table resources alter column environment_id set not null;
+
+alter table resources
+    add constraint resources_environment_id_fkey
+    foreign key (environment_id, project_id) references project_environments(id, project_id);
+
+create index environment_on_resources on resources(environment_id) where deleted_at is null;
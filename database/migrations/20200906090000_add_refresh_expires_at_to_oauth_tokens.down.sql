@@ -0,0 +1 @@
+alter table oauth_tokens drop column refresh_expires_at;
@@ -0,0 +1,6 @@
+alter table oauth_tokens add column refresh_expires_at timestamp with time zone;
+
+-- existing refresh tokens get default lifetime counted from their access token expiry
+update oauth_tokens set refresh_expires_at = expires_at + interval '30 days';
+
+alter table oauth_tokens alter column refresh_expires_at set not null;
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// userWorkspace represents workspace membership of a user
+type userWorkspace struct {
+	core.Workspace
+	AccessLevel string `json:"access_level"`
+}
+
+// userSession represents active access token of a user
+type userSession struct {
+	ID                 int64     `json:"id"`
+	RequesterIP        string    `json:"requester_ip"`
+	RequesterUserAgent string    `json:"requester_user_agent"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	Impersonated       bool      `json:"impersonated"`
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func searchUsers(ctx context.Context, param *searchUsersParam) ([]*core.User, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from users
+        where
+            deleted_at is null
+            and (
+                email ilike ?0
+                or name ilike ?0
+                or google_login_email ilike ?0
+                or github_login_username ilike ?0
+            )
+        order by id
+        limit ?1 offset ?2
+    `
+	pattern := "%" + likeEscaper.Replace(param.Query) + "%"
+
+	var users []*core.User
+	err := db.Query(&users, query, pattern, param.Limit, param.Offset)
+	if err != nil {
+		return []*core.User{}, err
+	}
+	return users, nil
+}
+
+func getUserByID(ctx context.Context, id int64) (*core.User, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from users
+        where id = ? and deleted_at is null
+    `
+	var returned core.User
+	err := db.Query(&returned, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if returned.ID == 0 {
+		return nil, nil
+	}
+	return &returned, nil
+}
+
+func getUserWorkspaces(ctx context.Context, userID int64) ([]*userWorkspace, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select workspaces.*, workspace_users.access_level
+        from workspaces
+        join workspace_users on workspaces.id = workspace_users.workspace_id
+        where
+            workspace_users.user_id = ?
+            and workspaces.deleted_at is null
+            and workspace_users.deleted_at is null
+        order by workspaces.id
+    `
+	var workspaces []*userWorkspace
+	err := db.Query(&workspaces, query, userID)
+	if err != nil {
+		return []*userWorkspace{}, err
+	}
+	return workspaces, nil
+}
+
+func getUserSessions(ctx context.Context, userID int64) ([]*userSession, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            id,
+            host(requester_ip) as requester_ip,
+            requester_user_agent,
+            expires_at,
+            impersonator_user_id is not null as impersonated
+        from oauth_tokens
+        where
+            user_id = ?
+            and deleted_at is null
+            and expires_at > now()
+        order by id desc
+    `
+	var sessions []*userSession
+	err := db.Query(&sessions, query, userID)
+	if err != nil {
+		return []*userSession{}, err
+	}
+	return sessions, nil
+}
+
+func setUserSuspended(ctx context.Context, user *core.User, suspended bool) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update users
+        set suspended_at = case when ? then coalesce(suspended_at, now()) else null end
+        where id = ?
+        returning suspended_at
+    `
+	return db.WriterQuery(user, query, suspended, user.ID)
+}
+
+func revokeUserTokens(ctx context.Context, userID int64) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with revoked as (
+            update oauth_tokens
+            set deleted_at = now()
+            where user_id = ? and deleted_at is null
+            returning 1
+        )
+        select count(*) as count from revoked
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, userID)
+	return returned.Count, err
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchUsers(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	for _, query := range []string{user.Email, user.Name, *user.GithubLoginUsername, *user.GoogleLoginEmail} {
+		users, err := searchUsers(ctx, &searchUsersParam{Query: query, Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, users, 1) {
+			assert.Equal(t, user.ID, users[0].ID)
+		}
+	}
+
+	users, err := searchUsers(ctx, &searchUsersParam{Query: "%", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, users, 0)
+}
+
+func TestSetUserSuspended(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	err := setUserSuspended(ctx, user, true)
+	assert.NoError(t, err)
+	assert.True(t, user.IsSuspended())
+
+	err = setUserSuspended(ctx, user, false)
+	assert.NoError(t, err)
+	assert.False(t, user.IsSuspended())
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	testutil.OauthTokenFactory(ctx, user.ID, "secret")
+	testutil.OauthTokenFactory(ctx, user.ID, "secret")
+
+	sessions, err := getUserSessions(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	revoked, err := revokeUserTokens(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+
+	sessions, err = getUserSessions(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 0)
+}
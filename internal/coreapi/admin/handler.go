@@ -0,0 +1,246 @@
+package admin
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+type revokeTokensResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// @Id api.v1.admin.users.search
+// @Summary Search users by email, name or provider identifier
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param queryParam query searchUsersParam false "Query param"
+// @Router /v1/admin/users [get]
+// @Produce json
+// @Success 200 {array} core.User
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	param := &searchUsersParam{
+		Query:  r.URL.Query().Get("q"),
+		Limit:  20,
+		Offset: 0,
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		param.Limit, _ = strconv.Atoi(limit)
+	}
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		param.Offset, _ = strconv.Atoi(offset)
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	users, err := searchUsers(r.Context(), param)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if users == nil {
+		users = []*core.User{}
+	}
+	apihelper.JSON(w, http.StatusOK, users)
+}
+
+// @Id api.v1.admin.users.get
+// @Summary Get user
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id} [get]
+// @Produce json
+// @Success 200 {object} core.User
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, user)
+}
+
+// @Id api.v1.admin.users.workspaces
+// @Summary List workspaces of a user
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/workspaces [get]
+// @Produce json
+// @Success 200 {array} userWorkspace
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListUserWorkspaces(w http.ResponseWriter, r *http.Request) {
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	workspaces, err := getUserWorkspaces(r.Context(), user.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if workspaces == nil {
+		workspaces = []*userWorkspace{}
+	}
+	apihelper.JSON(w, http.StatusOK, workspaces)
+}
+
+// @Id api.v1.admin.users.sessions
+// @Summary List active sessions of a user
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/sessions [get]
+// @Produce json
+// @Success 200 {array} userSession
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := getUserSessions(r.Context(), user.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []*userSession{}
+	}
+	apihelper.JSON(w, http.StatusOK, sessions)
+}
+
+// @Id api.v1.admin.users.suspend
+// @Summary Suspend user, suspended user can not use the API
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/suspend [post]
+// @Produce json
+// @Success 200 {object} core.User
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	handleSetSuspended(w, r, true)
+}
+
+// @Id api.v1.admin.users.unsuspend
+// @Summary Lift user suspension
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/unsuspend [post]
+// @Produce json
+// @Success 200 {object} core.User
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	handleSetSuspended(w, r, false)
+}
+
+func handleSetSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	actor := appctx.AuthenticatedUser(r.Context())
+
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == actor.ID {
+		apihelper.ValidationErrResp(w, map[string]string{
+			"user_id": "can not change own suspension",
+		})
+		return
+	}
+
+	if err := setUserSuspended(r.Context(), user, suspended); err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	log.Printf("admin action: actor_id=%d user_id=%d suspended=%t", actor.ID, user.ID, suspended)
+
+	apihelper.JSON(w, http.StatusOK, user)
+}
+
+// @Id api.v1.admin.users.revokeTokens
+// @Summary Revoke every token of a user
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/revoke-tokens [post]
+// @Produce json
+// @Success 200 {object} revokeTokensResponse
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleRevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	actor := appctx.AuthenticatedUser(r.Context())
+
+	user, ok := loadUser(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := revokeUserTokens(r.Context(), user.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	log.Printf("admin action: actor_id=%d user_id=%d revoked_tokens=%d", actor.ID, user.ID, revoked)
+
+	apihelper.JSON(w, http.StatusOK, revokeTokensResponse{Revoked: revoked})
+}
+
+func loadUser(w http.ResponseWriter, r *http.Request) (*core.User, bool) {
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+	if userID <= 0 {
+		apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+			"user_id": "invalid",
+		})
+		return nil, false
+	}
+
+	user, err := getUserByID(r.Context(), userID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return nil, false
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type searchUsersParam struct {
+	// Query matches part of email, name, google login email or github username
+	Query  string `json:"q"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+func (p searchUsersParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Query, validation.Length(0, 500)),
+		validation.Field(&p.Limit, validation.Min(1), validation.Max(100)),
+		validation.Field(&p.Offset, validation.Min(0)),
+	)
+}
@@ -5,6 +5,7 @@ import (
 
 	hansip "github.com/asasmoyo/pq-hansip"
 	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/mailer"
 )
 
 // Key context key
@@ -16,8 +17,14 @@ const (
 	KeyDatabase          Key = "database"
 	KeyDatabaseTx        Key = "database_transaction"
 	KeyAuthenticatedUser Key = "authenticated_user"
+	KeyImpersonator      Key = "impersonator"
 	KeyCurrentWorkspace  Key = "current_workspace"
+	KeyWorkspaceAccess   Key = "workspace_access_level"
+	KeyCurrentProject    Key = "current_project"
+	KeyProjectAccess     Key = "project_access_level"
+	KeyCurrentProjectEnv Key = "current_project_environment"
 	KeyGithubAppConfig   Key = "github_app_config"
+	KeyMailer            Key = "mailer"
 )
 
 // Environment fetch environment name from context
@@ -65,6 +72,25 @@ func AuthenticatedUser(ctx context.Context) *core.User {
 	return nil
 }
 
+// Impersonator fetch platform admin impersonating authenticated user from context,
+// returns nil when request is not made using impersonation token
+func Impersonator(ctx context.Context) *core.User {
+	raw := ctx.Value(KeyImpersonator)
+	if val, ok := raw.(*core.User); ok {
+		return val
+	}
+	return nil
+}
+
+// Actor fetch user who actually makes the request from context.
+// This is the impersonator when impersonating, otherwise authenticated user.
+func Actor(ctx context.Context) *core.User {
+	if impersonator := Impersonator(ctx); impersonator != nil {
+		return impersonator
+	}
+	return AuthenticatedUser(ctx)
+}
+
 // CurrentWorkspace fetch current workspace from context
 func CurrentWorkspace(ctx context.Context) *core.Workspace {
 	raw := ctx.Value(KeyCurrentWorkspace)
@@ -74,6 +100,42 @@ func CurrentWorkspace(ctx context.Context) *core.Workspace {
 	return nil
 }
 
+// WorkspaceAccessLevel fetch authenticated user access level on current workspace from context
+func WorkspaceAccessLevel(ctx context.Context) string {
+	raw := ctx.Value(KeyWorkspaceAccess)
+	if val, ok := raw.(string); ok {
+		return val
+	}
+	return ""
+}
+
+// CurrentProject fetch current project from context
+func CurrentProject(ctx context.Context) *core.Project {
+	raw := ctx.Value(KeyCurrentProject)
+	if val, ok := raw.(*core.Project); ok {
+		return val
+	}
+	return nil
+}
+
+// ProjectAccessLevel fetch authenticated user effective access level on current project from context
+func ProjectAccessLevel(ctx context.Context) string {
+	raw := ctx.Value(KeyProjectAccess)
+	if val, ok := raw.(string); ok {
+		return val
+	}
+	return ""
+}
+
+// CurrentProjectEnvironment fetch current project environment from context
+func CurrentProjectEnvironment(ctx context.Context) *core.ProjectEnvironment {
+	raw := ctx.Value(KeyCurrentProjectEnv)
+	if val, ok := raw.(*core.ProjectEnvironment); ok {
+		return val
+	}
+	return nil
+}
+
 // GithubAppConfig fetch github app config from context
 func GithubAppConfig(ctx context.Context) *core.GithubAppConfig {
 	raw := ctx.Value(KeyGithubAppConfig)
@@ -82,3 +144,12 @@ func GithubAppConfig(ctx context.Context) *core.GithubAppConfig {
 	}
 	return nil
 }
+
+// Mailer fetch mailer from context
+func Mailer(ctx context.Context) mailer.Mailer {
+	raw := ctx.Value(KeyMailer)
+	if val, ok := raw.(mailer.Mailer); ok {
+		return val
+	}
+	return nil
+}
@@ -6,12 +6,14 @@ import (
 
 	hansip "github.com/asasmoyo/pq-hansip"
 	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/mailer"
 )
 
 type Config struct {
 	Environment     string
 	DB              *hansip.Cluster
 	GithubAppConfig *core.GithubAppConfig
+	Mailer          mailer.Mailer
 }
 
 // Middleware inject stuff into request context
@@ -21,6 +23,7 @@ func Middleware(config Config) func(http.Handler) http.Handler {
 			ctx := context.WithValue(r.Context(), KeyEnvironment, config.Environment)
 			ctx = context.WithValue(ctx, KeyDatabase, config.DB)
 			ctx = context.WithValue(ctx, KeyGithubAppConfig, config.GithubAppConfig)
+			ctx = context.WithValue(ctx, KeyMailer, config.Mailer)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -7,10 +7,13 @@ import (
 	"time"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace"
+	"github.com/awanku/awanku/internal/coreapi/workspace/invitation"
 	"github.com/awanku/awanku/pkg/core"
 )
 
 var errOauthTokenExpired = errors.New("oauth token expired")
+var errUserDeleted = errors.New("user has been deleted")
 
 func getUserByID(ctx context.Context, id int64) (*core.User, error) {
 	db := appctx.Database(ctx)
@@ -72,6 +75,26 @@ func getOauthTokenByID(ctx context.Context, id int64) (*core.OauthToken, error)
 	return &returned, nil
 }
 
+// getOauthTokenForRefresh returns token regardless of access token expiry as long as its refresh token has not expired
+func getOauthTokenForRefresh(ctx context.Context, id int64) (*core.OauthToken, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from oauth_tokens
+        where id = ? and deleted_at is null and refresh_expires_at > now()
+    `
+	var returned core.OauthToken
+	err := db.Query(&returned, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if returned.ID == 0 {
+		return nil, nil
+	}
+	return &returned, nil
+}
+
 func deleteOauthToken(ctx context.Context, id int64) error {
 	db := appctx.Database(ctx)
 
@@ -87,14 +110,14 @@ func saveOauthToken(ctx context.Context, token *core.OauthToken) error {
 	db := appctx.Database(ctx)
 
 	var query = `
-        insert into oauth_tokens (user_id, access_token_hash, refresh_token_hash, expires_at, requester_ip, requester_user_agent)
-        values (?, ?, ?, ?, ?, ?)
+        insert into oauth_tokens (user_id, access_token_hash, refresh_token_hash, expires_at, refresh_expires_at, requester_ip, requester_user_agent, impersonator_user_id)
+        values (?, ?, ?, ?, ?, ?, ?, ?)
         returning id
     `
 	var returned struct {
 		ID int64
 	}
-	err := db.WriterQuery(&returned, query, token.UserID, token.AccessTokenHash, token.RefreshTokenHash, token.ExpiresAt, token.RequesterIP, token.RequesterUserAgent)
+	err := db.WriterQuery(&returned, query, token.UserID, token.AccessTokenHash, token.RefreshTokenHash, token.ExpiresAt, token.RefreshExpiresAt, token.RequesterIP, token.RequesterUserAgent, token.ImpersonatorUserID)
 	if err != nil {
 		return err
 	}
@@ -102,6 +125,27 @@ func saveOauthToken(ctx context.Context, token *core.OauthToken) error {
 	return nil
 }
 
+func saveImpersonationLog(ctx context.Context, entry *core.ImpersonationLog) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        insert into user_impersonation_logs (oauth_token_id, actor_user_id, user_id, method, path, requester_ip, created_at)
+        values (?, ?, ?, ?, ?, ?, now())
+        returning id, created_at
+    `
+	var returned struct {
+		ID        int64
+		CreatedAt time.Time
+	}
+	err := db.WriterQuery(&returned, query, entry.OauthTokenID, entry.ActorUserID, entry.UserID, entry.Method, entry.Path, entry.RequesterIP)
+	if err != nil {
+		return err
+	}
+	entry.ID = returned.ID
+	entry.CreatedAt = returned.CreatedAt
+	return nil
+}
+
 func registerUser(ctx context.Context, user *core.User, authorizationCode string) error {
 	ctx = appctx.CreateDatabaseTx(ctx)
 	defer func() {
@@ -121,6 +165,9 @@ func registerUser(ctx context.Context, user *core.User, authorizationCode string
 		if err := createWorkspace(ctx, user); err != nil {
 			return err
 		}
+		if err := claimWorkspaceInvitations(ctx, user); err != nil {
+			return err
+		}
 	}
 
 	return saveOauthAuthorizationCode(ctx, user.ID, authorizationCode)
@@ -138,6 +185,7 @@ func getOrCreateUserByEmail(ctx context.Context, user *core.User) (err error) {
         insert into users (name, email, google_login_email, github_login_username)
         values (?, ?, ?, ?)
         on conflict (email) do update set updated_at = now()
+        where users.deleted_at is null
         returning id, created_at, updated_at
     `
 	var returned struct {
@@ -149,6 +197,10 @@ func getOrCreateUserByEmail(ctx context.Context, user *core.User) (err error) {
 	if err != nil {
 		return err
 	}
+	// deleted users keep their email until anonymized, they can not sign in again
+	if returned.ID == 0 {
+		return errUserDeleted
+	}
 
 	user.ID = returned.ID
 	user.CreatedAt = returned.CreatedAt
@@ -165,13 +217,18 @@ func createWorkspace(ctx context.Context, user *core.User) (err error) {
 	}()
 
 	var name = fmt.Sprintf("%s's workspace", user.Name)
+	slug, err := workspace.GenerateSlug(tx, name)
+	if err != nil {
+		return err
+	}
+
 	var queryWorkspace = `
-        insert into workspaces (name, created_at)
-        values (?, now())
+        insert into workspaces (name, slug, created_at)
+        values (?, ?, now())
         returning id
     `
 	var returnedWorkspace struct{ ID int64 }
-	err = tx.Query(&returnedWorkspace, queryWorkspace, name)
+	err = tx.Query(&returnedWorkspace, queryWorkspace, name, slug)
 	if err != nil {
 		return err
 	}
@@ -184,6 +241,18 @@ func createWorkspace(ctx context.Context, user *core.User) (err error) {
 	return
 }
 
+// claimWorkspaceInvitations adds new user to workspaces which invited the user email before sign up
+func claimWorkspaceInvitations(ctx context.Context, user *core.User) (err error) {
+	tx := appctx.DatabaseTx(ctx)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	return invitation.ClaimPending(tx, user)
+}
+
 func saveOauthAuthorizationCode(ctx context.Context, userID int64, code string) (err error) {
 	tx := appctx.DatabaseTx(ctx)
 	defer func() {
@@ -199,3 +268,50 @@ func saveOauthAuthorizationCode(ctx context.Context, userID int64, code string)
 	err = tx.Exec(query, userID, code)
 	return
 }
+
+// PurgeExpiredOauthAuthorizationCodes deletes at most batchSize expired authorization codes
+func PurgeExpiredOauthAuthorizationCodes(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with deleted as (
+            delete from oauth_authorization_codes
+            where ctid in (
+                select ctid
+                from oauth_authorization_codes
+                where expires_at < now()
+                limit ?
+            )
+            returning 1
+        )
+        select count(*) as count from deleted
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, batchSize)
+	return returned.Count, err
+}
+
+// PurgeExpiredOauthTokens returns function which deletes at most batchSize tokens
+// whose access and refresh tokens have been expired for longer than retention
+func PurgeExpiredOauthTokens(retention time.Duration) func(ctx context.Context, batchSize int) (int64, error) {
+	return func(ctx context.Context, batchSize int) (int64, error) {
+		db := appctx.Database(ctx)
+
+		var query = `
+            with deleted as (
+                delete from oauth_tokens
+                where id in (
+                    select id
+                    from oauth_tokens
+                    where greatest(expires_at, refresh_expires_at) < now() - make_interval(secs => ?)
+                    limit ?
+                )
+                returning 1
+            )
+            select count(*) as count from deleted
+        `
+		var returned struct{ Count int64 }
+		err := db.WriterQuery(&returned, query, retention.Seconds(), batchSize)
+		return returned.Count, err
+	}
+}
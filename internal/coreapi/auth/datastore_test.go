@@ -164,3 +164,89 @@ func TestSaveOauthToken(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, retrieved.ID, token.ID)
 }
+
+func TestSaveImpersonationToken(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	users := testutil.UserFactory(ctx, 2)
+	admin, target := users[0], users[1]
+
+	token, err := core.BuildOauthToken([]byte("secret"), 10)
+	assert.NoError(t, err)
+
+	token.UserID = target.ID
+	token.ImpersonatorUserID = &admin.ID
+	token.RequesterIP = "127.0.0.1"
+	token.RequesterUserAgent = "testing"
+	err = saveOauthToken(ctx, token)
+	assert.NoError(t, err)
+
+	retrieved, err := getOauthTokenByID(ctx, token.ID)
+	assert.NoError(t, err)
+	assert.True(t, retrieved.IsImpersonation())
+	assert.Equal(t, admin.ID, *retrieved.ImpersonatorUserID)
+	assert.Empty(t, retrieved.Token().RefreshToken)
+}
+
+func TestSaveImpersonationLog(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	users := testutil.UserFactory(ctx, 2)
+	admin, target := users[0], users[1]
+	token := testutil.OauthTokenFactory(ctx, target.ID, "secret")
+
+	entry := &core.ImpersonationLog{
+		OauthTokenID: token.ID,
+		ActorUserID:  admin.ID,
+		UserID:       target.ID,
+		Method:       "GET",
+		Path:         "/v1/users/me",
+		RequesterIP:  "127.0.0.1",
+	}
+	err := saveImpersonationLog(ctx, entry)
+	assert.NoError(t, err)
+	assert.True(t, entry.ID > 0)
+	assert.False(t, entry.CreatedAt.IsZero())
+}
+
+func TestPurgeExpiredOauthAuthorizationCodes(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	expired := testutil.OauthAuthorizationCodeFactory(ctx, user.ID)
+	err := appctx.Database(ctx).WriterExec("update oauth_authorization_codes set expires_at = now() - interval '1 minute' where code = ?", expired.Code)
+	assert.NoError(t, err)
+	active := testutil.OauthAuthorizationCodeFactory(ctx, user.ID)
+
+	removed, err := PurgeExpiredOauthAuthorizationCodes(ctx, 1000)
+	assert.NoError(t, err)
+	assert.True(t, removed >= 1)
+
+	var count struct{ Count int64 }
+	err = appctx.Database(ctx).Query(&count, "select count(*) as count from oauth_authorization_codes where code in (?, ?)", expired.Code, active.Code)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Count)
+}
+
+func TestPurgeExpiredOauthTokens(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	expired := testutil.OauthTokenFactory(ctx, user.ID, "secret")
+	err := appctx.Database(ctx).WriterExec("update oauth_tokens set expires_at = now() - interval '2 hours' where id = ?", expired.ID)
+	assert.NoError(t, err)
+	active := testutil.OauthTokenFactory(ctx, user.ID, "secret")
+
+	removed, err := PurgeExpiredOauthTokens(time.Hour)(ctx, 1000)
+	assert.NoError(t, err)
+	assert.True(t, removed >= 1)
+
+	var count struct{ Count int64 }
+	err = appctx.Database(ctx).Query(&count, "select count(*) as count from oauth_tokens where id in (?, ?)", expired.ID, active.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Count)
+}
@@ -1,12 +1,18 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/emailverification"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
 	"github.com/awanku/awanku/pkg/core"
 	"github.com/go-chi/chi"
@@ -16,6 +22,8 @@ const oauthRedirectTo = "/oauth/callback"
 const oauthAuthorizationCodeLength = 20
 const oauthTokenLength = 20
 
+var errEmailNotVerified = errors.New("email is not verified")
+
 // @Id api.v1.auth.provider.connect
 // @Summary Auth provider connect
 // @Tags Auth
@@ -62,33 +70,164 @@ func HandleOauthProviderConnect(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} apihelper.HTTPError
 // @Failure 401 {object} apihelper.HTTPError
 // @Failure 500 {object} apihelper.InternalServerError
-func HandleOauthProviderCallback(w http.ResponseWriter, r *http.Request) {
-	environment := appctx.Environment(r.Context())
+func HandleOauthProviderCallback(oauthTokenSecretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		environment := appctx.Environment(r.Context())
 
-	param := &getProviderCallbackParam{
-		Provider: chi.URLParam(r, "provider"),
-		Code:     r.URL.Query().Get("code"),
-		State:    r.URL.Query().Get("state"),
-	}
-	if err := param.Validate(); err != nil {
-		apihelper.ValidationErrResp(w, err)
-		return
+		param := &getProviderCallbackParam{
+			Provider: chi.URLParam(r, "provider"),
+			Code:     r.URL.Query().Get("code"),
+			State:    r.URL.Query().Get("state"),
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		state, err := param.decodeState()
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		redirectTo := state["redirect_to"]
+
+		parsedRedirectTo, err := url.Parse(redirectTo)
+		if err != nil {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"state": "invalid",
+			})
+			return
+		}
+
+		authHandler := oauth2Provider(param.Provider, environment)
+		userData, err := authHandler.ExchangeCode(param.Code)
+		if err != nil {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"code": "invalid",
+			})
+			return
+		}
+
+		query := parsedRedirectTo.Query()
+
+		// unverified email must be confirmed before it is used to create or merge into an account
+		if !userData.EmailVerified {
+			verification := &core.EmailVerification{
+				Purpose: core.EmailVerificationPurposeSignup,
+				Email:   userData.Email,
+				Payload: signupVerificationPayload(userData, redirectTo),
+			}
+			if err := emailverification.Start(r.Context(), oauthTokenSecretKey, verification); err != nil {
+				apihelper.InternalServerErrResp(w, err)
+				return
+			}
+
+			query.Set("email_verification_id", strconv.FormatInt(verification.ID, 10))
+			parsedRedirectTo.RawQuery = query.Encode()
+			apihelper.RedirectResp(w, parsedRedirectTo.String())
+			return
+		}
+
+		authorizationCode, err := signup(r.Context(), userData)
+		if err == errUserDeleted {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"email": "account has been deleted",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		query.Set("code", authorizationCode)
+		parsedRedirectTo.RawQuery = query.Encode()
+
+		apihelper.RedirectResp(w, parsedRedirectTo.String())
 	}
+	return http.HandlerFunc(handler)
+}
 
-	state, err := param.decodeState()
-	if err != nil {
-		apihelper.InternalServerErrResp(w, err)
-		return
+type confirmEmailVerificationResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// @Id api.v1.auth.emailVerification.confirm
+// @Summary Confirm sign up email using code sent by email
+// @Tags Auth
+// @Accept json
+// @Param verification_id path integer true "Email verification id"
+// @Param param body confirmEmailVerificationParam true "Request body"
+// @Router /v1/auth/email-verifications/{verification_id} [post]
+// @Produce json
+// @Success 200 {object} confirmEmailVerificationResponse
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleConfirmEmailVerification(oauthTokenSecretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		verificationID, _ := strconv.ParseInt(chi.URLParam(r, "verification_id"), 10, 64)
+		if verificationID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"verification_id": "invalid",
+			})
+			return
+		}
+
+		var param confirmEmailVerificationParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		verification, err := emailverification.Confirm(r.Context(), oauthTokenSecretKey, verificationID, core.EmailVerificationPurposeSignup, param.Code)
+		if err == emailverification.ErrInvalidCode {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"code": "invalid",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		userData := signupVerificationUserData(verification)
+		authorizationCode, err := signup(r.Context(), userData)
+		if err == errUserDeleted {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"email": "account has been deleted",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		redirectTo, err := url.Parse(verification.Payload["redirect_to"])
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		query := redirectTo.Query()
+		query.Set("code", authorizationCode)
+		redirectTo.RawQuery = query.Encode()
+
+		apihelper.JSON(w, http.StatusOK, confirmEmailVerificationResponse{RedirectTo: redirectTo.String()})
 	}
-	redirectTo := state["redirect_to"]
+	return http.HandlerFunc(handler)
+}
 
-	authHandler := oauth2Provider(param.Provider, environment)
-	userData, err := authHandler.ExchangeCode(param.Code)
-	if err != nil {
-		apihelper.ValidationErrResp(w, map[string]string{
-			"code": "invalid",
-		})
-		return
+// signup creates or merges user using verified oauth user data and returns new authorization code
+func signup(ctx context.Context, userData *core.OauthUserData) (string, error) {
+	if !userData.EmailVerified {
+		return "", errEmailNotVerified
 	}
 
 	user := &core.User{
@@ -99,61 +238,84 @@ func HandleOauthProviderCallback(w http.ResponseWriter, r *http.Request) {
 
 	authorizationCode, err := core.BuildOauthAuthorizationCode(oauthAuthorizationCodeLength)
 	if err != nil {
-		apihelper.InternalServerErrResp(w, err)
-		return
+		return "", err
 	}
 
-	if err := registerUser(r.Context(), user, authorizationCode); err != nil {
-		apihelper.InternalServerErrResp(w, err)
-		return
+	if err := registerUser(ctx, user, authorizationCode); err != nil {
+		return "", err
 	}
+	return authorizationCode, nil
+}
 
-	parsedRedirectTo, err := url.Parse(redirectTo)
-	if err != nil {
-		apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
-			"state": "invalid",
-		})
-		return
+func signupVerificationPayload(userData *core.OauthUserData, redirectTo string) map[string]string {
+	return map[string]string{
+		"provider":    userData.Provider,
+		"name":        userData.Name,
+		"identifier":  userData.Identifier,
+		"redirect_to": redirectTo,
+	}
+}
+
+func signupVerificationUserData(verification *core.EmailVerification) *core.OauthUserData {
+	return &core.OauthUserData{
+		Provider:      verification.Payload["provider"],
+		Name:          verification.Payload["name"],
+		Email:         verification.Email,
+		EmailVerified: verification.VerifiedAt != nil,
+		Identifier:    verification.Payload["identifier"],
 	}
-	query := parsedRedirectTo.Query()
-	query.Set("code", authorizationCode)
-	parsedRedirectTo.RawQuery = query.Encode()
+}
+
+// tokenResponse represents successful token response as described in RFC 6749 section 5.1
+type tokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in"`
+	Expiry       time.Time `json:"expiry"`
+}
 
-	apihelper.RedirectResp(w, parsedRedirectTo.String())
+func newTokenResponse(token *core.OauthToken) tokenResponse {
+	t := token.Token()
+	return tokenResponse{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		ExpiresIn:    int64(time.Until(t.Expiry).Seconds()),
+		Expiry:       t.Expiry,
+	}
 }
 
 // @Id api.v1.auth.exchangeToken
 // @Summary Exchange authorization code for authentication token
 // @Tags Auth
+// @Accept x-www-form-urlencoded
 // @Accept json
 // @Param param body postTokenParam true "Request body"
 // @Router /v1/auth/token [post]
 // @Produce json
-// @Success 200 {object} oauth2.Token
-// @Failure 400 {object} apihelper.HTTPError
-// @Failure 401 {object} apihelper.HTTPError
+// @Success 200 {object} tokenResponse
+// @Failure 400 {object} oauthErrorResponse
+// @Failure 401 {object} oauthErrorResponse
 // @Failure 500 {object} apihelper.InternalServerError
-func HandleExchangeOauthToken(oauthTokenSecretKey []byte) http.HandlerFunc {
+func HandleExchangeOauthToken(oauthTokenSecretKey []byte, oauthClients map[string]string, refreshTokenLifetime time.Duration) http.HandlerFunc {
 	handler := func(w http.ResponseWriter, r *http.Request) {
-		if err := r.ParseForm(); err != nil {
-			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
-				"request_body": "malformed format",
-			})
-			return
-		}
-
 		param := postTokenParam{
 			oauthTokenSecretKey: oauthTokenSecretKey,
 		}
-		err := json.NewDecoder(r.Body).Decode(&param)
-		if err != nil {
-			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
-				"request_body": "malformed format",
-			})
+		if err := parsePostTokenParam(r, &param); err != nil {
+			oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidRequest, "malformed request body")
+			return
+		}
+		if !param.authenticateClient(oauthClients) {
+			if param.clientAuthenticatedByHeader {
+				w.Header().Set("WWW-Authenticate", `Basic realm="awanku"`)
+			}
+			oauthErrResp(w, http.StatusUnauthorized, oauthErrInvalidClient, "client authentication failed")
 			return
 		}
 		if err := param.Validate(r.Context()); err != nil {
-			apihelper.ValidationErrResp(w, err)
+			oauthValidationErrResp(w, err)
 			return
 		}
 
@@ -163,36 +325,121 @@ func HandleExchangeOauthToken(oauthTokenSecretKey []byte) http.HandlerFunc {
 			return
 		}
 
-		token.RequesterIP = r.Header.Get("X-Real-Ip")
-		if token.RequesterIP == "" {
-			parts := strings.Split(r.Header.Get("X-Forwarded-For"), " ")
-			if len(parts) > 0 {
-				token.RequesterIP = parts[len(parts)-1]
-			}
-		}
-		if token.RequesterIP == "" {
-			token.RequesterIP = "127.0.0.1"
-		}
-
+		token.RefreshExpiresAt = time.Now().Add(refreshTokenLifetime)
+		token.RequesterIP = requesterIP(r)
 		token.RequesterUserAgent = r.Header.Get("User-Agent")
 
 		switch param.GrantType {
 		case "refresh_token":
-			// if grant type is refresh_token, also delete old token
+			token.UserID = param.retrievedOauthToken.UserID
+		case "authorization_code":
+			token.UserID = param.retrievedCode.UserID
+		}
+
+		user, err := getUserByID(r.Context(), token.UserID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if user == nil || user.IsSuspended() {
+			oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidGrant, "user is suspended or deleted")
+			return
+		}
+
+		// if grant type is refresh_token, also delete old token
+		if param.GrantType == "refresh_token" {
 			if err := deleteOauthToken(r.Context(), param.retrievedOauthToken.ID); err != nil {
 				apihelper.InternalServerErrResp(w, err)
 				return
 			}
-			token.UserID = param.retrievedOauthToken.UserID
-		case "authorization_code":
-			token.UserID = param.retrievedCode.UserID
 		}
 
 		if err := saveOauthToken(r.Context(), token); err != nil {
 			apihelper.InternalServerErrResp(w, err)
 			return
 		}
-		apihelper.JSON(w, http.StatusOK, token.Token())
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		apihelper.JSON(w, http.StatusOK, newTokenResponse(token))
+	}
+	return http.HandlerFunc(handler)
+}
+
+// @Id api.v1.admin.users.impersonate
+// @Summary Issue short-lived token for acting as another user
+// @Tags Admin
+// @Security oauthAccessToken
+// @Param user_id path integer true "User id"
+// @Router /v1/admin/users/{user_id}/impersonate [post]
+// @Produce json
+// @Success 200 {object} tokenResponse
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleImpersonateUser(oauthTokenSecretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		actor := appctx.Actor(r.Context())
+
+		userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if userID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"user_id": "invalid",
+			})
+			return
+		}
+
+		target, err := getUserByID(r.Context(), userID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if target == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if target.ID == actor.ID || target.IsAdmin {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"user_id": "can not impersonate platform admin",
+			})
+			return
+		}
+
+		token, err := core.BuildOauthToken(oauthTokenSecretKey, oauthTokenLength)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		token.UserID = target.ID
+		token.ImpersonatorUserID = &actor.ID
+		token.ExpiresAt = time.Now().Add(core.ImpersonationTokenMaxDuration)
+		token.RefreshExpiresAt = token.ExpiresAt
+		token.RequesterIP = requesterIP(r)
+		token.RequesterUserAgent = r.Header.Get("User-Agent")
+
+		if err := saveOauthToken(r.Context(), token); err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		log.Printf("impersonation token issued: token_id=%d actor_id=%d user_id=%d", token.ID, actor.ID, target.ID)
+
+		apihelper.JSON(w, http.StatusOK, newTokenResponse(token))
 	}
 	return http.HandlerFunc(handler)
 }
+
+func requesterIP(r *http.Request) string {
+	ip := r.Header.Get("X-Real-Ip")
+	if ip == "" {
+		parts := strings.Split(r.Header.Get("X-Forwarded-For"), " ")
+		if len(parts) > 0 {
+			ip = parts[len(parts)-1]
+		}
+	}
+	if ip == "" {
+		ip = "127.0.0.1"
+	}
+	return ip
+}
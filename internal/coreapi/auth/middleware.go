@@ -2,7 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -87,9 +89,111 @@ func OauthTokenValidatorMiddleware(oauthSecretKey []byte) func(http.Handler) htt
 				apihelper.InternalServerErrResp(w, err)
 				return
 			}
+			if user == nil {
+				apihelper.UnauthorizedAccessResp(w, "access_denied", map[string]string{
+					"access_token": "invalid",
+				})
+				return
+			}
+			if user.IsSuspended() {
+				apihelper.UnauthorizedAccessResp(w, "access_denied", map[string]string{
+					"user": "suspended",
+				})
+				return
+			}
 
 			ctx := context.WithValue(r.Context(), appctx.KeyAuthenticatedUser, user)
+
+			if storedToken.IsImpersonation() {
+				impersonator, err := getUserByID(r.Context(), *storedToken.ImpersonatorUserID)
+				if err != nil {
+					apihelper.InternalServerErrResp(w, err)
+					return
+				}
+				if impersonator == nil || !impersonator.IsAdmin {
+					apihelper.UnauthorizedAccessResp(w, "access_denied", map[string]string{
+						"access_token": "invalid",
+					})
+					return
+				}
+
+				entry := &core.ImpersonationLog{
+					OauthTokenID: storedToken.ID,
+					ActorUserID:  impersonator.ID,
+					UserID:       user.ID,
+					Method:       r.Method,
+					Path:         r.URL.RequestURI(),
+					RequesterIP:  requesterIP(r),
+				}
+				if err := saveImpersonationLog(r.Context(), entry); err != nil {
+					apihelper.InternalServerErrResp(w, err)
+					return
+				}
+				log.Printf("impersonated request: actor_id=%d user_id=%d %s %s", impersonator.ID, user.ID, r.Method, entry.Path)
+
+				ctx = context.WithValue(ctx, appctx.KeyImpersonator, impersonator)
+			}
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// PlatformAdminMiddleware only allows platform admins, impersonation tokens are always rejected
+func PlatformAdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if appctx.Impersonator(r.Context()) != nil {
+			apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+				"access_token": "impersonation token is not allowed",
+			})
+			return
+		}
+
+		user := appctx.AuthenticatedUser(r.Context())
+		if user == nil || !user.IsAdmin {
+			apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+				"user": "platform admin only",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DenyImpersonationMiddleware rejects requests made using impersonation token,
+// use it to guard destructive operations
+func DenyImpersonationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if appctx.Impersonator(r.Context()) != nil {
+			apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+				"access_token": "operation is not allowed using impersonation token",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServiceTokenMiddleware authenticates internal service such as provisioner using its own static bearer token,
+// user tokens are not accepted and every request is rejected when token is not configured
+func ServiceTokenMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headerParts := strings.Split(r.Header.Get("authorization"), " ")
+			if len(headerParts) != 2 || strings.ToLower(headerParts[0]) != "bearer" {
+				apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+					"authorization_header": "malformed format",
+				})
+				return
+			}
+
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(headerParts[1])) != 1 {
+				apihelper.UnauthorizedAccessResp(w, "access_denied", map[string]string{
+					"service_token": "invalid",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
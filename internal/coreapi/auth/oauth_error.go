@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// error codes defined in RFC 6749 section 5.2
+const (
+	oauthErrInvalidRequest       = "invalid_request"
+	oauthErrInvalidClient        = "invalid_client"
+	oauthErrInvalidGrant         = "invalid_grant"
+	oauthErrUnsupportedGrantType = "unsupported_grant_type"
+)
+
+// oauthErrorResponse represents token endpoint error response as described in RFC 6749
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func oauthErrResp(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	apihelper.JSON(w, status, oauthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+// oauthValidationErrResp translates postTokenParam validation errors into RFC 6749 error response
+func oauthValidationErrResp(w http.ResponseWriter, err error) {
+	var internalErr validation.InternalError
+	if errors.As(err, &internalErr) {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidRequest, err.Error())
+		return
+	}
+
+	if fieldErr, ok := errs["grant_type"]; ok {
+		if isRequiredErr(fieldErr) {
+			oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidRequest, "grant_type is required")
+			return
+		}
+		oauthErrResp(w, http.StatusBadRequest, oauthErrUnsupportedGrantType, "grant_type is not supported")
+		return
+	}
+
+	for _, field := range []string{"code", "refresh_token"} {
+		fieldErr, ok := errs[field]
+		if !ok {
+			continue
+		}
+		if isRequiredErr(fieldErr) {
+			oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidRequest, field+" is required")
+			return
+		}
+		oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidGrant, field+" is invalid or expired")
+		return
+	}
+
+	oauthErrResp(w, http.StatusBadRequest, oauthErrInvalidRequest, err.Error())
+}
+
+func isRequiredErr(err error) bool {
+	validationErr, ok := err.(validation.Error)
+	return ok && validationErr.Code() == validation.ErrRequired.Code()
+}
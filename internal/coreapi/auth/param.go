@@ -2,9 +2,13 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"mime"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -70,6 +74,10 @@ type postTokenParam struct {
 	GrantType    string `json:"grant_type" schema:"grant_type" validate:"required"`
 	Code         string `json:"code" schema:"code"`
 	RefreshToken string `json:"refresh_token" schema:"refresh_token"`
+	ClientID     string `json:"client_id" schema:"client_id"`
+	ClientSecret string `json:"client_secret" schema:"client_secret"`
+
+	clientAuthenticatedByHeader bool
 
 	retrievedCode       *core.OauthAuthorizationCode
 	retrievedOauthToken *core.OauthToken
@@ -77,6 +85,61 @@ type postTokenParam struct {
 	oauthTokenSecretKey []byte
 }
 
+var errMultipleClientAuthentication = errors.New("multiple client authentication methods")
+
+// parsePostTokenParam reads token request from either url encoded form or JSON body,
+// client credentials may also be passed using HTTP Basic authentication
+func parsePostTokenParam(r *http.Request, p *postTokenParam) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return err
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+		p.GrantType = r.PostForm.Get("grant_type")
+		p.Code = r.PostForm.Get("code")
+		p.RefreshToken = r.PostForm.Get("refresh_token")
+		p.ClientID = r.PostForm.Get("client_id")
+		p.ClientSecret = r.PostForm.Get("client_secret")
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	if p.ClientSecret != "" {
+		return errMultipleClientAuthentication
+	}
+
+	// RFC 6749 section 2.3.1 requires credentials to be url encoded before being put into basic auth header
+	var err error
+	if p.ClientID, err = url.QueryUnescape(clientID); err != nil {
+		return err
+	}
+	if p.ClientSecret, err = url.QueryUnescape(clientSecret); err != nil {
+		return err
+	}
+	p.clientAuthenticatedByHeader = true
+	return nil
+}
+
+// authenticateClient checks client credentials against registered clients.
+// Requests without client id are treated as coming from public client.
+func (p *postTokenParam) authenticateClient(clients map[string]string) bool {
+	if p.ClientID == "" {
+		return p.ClientSecret == ""
+	}
+	secret, ok := clients[p.ClientID]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(p.ClientSecret)) == 1
+}
+
 func (p *postTokenParam) Validate(ctx context.Context) error {
 	return validation.ValidateStruct(p,
 		validation.Field(&p.GrantType, validation.Required, validation.In("authorization_code", "refresh_token")),
@@ -131,11 +194,17 @@ func (p *postTokenParam) validateRefreshToken(ctx context.Context) validation.Ru
 			return errors.New("invalid")
 		}
 
-		tokenID, _ := strconv.ParseInt(tokenIDStr, 10, 64)
-		p.retrievedOauthToken, err = getOauthTokenByID(ctx, tokenID)
+		tokenID, err := strconv.ParseInt(tokenIDStr, 10, 64)
+		if err != nil || tokenID <= 0 {
+			return errors.New("invalid")
+		}
+		p.retrievedOauthToken, err = getOauthTokenForRefresh(ctx, tokenID)
 		if err != nil {
 			return validation.NewInternalError(err)
 		}
+		if p.retrievedOauthToken == nil || p.retrievedOauthToken.IsImpersonation() {
+			return errors.New("invalid")
+		}
 
 		valid, err := core.ValidateHMAC(p.oauthTokenSecretKey, refreshTokenDecoded, p.retrievedOauthToken.RefreshTokenHash)
 		if err != nil {
@@ -147,3 +216,13 @@ func (p *postTokenParam) validateRefreshToken(ctx context.Context) validation.Ru
 		return errors.New("invalid")
 	}
 }
+
+type confirmEmailVerificationParam struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (p confirmEmailVerificationParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePostTokenParam(t *testing.T) {
+	t.Run("url encoded form", func(t *testing.T) {
+		form := url.Values{}
+		form.Set("grant_type", "authorization_code")
+		form.Set("code", "abc")
+		r := httptest.NewRequest("POST", "/v1/auth/token", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var param postTokenParam
+		err := parsePostTokenParam(r, &param)
+		assert.NoError(t, err)
+		assert.Equal(t, "authorization_code", param.GrantType)
+		assert.Equal(t, "abc", param.Code)
+	})
+
+	t.Run("json body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/auth/token", strings.NewReader(`{"grant_type":"refresh_token","refresh_token":"1:abc"}`))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		var param postTokenParam
+		err := parsePostTokenParam(r, &param)
+		assert.NoError(t, err)
+		assert.Equal(t, "refresh_token", param.GrantType)
+		assert.Equal(t, "1:abc", param.RefreshToken)
+	})
+
+	t.Run("basic client authentication", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/auth/token", strings.NewReader("grant_type=authorization_code"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetBasicAuth("dashboard", url.QueryEscape("s3cret/+"))
+
+		var param postTokenParam
+		err := parsePostTokenParam(r, &param)
+		assert.NoError(t, err)
+		assert.Equal(t, "dashboard", param.ClientID)
+		assert.Equal(t, "s3cret/+", param.ClientSecret)
+		assert.True(t, param.clientAuthenticatedByHeader)
+	})
+
+	t.Run("multiple client authentication", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/v1/auth/token", strings.NewReader("grant_type=authorization_code&client_id=dashboard&client_secret=secret"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetBasicAuth("dashboard", "secret")
+
+		var param postTokenParam
+		err := parsePostTokenParam(r, &param)
+		assert.Equal(t, errMultipleClientAuthentication, err)
+	})
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	clients := map[string]string{"dashboard": "secret"}
+
+	assert.True(t, (&postTokenParam{}).authenticateClient(clients))
+	assert.True(t, (&postTokenParam{ClientID: "dashboard", ClientSecret: "secret"}).authenticateClient(clients))
+	assert.False(t, (&postTokenParam{ClientID: "dashboard", ClientSecret: "wrong"}).authenticateClient(clients))
+	assert.False(t, (&postTokenParam{ClientID: "unknown", ClientSecret: "secret"}).authenticateClient(clients))
+	assert.False(t, (&postTokenParam{ClientSecret: "secret"}).authenticateClient(clients))
+}
+
+func TestValidateRefreshTokenAfterAccessTokenExpiry(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	secretKey := "secret"
+	user := testutil.UserFactory(ctx, 1)[0]
+	token := testutil.OauthTokenFactory(ctx, user.ID, secretKey)
+	err := appctx.Database(ctx).WriterExec("update oauth_tokens set expires_at = now() - interval '1 hour' where id = ?", token.ID)
+	assert.NoError(t, err)
+
+	param := postTokenParam{
+		GrantType:           "refresh_token",
+		RefreshToken:        token.Token().RefreshToken,
+		oauthTokenSecretKey: []byte(secretKey),
+	}
+	assert.NoError(t, param.Validate(ctx))
+	if assert.NotNil(t, param.retrievedOauthToken) {
+		assert.Equal(t, token.ID, param.retrievedOauthToken.ID)
+	}
+
+	for _, refreshToken := range []string{"999999999:YWJj", "abc:YWJj", "1"} {
+		param := postTokenParam{
+			GrantType:           "refresh_token",
+			RefreshToken:        refreshToken,
+			oauthTokenSecretKey: []byte(secretKey),
+		}
+		err := param.Validate(ctx)
+		assert.Error(t, err)
+
+		resp := httptest.NewRecorder()
+		oauthValidationErrResp(resp, err)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		var body oauthErrorResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, oauthErrInvalidGrant, body.Error, "refresh token %s", refreshToken)
+	}
+}
+
+func TestValidateRefreshTokenAfterRefreshExpiry(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	secretKey := "secret"
+	user := testutil.UserFactory(ctx, 1)[0]
+	token := testutil.OauthTokenFactory(ctx, user.ID, secretKey)
+	err := appctx.Database(ctx).WriterExec("update oauth_tokens set refresh_expires_at = now() - interval '1 second' where id = ?", token.ID)
+	assert.NoError(t, err)
+
+	param := postTokenParam{
+		GrantType:           "refresh_token",
+		RefreshToken:        token.Token().RefreshToken,
+		oauthTokenSecretKey: []byte(secretKey),
+	}
+	err = param.Validate(ctx)
+	assert.Error(t, err)
+
+	resp := httptest.NewRecorder()
+	oauthValidationErrResp(resp, err)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	var body oauthErrorResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, oauthErrInvalidGrant, body.Error)
+}
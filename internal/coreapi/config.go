@@ -1,19 +1,50 @@
 package coreapi
 
 import (
+	"fmt"
 	"io/ioutil"
+	"strings"
+	"time"
 
 	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/awanku/awanku/pkg/mailer"
 	"github.com/caarlos0/env"
 )
 
 type Config struct {
-	Environment             string `env:"ENVIRONMENT"`
-	DatabaseURL             string `env:"DATABASE_URL"`
-	OAuthSecretKey          string `env:"OAUTH_SECRET_KEY"`
-	GithubAppID             int64  `env:"GITHUB_APP_ID"`
-	GithubAppPrivateKeyPath string `env:"GITHUB_APP_PRIVATE_KEY_PATH"`
-	GithubAppInstallURL     string `env:"GITHUB_APP_INSTALL_URL"`
+	Environment    string `env:"ENVIRONMENT"`
+	DatabaseURL    string `env:"DATABASE_URL"`
+	OAuthSecretKey string `env:"OAUTH_SECRET_KEY"`
+	OAuthClients   string `env:"OAUTH_CLIENTS"`
+	// OAuthRefreshTokenLifetime is how long refresh token can be used since it was issued
+	OAuthRefreshTokenLifetime time.Duration `env:"OAUTH_REFRESH_TOKEN_LIFETIME" envDefault:"720h"`
+	GithubAppID               int64         `env:"GITHUB_APP_ID"`
+	GithubAppPrivateKeyPath   string        `env:"GITHUB_APP_PRIVATE_KEY_PATH"`
+	GithubAppInstallURL       string        `env:"GITHUB_APP_INSTALL_URL"`
+
+	SMTPAddr     string `env:"SMTP_ADDR"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	MailFrom     string `env:"MAIL_FROM" envDefault:"Awanku <hello@awanku.id>"`
+
+	// ProvisionerToken authenticates provisioner when it reports resource state
+	ProvisionerToken string `env:"PROVISIONER_TOKEN"`
+
+	InvitationAcceptURL string `env:"INVITATION_ACCEPT_URL" envDefault:"https://console.awanku.id/invitations/accept"`
+
+	SecretsKeyFile         string `env:"SECRETS_KEY_FILE"`
+	SecretsRotateBatchSize int    `env:"SECRETS_ROTATE_BATCH_SIZE" envDefault:"100"`
+
+	JanitorInterval                 time.Duration `env:"JANITOR_INTERVAL" envDefault:"10m"`
+	JanitorBatchSize                int           `env:"JANITOR_BATCH_SIZE" envDefault:"1000"`
+	JanitorOauthTokenRetention      time.Duration `env:"JANITOR_OAUTH_TOKEN_RETENTION" envDefault:"720h"`
+	JanitorDeletedUserGracePeriod   time.Duration `env:"JANITOR_DELETED_USER_GRACE_PERIOD" envDefault:"720h"`
+	JanitorWebhookDeliveryRetention time.Duration `env:"JANITOR_WEBHOOK_DELIVERY_RETENTION" envDefault:"720h"`
+
+	DataExportWorkerInterval time.Duration `env:"DATA_EXPORT_WORKER_INTERVAL" envDefault:"30s"`
+	WebhookWorkerInterval    time.Duration `env:"WEBHOOK_WORKER_INTERVAL" envDefault:"10s"`
+	InvoiceWorkerInterval    time.Duration `env:"INVOICE_WORKER_INTERVAL" envDefault:"1h"`
 }
 
 func (c *Config) Load() error {
@@ -33,3 +64,38 @@ func (c *Config) GithubAppConfig() (*core.GithubAppConfig, error) {
 	}
 	return &config, nil
 }
+
+// SecretKeyProvider returns master key provider for secrets, master keys are read from SECRETS_KEY_FILE
+func (c *Config) SecretKeyProvider() (envelope.KeyProvider, error) {
+	return envelope.LoadLocalKeyProvider(c.SecretsKeyFile)
+}
+
+// Mailer returns SMTP mailer, when SMTP_ADDR is not set emails are only written to log
+func (c *Config) Mailer() mailer.Mailer {
+	if c.SMTPAddr == "" {
+		return &mailer.LogMailer{}
+	}
+	return &mailer.SMTPMailer{
+		Addr:     c.SMTPAddr,
+		Username: c.SMTPUsername,
+		Password: c.SMTPPassword,
+		From:     c.MailFrom,
+	}
+}
+
+// OAuthClientCredentials parses OAUTH_CLIENTS which is formatted as comma separated client_id:client_secret pairs
+func (c *Config) OAuthClientCredentials() (map[string]string, error) {
+	clients := map[string]string{}
+	for _, pair := range strings.Split(c.OAuthClients, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid oauth client credentials: %s", pair)
+		}
+		clients[parts[0]] = parts[1]
+	}
+	return clients, nil
+}
@@ -1,6 +1,16 @@
 package contract
 
-import "github.com/awanku/awanku/pkg/core"
+import (
+	"errors"
+
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// ErrUserModified is returned by UserStore.Save when user has been updated since it was read
+var ErrUserModified = errors.New("user has been modified")
+
+// ErrEmailTaken is returned by UserStore.Save when email is used by another user
+var ErrEmailTaken = errors.New("email is used by another user")
 
 type UserStore interface {
 	GetOrCreateByEmail(user *core.User) error
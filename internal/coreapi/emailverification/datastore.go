@@ -0,0 +1,91 @@
+package emailverification
+
+import (
+	"context"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+func saveVerification(ctx context.Context, v *core.EmailVerification) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        insert into email_verifications (purpose, email, code_hash, payload, expires_at, created_at)
+        values (?, ?, ?, ?, ?, now())
+        returning id, created_at
+    `
+	err := db.WriterQuery(v, query, v.Purpose, v.Email, v.CodeHash, v.Payload, v.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// useAttempt counts a confirmation attempt and returns the verification only if it can still be confirmed
+func useAttempt(ctx context.Context, id int64, purpose string) (*core.EmailVerification, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update email_verifications
+        set attempts = attempts + 1
+        where
+            id = ?
+            and purpose = ?
+            and verified_at is null
+            and expires_at > now()
+            and attempts < ?
+        returning *
+    `
+	var returned core.EmailVerification
+	err := db.WriterQuery(&returned, query, id, purpose, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if returned.ID == 0 {
+		return nil, nil
+	}
+	return &returned, nil
+}
+
+// markVerified returns false when verification has been used by concurrent request
+func markVerified(ctx context.Context, v *core.EmailVerification) (bool, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update email_verifications
+        set verified_at = now()
+        where id = ? and verified_at is null
+        returning verified_at
+    `
+	err := db.WriterQuery(v, query, v.ID)
+	if err != nil {
+		return false, err
+	}
+	return v.VerifiedAt != nil, nil
+}
+
+// PurgeExpired deletes at most batchSize expired email verifications.
+// Confirmed email changes are kept as account security history.
+func PurgeExpired(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with deleted as (
+            delete from email_verifications
+            where id in (
+                select id
+                from email_verifications
+                where
+                    expires_at < now()
+                    and (verified_at is null or purpose != ?)
+                limit ?
+            )
+            returning 1
+        )
+        select count(*) as count from deleted
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, core.EmailVerificationPurposeChangeEmail, batchSize)
+	return returned.Count, err
+}
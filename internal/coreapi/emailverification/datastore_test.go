@@ -0,0 +1,78 @@
+package emailverification
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+var secretKey = []byte("secret")
+
+func sentCode(t *testing.T, body string) string {
+	code := regexp.MustCompile(`[0-9]{6}`).FindString(body)
+	assert.NotEmpty(t, code)
+	return code
+}
+
+func TestStartAndConfirm(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	t.Run("valid code", func(t *testing.T) {
+		v := &core.EmailVerification{
+			Purpose: core.EmailVerificationPurposeSignup,
+			Email:   faker.Word() + "_" + faker.Email(),
+			Payload: map[string]string{"name": "someone"},
+		}
+		err := Start(ctx, secretKey, v)
+		assert.NoError(t, err)
+		assert.True(t, v.ID > 0)
+
+		msg := testutil.Mailer(ctx).LastMessageTo(v.Email)
+		assert.NotNil(t, msg)
+
+		confirmed, err := Confirm(ctx, secretKey, v.ID, core.EmailVerificationPurposeSignup, sentCode(t, msg.Body))
+		assert.NoError(t, err)
+		assert.Equal(t, v.Email, confirmed.Email)
+		assert.Equal(t, "someone", confirmed.Payload["name"])
+		assert.NotNil(t, confirmed.VerifiedAt)
+
+		// code can only be used once
+		_, err = Confirm(ctx, secretKey, v.ID, core.EmailVerificationPurposeSignup, sentCode(t, msg.Body))
+		assert.Equal(t, ErrInvalidCode, err)
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		v := &core.EmailVerification{
+			Purpose: core.EmailVerificationPurposeSignup,
+			Email:   faker.Word() + "_" + faker.Email(),
+		}
+		err := Start(ctx, secretKey, v)
+		assert.NoError(t, err)
+		code := sentCode(t, testutil.Mailer(ctx).LastMessageTo(v.Email).Body)
+
+		for i := 0; i < maxAttempts; i++ {
+			_, err = Confirm(ctx, secretKey, v.ID, core.EmailVerificationPurposeSignup, "abcdef")
+			assert.Equal(t, ErrInvalidCode, err)
+		}
+		_, err = Confirm(ctx, secretKey, v.ID, core.EmailVerificationPurposeSignup, code)
+		assert.Equal(t, ErrInvalidCode, err)
+	})
+
+	t.Run("wrong purpose", func(t *testing.T) {
+		v := &core.EmailVerification{
+			Purpose: core.EmailVerificationPurposeSignup,
+			Email:   faker.Word() + "_" + faker.Email(),
+		}
+		err := Start(ctx, secretKey, v)
+		assert.NoError(t, err)
+		code := sentCode(t, testutil.Mailer(ctx).LastMessageTo(v.Email).Body)
+
+		_, err = Confirm(ctx, secretKey, v.ID, "other", code)
+		assert.Equal(t, ErrInvalidCode, err)
+	})
+}
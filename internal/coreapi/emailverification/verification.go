@@ -0,0 +1,77 @@
+package emailverification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/mailer"
+)
+
+const codeDigits = 6
+const maxAttempts = 5
+
+// ErrInvalidCode is returned when code is wrong, expired, already used or attempted too many times
+var ErrInvalidCode = errors.New("invalid verification code")
+
+// Start stores new email verification and sends the code to the email address
+func Start(ctx context.Context, secretKey []byte, v *core.EmailVerification) error {
+	code, err := core.BuildVerificationCode(codeDigits)
+	if err != nil {
+		return err
+	}
+	v.CodeHash, err = core.HashHMAC(secretKey, []byte(code))
+	if err != nil {
+		return err
+	}
+	v.ExpiresAt = time.Now().Add(core.EmailVerificationMaxDuration)
+	if v.Payload == nil {
+		v.Payload = map[string]string{}
+	}
+
+	if err := saveVerification(ctx, v); err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      v.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Use this code to confirm your email address on Awanku: %s\n\nThe code expires in %d minutes. If you did not request this, you can ignore this email.",
+			code,
+			int(core.EmailVerificationMaxDuration.Minutes()),
+		),
+	}
+	return appctx.Mailer(ctx).Send(ctx, msg)
+}
+
+// Confirm checks the code and marks verification as used, each verification can only be confirmed once
+func Confirm(ctx context.Context, secretKey []byte, id int64, purpose, code string) (*core.EmailVerification, error) {
+	v, err := useAttempt(ctx, id, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, ErrInvalidCode
+	}
+
+	valid, err := core.ValidateHMAC(secretKey, []byte(code), v.CodeHash)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidCode
+	}
+
+	ok, err := markVerified(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	return v, nil
+}
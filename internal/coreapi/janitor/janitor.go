@@ -0,0 +1,160 @@
+package janitor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+)
+
+// advisoryLockKey is postgres advisory lock key used to make sure only one core-api
+// instance is running janitor at a time
+const advisoryLockKey = 20200816
+
+// TaskFunc removes at most batchSize rows and returns number of rows removed
+type TaskFunc func(ctx context.Context, batchSize int) (int64, error)
+
+// Task represents periodic cleanup task
+type Task struct {
+	Name string
+	Run  TaskFunc
+}
+
+// Config represents janitor config
+type Config struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Stats represents janitor statistics since process start
+type Stats struct {
+	Runs        int64            `json:"runs"`
+	LastRunAt   *time.Time       `json:"last_run_at"`
+	RowsRemoved map[string]int64 `json:"rows_removed"`
+}
+
+// Janitor periodically runs cleanup tasks
+type Janitor struct {
+	db     *hansip.Cluster
+	config Config
+	tasks  []Task
+
+	mut   sync.Mutex
+	stats Stats
+}
+
+// New creates new janitor
+func New(db *hansip.Cluster, config Config, tasks ...Task) *Janitor {
+	return &Janitor{
+		db:     db,
+		config: config,
+		tasks:  tasks,
+		stats: Stats{
+			RowsRemoved: map[string]int64{},
+		},
+	}
+}
+
+// Start runs janitor periodically until ctx is cancelled
+func (j *Janitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx); err != nil {
+			log.Println("janitor run failed:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs all tasks once, it does nothing when another instance holds the lock
+func (j *Janitor) RunOnce(ctx context.Context) error {
+	// advisory lock is bound to the transaction, it is released when transaction ends.
+	// Tasks run outside this transaction so each batch is committed and its row locks are released right away.
+	lockTx, err := j.db.NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer lockTx.Rollback()
+
+	var lock struct {
+		Locked bool
+	}
+	if err := lockTx.Query(&lock, "select pg_try_advisory_xact_lock(?) as locked", advisoryLockKey); err != nil {
+		return err
+	}
+	if !lock.Locked {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, appctx.KeyDatabase, j.db)
+	for _, task := range j.tasks {
+		removed, err := j.runTask(ctx, task)
+		j.recordRemoved(task.Name, removed)
+		if err != nil {
+			log.Printf("janitor task %s failed: %s", task.Name, err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("janitor task %s removed %d rows", task.Name, removed)
+		}
+	}
+
+	j.mut.Lock()
+	now := time.Now()
+	j.stats.Runs++
+	j.stats.LastRunAt = &now
+	j.mut.Unlock()
+	return nil
+}
+
+func (j *Janitor) runTask(ctx context.Context, task Task) (int64, error) {
+	var total int64
+	for {
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		default:
+		}
+
+		removed, err := task.Run(ctx, j.config.BatchSize)
+		total += removed
+		if err != nil {
+			return total, err
+		}
+		if removed < int64(j.config.BatchSize) {
+			return total, nil
+		}
+	}
+}
+
+func (j *Janitor) recordRemoved(task string, n int64) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.stats.RowsRemoved[task] += n
+}
+
+// Stats returns copy of janitor statistics
+func (j *Janitor) Stats() Stats {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	stats := Stats{
+		Runs:        j.stats.Runs,
+		LastRunAt:   j.stats.LastRunAt,
+		RowsRemoved: map[string]int64{},
+	}
+	for name, n := range j.stats.RowsRemoved {
+		stats.RowsRemoved[name] = n
+	}
+	return stats
+}
@@ -3,13 +3,26 @@ package coreapi
 import (
 	"net/http"
 
+	"github.com/awanku/awanku/internal/coreapi/admin"
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/internal/coreapi/auth"
 	"github.com/awanku/awanku/internal/coreapi/user"
+	userDataExport "github.com/awanku/awanku/internal/coreapi/user/dataexport"
 	"github.com/awanku/awanku/internal/coreapi/workspace"
+	workspaceActivity "github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	workspaceBilling "github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	workspaceInvitation "github.com/awanku/awanku/internal/coreapi/workspace/invitation"
+	workspaceMember "github.com/awanku/awanku/internal/coreapi/workspace/member"
 	workspaceProject "github.com/awanku/awanku/internal/coreapi/workspace/project"
+	workspaceProjectEnvironment "github.com/awanku/awanku/internal/coreapi/workspace/project/environment"
+	workspaceProjectMember "github.com/awanku/awanku/internal/coreapi/workspace/project/member"
 	workspaceProjectResource "github.com/awanku/awanku/internal/coreapi/workspace/project/resource"
+	workspaceQuota "github.com/awanku/awanku/internal/coreapi/workspace/quota"
 	workspaceRepository "github.com/awanku/awanku/internal/coreapi/workspace/repository"
+	workspaceSecret "github.com/awanku/awanku/internal/coreapi/workspace/secret"
+	workspaceTrash "github.com/awanku/awanku/internal/coreapi/workspace/trash"
+	workspaceWebhook "github.com/awanku/awanku/internal/coreapi/workspace/webhook"
+	"github.com/awanku/awanku/pkg/core"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
 )
@@ -22,12 +35,14 @@ func (s *Server) initRoutes() {
 	})
 
 	s.router.Get("/status", statusHandler(s.db))
+	s.router.Get("/status/janitor", janitorStatusHandler(s.janitor))
 
 	s.router.Route("/v1", func(r chi.Router) {
 		r.Use(appctx.Middleware(appctx.Config{
 			Environment:     s.Config.Environment,
 			DB:              s.db,
 			GithubAppConfig: s.githubAppConfig,
+			Mailer:          s.mailer,
 		}))
 
 		r.Use(cors.New(cors.Options{
@@ -40,46 +55,193 @@ func (s *Server) initRoutes() {
 
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/{provider:[a-z]+}/connect", auth.HandleOauthProviderConnect)
-			r.Get("/{provider:[a-z]+}/callback", auth.HandleOauthProviderCallback)
-			r.Post("/token", auth.HandleExchangeOauthToken(s.oauthTokenSecretKey))
+			r.Get("/{provider:[a-z]+}/callback", auth.HandleOauthProviderCallback(s.oauthTokenSecretKey))
+			r.Post("/email-verifications/{verification_id:[0-9]+}", auth.HandleConfirmEmailVerification(s.oauthTokenSecretKey))
+			r.Post("/token", auth.HandleExchangeOauthToken(s.oauthTokenSecretKey, s.oauthClients, s.Config.OAuthRefreshTokenLifetime))
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.OauthTokenValidatorMiddleware(s.oauthTokenSecretKey))
+			r.Use(auth.PlatformAdminMiddleware)
+
+			r.Route("/users", func(r chi.Router) {
+				r.Get("/", admin.HandleSearchUsers)
+
+				r.Route("/{user_id:[0-9]+}", func(r chi.Router) {
+					r.Get("/", admin.HandleGetUser)
+					r.Get("/workspaces", admin.HandleListUserWorkspaces)
+					r.Get("/sessions", admin.HandleListUserSessions)
+					r.Post("/suspend", admin.HandleSuspendUser)
+					r.Post("/unsuspend", admin.HandleUnsuspendUser)
+					r.Post("/revoke-tokens", admin.HandleRevokeUserTokens)
+					r.Post("/impersonate", auth.HandleImpersonateUser(s.oauthTokenSecretKey))
+				})
+			})
+		})
+
+		r.Route("/provisioner", func(r chi.Router) {
+			r.Use(auth.ServiceTokenMiddleware(s.Config.ProvisionerToken))
+
+			r.Post("/resources/{resource_id:[0-9]+}/state", workspaceProjectResource.HandleUpdateState)
 		})
 
 		r.Route("/users", func(r chi.Router) {
 			r.Use(auth.OauthTokenValidatorMiddleware(s.oauthTokenSecretKey))
 
 			r.Get("/me", user.HandleGetMe)
+			r.Patch("/me", user.HandleUpdateMe(s.oauthTokenSecretKey))
+			r.With(auth.DenyImpersonationMiddleware).Delete("/me", user.HandleDeleteMe)
+			r.Get("/me/deletion", user.HandlePreviewDeleteMe)
+			r.Post("/me/export", userDataExport.HandleCreate)
+			r.Get("/me/exports/{export_id:[0-9]+}", userDataExport.HandleGet(s.oauthTokenSecretKey))
+			r.Post("/me/email-verifications/{verification_id:[0-9]+}", user.HandleConfirmEmailChange(s.oauthTokenSecretKey))
+		})
+
+		r.Get("/data-exports/{export_id:[0-9]+}/download", userDataExport.HandleDownload(s.oauthTokenSecretKey))
+
+		r.Route("/workspace-invitations", func(r chi.Router) {
+			r.Use(auth.OauthTokenValidatorMiddleware(s.oauthTokenSecretKey))
+
+			r.Post("/accept", workspaceInvitation.HandleAccept(s.oauthTokenSecretKey))
 		})
 
 		r.Route("/workspaces", func(r chi.Router) {
 			r.Use(auth.OauthTokenValidatorMiddleware(s.oauthTokenSecretKey))
 
 			r.Get("/", workspace.HandleListAll)
+			r.Post("/", workspace.HandleCreate)
 
-			r.Route("/{workspace_id:[0-9]+}", func(r chi.Router) {
+			r.Route("/{workspace_id}", func(r chi.Router) {
 				r.Use(workspace.CurrentWorkspaceMiddleware)
 
+				owner := workspace.RequireAccessLevel(core.WorkspaceAccessLevelOwner)
+				editor := workspace.RequireAccessLevel(core.WorkspaceAccessLevelEditor)
+				viewer := workspace.RequireAccessLevel(core.WorkspaceAccessLevelViewer)
+				guest := workspace.RequireAccessLevel(core.WorkspaceAccessLevelGuest)
+
+				r.With(guest).Get("/", workspace.HandleGet)
+				r.With(editor).Patch("/", workspace.HandleUpdate)
+				r.With(owner, auth.DenyImpersonationMiddleware).Delete("/", workspace.HandleDelete)
+
+				r.With(viewer).Get("/activities", workspaceActivity.HandleListAll)
+				r.With(viewer).Get("/usage", workspaceQuota.HandleGetUsage)
+
+				r.Route("/invoices", func(r chi.Router) {
+					r.Use(owner)
+
+					r.Get("/", workspaceBilling.HandleListAll)
+					r.Get("/{invoice_id:[0-9]+}", workspaceBilling.HandleGet)
+					r.Get("/{invoice_id:[0-9]+}/download", workspaceBilling.HandleDownload)
+				})
+
+				r.Route("/members", func(r chi.Router) {
+					r.With(viewer).Get("/", workspaceMember.HandleListAll)
+					r.With(owner).Patch("/{user_id:[0-9]+}", workspaceMember.HandleUpdate)
+					r.With(owner, auth.DenyImpersonationMiddleware).Delete("/{user_id:[0-9]+}", workspaceMember.HandleDelete)
+				})
+				r.With(viewer).Post("/leave", workspaceMember.HandleLeave)
+
+				r.Route("/ownership-transfer", func(r chi.Router) {
+					r.With(viewer).Get("/", workspaceMember.HandleGetOwnershipTransfer)
+					r.With(owner, auth.DenyImpersonationMiddleware).Post("/", workspaceMember.HandleProposeOwnershipTransfer)
+					r.With(viewer).Delete("/", workspaceMember.HandleCancelOwnershipTransfer)
+					r.With(viewer, auth.DenyImpersonationMiddleware).Post("/accept", workspaceMember.HandleAcceptOwnershipTransfer)
+				})
+
+				r.Route("/invitations", func(r chi.Router) {
+					r.Use(owner)
+
+					r.Get("/", workspaceInvitation.HandleListAll)
+					r.Post("/", workspaceInvitation.HandleCreate(s.oauthTokenSecretKey, s.Config.InvitationAcceptURL))
+					r.Post("/{invitation_id:[0-9]+}/resend", workspaceInvitation.HandleResend(s.oauthTokenSecretKey, s.Config.InvitationAcceptURL))
+					r.With(auth.DenyImpersonationMiddleware).Delete("/{invitation_id:[0-9]+}", workspaceInvitation.HandleDelete)
+				})
+
 				r.Route("/repositories", func(r chi.Router) {
-					r.Get("/", workspaceRepository.HandleListAllRepositories)
-					r.Get("/connections", workspaceRepository.HandleListAllConnections)
+					r.With(viewer).Get("/", workspaceRepository.HandleListAllRepositories)
+					r.With(viewer).Get("/connections", workspaceRepository.HandleListAllConnections)
+					r.With(owner, auth.DenyImpersonationMiddleware).Delete("/connections/{connection_id:[0-9]+}", workspaceRepository.HandleDeleteConnection)
 
 					r.Route("/providers", func(r chi.Router) {
-						r.Get("/github", workspaceRepository.HandleConnectGithub)
-						r.Post("/github", workspaceRepository.HandleSaveGithubConnection)
+						r.With(owner).Get("/github", workspaceRepository.HandleConnectGithub)
+						r.With(owner).Post("/github", workspaceRepository.HandleSaveGithubConnection)
+					})
+				})
+
+				r.Route("/secrets", s.secretRoutes(viewer, editor))
+
+				r.Route("/trash", func(r chi.Router) {
+					r.With(viewer).Get("/", workspaceTrash.HandleListAll)
+					r.With(editor).Post("/projects/{project_id:[0-9]+}/restore", workspaceTrash.HandleRestoreProject)
+					r.With(editor).Post("/resources/{resource_id:[0-9]+}/restore", workspaceTrash.HandleRestoreResource)
+					r.With(owner).Post("/repository-connections/{connection_id:[0-9]+}/restore", workspaceTrash.HandleRestoreRepositoryConnection)
+				})
+
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Use(owner)
+
+					r.Get("/", workspaceWebhook.HandleListAll)
+					r.Post("/", workspaceWebhook.HandleCreate(s.secretKeys))
+
+					r.Route("/{webhook_id:[0-9]+}", func(r chi.Router) {
+						r.Get("/", workspaceWebhook.HandleGet)
+						r.Patch("/", workspaceWebhook.HandleUpdate)
+						r.With(auth.DenyImpersonationMiddleware).Delete("/", workspaceWebhook.HandleDelete)
+						r.Get("/deliveries", workspaceWebhook.HandleListDeliveries)
+						r.Get("/deliveries/{delivery_id:[0-9]+}", workspaceWebhook.HandleGetDelivery)
+						r.Post("/deliveries/{delivery_id:[0-9]+}/redeliver", workspaceWebhook.HandleRedeliver)
 					})
 				})
 
 				r.Route("/projects", func(r chi.Router) {
-					r.Get("/", workspaceProject.HandleListAll)
+					r.With(guest).Get("/", workspaceProject.HandleListAll)
+					r.With(editor).Post("/", workspaceProject.HandleCreate)
 
 					r.Route("/{project_id:[0-9]+}", func(r chi.Router) {
-						r.Route("/resources", func(r chi.Router) {
-							r.Get("/", workspaceProjectResource.HandleListAll)
-							r.Post("/", workspaceProjectResource.HandleCreate)
-
-							r.Route("/{resource_id:[0-9]+}", func(r chi.Router) {
-								r.Get("/", workspaceProjectResource.HandleGet)
-								r.Patch("/", workspaceProjectResource.HandleUpdate)
-								r.Delete("/", workspaceProjectResource.HandleDelete)
+						r.Use(workspaceProject.CurrentProjectMiddleware)
+
+						projectOwner := workspaceProject.RequireAccessLevel(core.WorkspaceAccessLevelOwner)
+						projectEditor := workspaceProject.RequireAccessLevel(core.ProjectAccessLevelEditor)
+						projectViewer := workspaceProject.RequireAccessLevel(core.ProjectAccessLevelViewer)
+
+						r.With(projectViewer).Get("/", workspaceProject.HandleGet)
+						r.With(projectEditor).Patch("/", workspaceProject.HandleUpdate)
+						r.With(projectOwner, auth.DenyImpersonationMiddleware).Delete("/", workspaceProject.HandleDelete)
+						r.With(projectOwner, auth.DenyImpersonationMiddleware).Post("/transfer", workspaceProject.HandleTransfer)
+
+						r.With(projectEditor).Put("/repository", workspaceProject.HandleLinkRepository)
+						r.With(projectEditor).Delete("/repository", workspaceProject.HandleUnlinkRepository)
+
+						r.Route("/members", func(r chi.Router) {
+							r.With(projectViewer).Get("/", workspaceProjectMember.HandleListAll)
+							r.With(projectOwner).Post("/", workspaceProjectMember.HandleCreate)
+							r.With(projectOwner).Patch("/{user_id:[0-9]+}", workspaceProjectMember.HandleUpdate)
+							r.With(projectOwner, auth.DenyImpersonationMiddleware).Delete("/{user_id:[0-9]+}", workspaceProjectMember.HandleDelete)
+						})
+
+						r.Route("/secrets", s.secretRoutes(projectViewer, projectEditor))
+
+						r.Route("/environments", func(r chi.Router) {
+							r.With(projectViewer).Get("/", workspaceProjectEnvironment.HandleListAll)
+							r.With(projectEditor).Post("/", workspaceProjectEnvironment.HandleCreate)
+
+							r.Route("/{environment_id:[0-9]+}", func(r chi.Router) {
+								r.Use(workspaceProjectEnvironment.CurrentEnvironmentMiddleware)
+
+								r.With(projectViewer).Get("/", workspaceProjectEnvironment.HandleGet)
+								r.With(projectEditor).Patch("/", workspaceProjectEnvironment.HandleUpdate)
+								r.With(projectEditor, auth.DenyImpersonationMiddleware).Delete("/", workspaceProjectEnvironment.HandleDelete)
+
+								r.Route("/resources", func(r chi.Router) {
+									r.With(projectViewer).Get("/", workspaceProjectResource.HandleListAll)
+									r.With(projectEditor).Post("/", workspaceProjectResource.HandleCreate)
+
+									r.Route("/{resource_id:[0-9]+}", func(r chi.Router) {
+										r.With(projectViewer).Get("/", workspaceProjectResource.HandleGet)
+										r.With(projectEditor).Patch("/", workspaceProjectResource.HandleUpdate)
+										r.With(projectEditor, auth.DenyImpersonationMiddleware).Delete("/", workspaceProjectResource.HandleDelete)
+									})
+								})
 							})
 						})
 					})
@@ -89,6 +251,20 @@ func (s *Server) initRoutes() {
 	})
 }
 
+// secretRoutes is shared by workspace and project secrets, handlers tell them apart by project_id url param
+func (s *Server) secretRoutes(viewer, editor func(http.Handler) http.Handler) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.With(viewer).Get("/", workspaceSecret.HandleListAll)
+		r.With(editor).Post("/", workspaceSecret.HandleCreate(s.secretKeys))
+
+		r.Route("/{secret_id:[0-9]+}", func(r chi.Router) {
+			r.With(editor).Put("/", workspaceSecret.HandleUpdate(s.secretKeys))
+			r.With(editor, auth.DenyImpersonationMiddleware).Delete("/", workspaceSecret.HandleDelete)
+			r.With(editor, auth.DenyImpersonationMiddleware).Post("/reveal", workspaceSecret.HandleReveal(s.secretKeys))
+		})
+	}
+}
+
 func baseMiddleware(next http.Handler) http.Handler {
 	f := func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "max-age=0, private, must-revalidate")
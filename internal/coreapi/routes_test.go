@@ -0,0 +1,486 @@
+package coreapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	workspaceBilling "github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/awanku/awanku/pkg/mailer"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/bxcodec/faker/v3"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecretKey = "secret"
+
+// workspaceRouteAccessLevels lists minimum access level for every route under a workspace
+var workspaceRouteAccessLevels = map[string]string{
+	"GET /v1/workspaces/{workspace_id}/":                                                                                                     core.WorkspaceAccessLevelGuest,
+	"PATCH /v1/workspaces/{workspace_id}/":                                                                                                   core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/":                                                                                                  core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/activities":                                                                                           core.WorkspaceAccessLevelViewer,
+	"GET /v1/workspaces/{workspace_id}/usage":                                                                                                core.WorkspaceAccessLevelViewer,
+	"GET /v1/workspaces/{workspace_id}/invoices/":                                                                                            core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/invoices/{invoice_id:[0-9]+}":                                                                         core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/invoices/{invoice_id:[0-9]+}/download":                                                                core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/members/":                                                                                             core.WorkspaceAccessLevelViewer,
+	"PATCH /v1/workspaces/{workspace_id}/members/{user_id:[0-9]+}":                                                                           core.WorkspaceAccessLevelOwner,
+	"DELETE /v1/workspaces/{workspace_id}/members/{user_id:[0-9]+}":                                                                          core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/invitations/":                                                                                         core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/invitations/":                                                                                        core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/invitations/{invitation_id:[0-9]+}/resend":                                                           core.WorkspaceAccessLevelOwner,
+	"DELETE /v1/workspaces/{workspace_id}/invitations/{invitation_id:[0-9]+}":                                                                core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/ownership-transfer/":                                                                                  core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/ownership-transfer/":                                                                                 core.WorkspaceAccessLevelOwner,
+	"DELETE /v1/workspaces/{workspace_id}/ownership-transfer/":                                                                               core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/ownership-transfer/accept":                                                                           core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/leave":                                                                                               core.WorkspaceAccessLevelViewer,
+	"GET /v1/workspaces/{workspace_id}/repositories/":                                                                                        core.WorkspaceAccessLevelViewer,
+	"GET /v1/workspaces/{workspace_id}/repositories/connections":                                                                             core.WorkspaceAccessLevelViewer,
+	"DELETE /v1/workspaces/{workspace_id}/repositories/connections/{connection_id:[0-9]+}":                                                   core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/repositories/providers/github":                                                                        core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/repositories/providers/github":                                                                       core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/projects/":                                                                                            core.WorkspaceAccessLevelGuest,
+	"POST /v1/workspaces/{workspace_id}/projects/":                                                                                           core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/":                                                                        core.WorkspaceAccessLevelViewer,
+	"PATCH /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/":                                                                      core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/":                                                                     core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/transfer":                                                               core.WorkspaceAccessLevelOwner,
+	"PUT /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/repository":                                                              core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/repository":                                                           core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/":                                                           core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/":                                                          core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/":                                   core.WorkspaceAccessLevelViewer,
+	"PATCH /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/":                                 core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/":                                core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/members/":                                                                core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/members/":                                                               core.WorkspaceAccessLevelOwner,
+	"PATCH /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/members/{user_id:[0-9]+}":                                              core.WorkspaceAccessLevelOwner,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/members/{user_id:[0-9]+}":                                             core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/secrets/":                                                                                             core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/secrets/":                                                                                            core.WorkspaceAccessLevelEditor,
+	"PUT /v1/workspaces/{workspace_id}/secrets/{secret_id:[0-9]+}/":                                                                          core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/secrets/{secret_id:[0-9]+}/":                                                                       core.WorkspaceAccessLevelEditor,
+	"POST /v1/workspaces/{workspace_id}/secrets/{secret_id:[0-9]+}/reveal":                                                                   core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/trash/":                                                                                               core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/trash/projects/{project_id:[0-9]+}/restore":                                                          core.WorkspaceAccessLevelEditor,
+	"POST /v1/workspaces/{workspace_id}/trash/resources/{resource_id:[0-9]+}/restore":                                                        core.WorkspaceAccessLevelEditor,
+	"POST /v1/workspaces/{workspace_id}/trash/repository-connections/{connection_id:[0-9]+}/restore":                                         core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/webhooks/":                                                                                            core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/webhooks/":                                                                                           core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/":                                                                        core.WorkspaceAccessLevelOwner,
+	"PATCH /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/":                                                                      core.WorkspaceAccessLevelOwner,
+	"DELETE /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/":                                                                     core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/deliveries":                                                              core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/deliveries/{delivery_id:[0-9]+}":                                         core.WorkspaceAccessLevelOwner,
+	"POST /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/redeliver":                              core.WorkspaceAccessLevelOwner,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/":                                                                core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/":                                                               core.WorkspaceAccessLevelEditor,
+	"PUT /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/{secret_id:[0-9]+}/":                                             core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/{secret_id:[0-9]+}/":                                          core.WorkspaceAccessLevelEditor,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/{secret_id:[0-9]+}/reveal":                                      core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/":                         core.WorkspaceAccessLevelViewer,
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/":                        core.WorkspaceAccessLevelEditor,
+	"GET /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/{resource_id:[0-9]+}/":    core.WorkspaceAccessLevelViewer,
+	"PATCH /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/{resource_id:[0-9]+}/":  core.WorkspaceAccessLevelEditor,
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/{resource_id:[0-9]+}/": core.WorkspaceAccessLevelEditor,
+}
+
+// impersonationDeniedRoutes lists destructive routes which can not be called using impersonation token
+var impersonationDeniedRoutes = []string{
+	"DELETE /v1/users/me",
+	"DELETE /v1/workspaces/{workspace_id}/",
+	"DELETE /v1/workspaces/{workspace_id}/members/{user_id:[0-9]+}",
+	"POST /v1/workspaces/{workspace_id}/ownership-transfer/",
+	"POST /v1/workspaces/{workspace_id}/ownership-transfer/accept",
+	"DELETE /v1/workspaces/{workspace_id}/invitations/{invitation_id:[0-9]+}",
+	"DELETE /v1/workspaces/{workspace_id}/repositories/connections/{connection_id:[0-9]+}",
+	"DELETE /v1/workspaces/{workspace_id}/secrets/{secret_id:[0-9]+}/",
+	"POST /v1/workspaces/{workspace_id}/secrets/{secret_id:[0-9]+}/reveal",
+	"DELETE /v1/workspaces/{workspace_id}/webhooks/{webhook_id:[0-9]+}/",
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/",
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/transfer",
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/members/{user_id:[0-9]+}",
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/{secret_id:[0-9]+}/",
+	"POST /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/secrets/{secret_id:[0-9]+}/reveal",
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/",
+	"DELETE /v1/workspaces/{workspace_id}/projects/{project_id:[0-9]+}/environments/{environment_id:[0-9]+}/resources/{resource_id:[0-9]+}/",
+}
+
+var urlParamPattern = regexp.MustCompile(`\{([a-z_]+)(:[^}]*)?\}`)
+
+func testServer(db *hansip.Cluster) *Server {
+	s := &Server{
+		router:              chi.NewRouter(),
+		db:                  db,
+		oauthTokenSecretKey: []byte(testSecretKey),
+		githubAppConfig:     &core.GithubAppConfig{InstallURL: "https://github.com/apps/awanku/installations/new"},
+		secretKeys:          testSecretKeys(),
+		mailer:              &mailer.MemoryMailer{},
+		Config:              &Config{Environment: "testing"},
+	}
+	s.initRoutes()
+	return s
+}
+
+func testSecretKeys() envelope.KeyProvider {
+	keys, err := envelope.NewLocalKeyProvider(map[string][]byte{"test": make([]byte, 32)}, "test")
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+func workspaceRoutes(t *testing.T, router chi.Router) []string {
+	routes := []string{}
+	err := chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/v1/workspaces/{workspace_id") {
+			routes = append(routes, method+" "+route)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	return routes
+}
+
+func TestWorkspaceRoutesDeclareAccessLevel(t *testing.T) {
+	s := testServer(nil)
+
+	routes := workspaceRoutes(t, s.router)
+	assert.Len(t, routes, len(workspaceRouteAccessLevels))
+	for _, route := range routes {
+		_, ok := workspaceRouteAccessLevels[route]
+		assert.True(t, ok, "missing access level for route %s", route)
+	}
+}
+
+func TestWorkspaceRoutesAccessLevel(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	s := testServer(appctx.Database(ctx))
+
+	memberships := []string{
+		core.WorkspaceAccessLevelOwner,
+		core.WorkspaceAccessLevelEditor,
+		core.WorkspaceAccessLevelViewer,
+		"",
+	}
+
+	for route, required := range workspaceRouteAccessLevels {
+		for _, membership := range memberships {
+			t.Run(fmt.Sprintf("%s as %q", route, membership), func(t *testing.T) {
+				user := testutil.UserFactory(ctx, 1)[0]
+				token := testutil.OauthTokenFactory(ctx, user.ID, testSecretKey)
+				workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+				if membership != "" {
+					testutil.WorkspaceUserFactory(ctx, workspace.ID, user.ID, membership)
+				}
+				invitation := testutil.WorkspaceInvitationFactory(ctx, workspace.ID, user.ID, faker.Email())
+				project := testutil.ProjectFactory(ctx, workspace.ID)
+				environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeStaging)
+
+				parts := strings.SplitN(route, " ", 2)
+				path := urlParamPattern.ReplaceAllStringFunc(parts[1], func(param string) string {
+					switch {
+					case strings.HasPrefix(param, "{workspace_id"):
+						return fmt.Sprint(workspace.ID)
+					case strings.HasPrefix(param, "{user_id"):
+						return fmt.Sprint(user.ID)
+					case strings.HasPrefix(param, "{invitation_id"):
+						return fmt.Sprint(invitation.ID)
+					case strings.HasPrefix(param, "{project_id"):
+						return fmt.Sprint(project.ID)
+					case strings.HasPrefix(param, "{environment_id"):
+						return fmt.Sprint(environment.ID)
+					}
+					return "1"
+				})
+
+				req := httptest.NewRequest(parts[0], path, strings.NewReader("{}"))
+				req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+				resp := httptest.NewRecorder()
+				s.router.ServeHTTP(resp, req)
+
+				switch {
+				case membership == "":
+					assert.Equal(t, http.StatusNotFound, resp.Code)
+				case !core.WorkspaceAccessLevelAtLeast(membership, required):
+					assert.Equal(t, http.StatusForbidden, resp.Code)
+				case route == "GET /v1/workspaces/{workspace_id}/":
+					assert.Equal(t, http.StatusOK, resp.Code)
+				default:
+					// handler may still answer 404 when the addressed item does not exist
+					assert.NotEqual(t, http.StatusForbidden, resp.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestImpersonationDeniedRoutesExist(t *testing.T) {
+	s := testServer(nil)
+
+	routes := map[string]bool{}
+	err := chi.Walk(s.router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	assert.NoError(t, err)
+	for _, route := range impersonationDeniedRoutes {
+		assert.True(t, routes[route], "unknown route %s", route)
+	}
+}
+
+func TestImpersonationDeniedRoutes(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	s := testServer(db)
+
+	users := testutil.UserFactory(ctx, 2)
+	user, admin := users[0], users[1]
+	err := db.WriterExec("update users set is_admin = true where id = ?", admin.ID)
+	assert.NoError(t, err)
+	token := testutil.OauthTokenFactory(ctx, user.ID, testSecretKey)
+	err = db.WriterExec("update oauth_tokens set impersonator_user_id = ? where id = ?", admin.ID, token.ID)
+	assert.NoError(t, err)
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, user.ID, core.WorkspaceAccessLevelOwner)
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeStaging)
+
+	for _, route := range impersonationDeniedRoutes {
+		t.Run(route, func(t *testing.T) {
+			parts := strings.SplitN(route, " ", 2)
+			path := urlParamPattern.ReplaceAllStringFunc(parts[1], func(param string) string {
+				switch {
+				case strings.HasPrefix(param, "{workspace_id"):
+					return fmt.Sprint(workspace.ID)
+				case strings.HasPrefix(param, "{project_id"):
+					return fmt.Sprint(project.ID)
+				case strings.HasPrefix(param, "{environment_id"):
+					return fmt.Sprint(environment.ID)
+				}
+				return "1"
+			})
+
+			req := httptest.NewRequest(parts[0], path, strings.NewReader("{}"))
+			req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+			resp := httptest.NewRecorder()
+			s.router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			assert.Contains(t, resp.Body.String(), "impersonation token")
+		})
+	}
+}
+
+func TestProjectGuestAccess(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	s := testServer(appctx.Database(ctx))
+
+	contractor := testutil.UserFactory(ctx, 1)[0]
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	granted := testutil.ProjectFactory(ctx, workspace.ID)
+	other := testutil.ProjectFactory(ctx, workspace.ID)
+	testutil.ProjectUserFactory(ctx, granted.ID, contractor.ID, core.ProjectAccessLevelViewer)
+
+	token := testutil.OauthTokenFactory(ctx, contractor.ID, testSecretKey)
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+		resp := httptest.NewRecorder()
+		s.router.ServeHTTP(resp, req)
+		return resp
+	}
+	base := fmt.Sprintf("/v1/workspaces/%d", workspace.ID)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, base+"/").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, base+"/members/").Code)
+
+	resp := request(http.MethodGet, base+"/projects/")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), granted.Name)
+	assert.NotContains(t, resp.Body.String(), other.Name)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, fmt.Sprintf("%s/projects/%d/", base, granted.ID)).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPatch, fmt.Sprintf("%s/projects/%d/", base, granted.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, fmt.Sprintf("%s/projects/%d/", base, other.ID)).Code)
+}
+
+func TestUpdateMeEmailChange(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	s := testServer(appctx.Database(ctx))
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	token := testutil.OauthTokenFactory(ctx, user.ID, testSecretKey)
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+		resp := httptest.NewRecorder()
+		s.router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	var me core.User
+	resp := request(http.MethodGet, "/v1/users/me", nil)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&me))
+
+	newEmail := faker.Email()
+	resp = request(http.MethodPatch, "/v1/users/me", map[string]interface{}{
+		"email":      newEmail,
+		"updated_at": me.UpdatedAt,
+	})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var updated struct {
+		EmailVerificationID int64 `json:"email_verification_id"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	assert.True(t, updated.EmailVerificationID > 0)
+
+	msg := s.mailer.(*mailer.MemoryMailer).LastMessageTo(newEmail)
+	if assert.NotNil(t, msg) {
+		code := regexp.MustCompile(`\d{6}`).FindString(msg.Body)
+		resp = request(http.MethodPost, fmt.Sprintf("/v1/users/me/email-verifications/%d", updated.EmailVerificationID), map[string]string{"code": code})
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), newEmail)
+	}
+}
+
+func TestProvisionedResourceIsInvoiced(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	s := testServer(db)
+
+	s.Config.ProvisionerToken = "provisioner-secret"
+
+	users := testutil.UserFactory(ctx, 2)
+	owner, admin := users[0], users[1]
+	err := db.WriterExec("update users set is_admin = true where id = ?", admin.ID)
+	assert.NoError(t, err)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeStaging)
+
+	request := func(userID int64, method, path string, body interface{}) *httptest.ResponseRecorder {
+		token := testutil.OauthTokenFactory(ctx, userID, testSecretKey)
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+		resp := httptest.NewRecorder()
+		s.router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := request(owner.ID, http.MethodPost, fmt.Sprintf("/v1/workspaces/%d/projects/%d/environments/%d/resources", workspace.ID, project.ID, environment.ID), map[string]interface{}{
+		"name": "db",
+		"type": core.ResourceTypePostgres,
+	})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var resource core.Resource
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&resource))
+
+	// only provisioner token is accepted, even platform admin user token is not
+	path := fmt.Sprintf("/v1/provisioner/resources/%d/state", resource.ID)
+	resp = request(admin.ID, http.MethodPost, path, map[string]string{"state": core.ResourceStateProvisioningSuccess})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	payload, _ := json.Marshal(map[string]string{"state": core.ResourceStateProvisioningSuccess})
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer provisioner-secret")
+	resp = httptest.NewRecorder()
+	s.router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var activity struct{ ActorUserID *int64 }
+	err = db.Query(&activity, "select actor_user_id from workspace_activity_logs where target_type = 'resource' and target_id = ? order by id desc limit 1", resource.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, activity.ActorUserID)
+
+	// move workspace and metering into previous month, which is invoiced by monthly task
+	now := time.Now().UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	err = db.WriterExec("update workspaces set created_at = ? where id = ?", periodStart, workspace.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec("update resource_metering_events set occurred_at = ? where resource_id = ?", periodStart, resource.ID)
+	assert.NoError(t, err)
+	for {
+		generated, err := workspaceBilling.GenerateMonthlyInvoices(ctx, 100)
+		assert.NoError(t, err)
+		if err != nil || generated == 0 {
+			break
+		}
+	}
+
+	resp = request(owner.ID, http.MethodGet, fmt.Sprintf("/v1/workspaces/%d/invoices", workspace.ID), nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var invoices []core.Invoice
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&invoices))
+	if assert.Len(t, invoices, 1) && assert.Len(t, invoices[0].LineItems, 1) {
+		assert.Equal(t, resource.ID, invoices[0].LineItems[0].ResourceID)
+		assert.True(t, invoices[0].LineItems[0].Hours > 0)
+	}
+}
+
+func TestWorkspaceRoutesResolveSlug(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	s := testServer(appctx.Database(ctx))
+
+	users := testutil.UserFactory(ctx, 2)
+	member, outsider := users[0], users[1]
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, member.ID, core.WorkspaceAccessLevelOwner)
+
+	oldSlug := workspace.Slug
+	newSlug := "renamed-" + faker.UUIDDigit()
+	err := appctx.Database(ctx).WriterExec("update workspaces set slug = ? where id = ?", newSlug, workspace.ID)
+	assert.NoError(t, err)
+	err = appctx.Database(ctx).WriterExec("insert into workspace_slug_redirects (slug, workspace_id, expires_at) values (?, ?, now() + interval '1 day')", oldSlug, workspace.ID)
+	assert.NoError(t, err)
+
+	get := func(user *core.User, path string) *httptest.ResponseRecorder {
+		token := testutil.OauthTokenFactory(ctx, user.ID, testSecretKey)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token.Token().AccessToken)
+		resp := httptest.NewRecorder()
+		s.router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get(member, "/v1/workspaces/"+newSlug+"/")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = get(member, "/v1/workspaces/"+oldSlug+"/members/?page=1")
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.Code)
+	assert.Equal(t, "/v1/workspaces/"+newSlug+"/members/?page=1", resp.Header().Get("Location"))
+
+	resp = get(outsider, "/v1/workspaces/"+oldSlug+"/")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = get(member, "/v1/workspaces/Not_A_Slug/")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
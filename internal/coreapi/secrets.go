@@ -0,0 +1,27 @@
+package coreapi
+
+import (
+	"github.com/awanku/awanku/internal/coreapi/workspace/secret"
+	"github.com/awanku/awanku/internal/coreapi/workspace/webhook"
+)
+
+// RotateSecretsMasterKey rewraps data keys of all secrets and webhook signing secrets with current master key from SECRETS_KEY_FILE
+func RotateSecretsMasterKey(conf *Config) (int, error) {
+	keys, err := conf.SecretKeyProvider()
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := initDB(conf.DatabaseURL)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated, err := secret.RotateMasterKey(db, keys, conf.SecretsRotateBatchSize)
+	if err != nil {
+		return rotated, err
+	}
+
+	rotatedWebhooks, err := webhook.RotateMasterKey(db, keys, conf.SecretsRotateBatchSize)
+	return rotated + rotatedWebhooks, err
+}
@@ -1,11 +1,23 @@
 package coreapi
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/auth"
+	"github.com/awanku/awanku/internal/coreapi/emailverification"
+	"github.com/awanku/awanku/internal/coreapi/janitor"
+	"github.com/awanku/awanku/internal/coreapi/user"
+	userDataExport "github.com/awanku/awanku/internal/coreapi/user/dataexport"
+	workspaceBilling "github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	workspaceProjectEnvironment "github.com/awanku/awanku/internal/coreapi/workspace/project/environment"
+	workspaceTrash "github.com/awanku/awanku/internal/coreapi/workspace/trash"
+	workspaceWebhook "github.com/awanku/awanku/internal/coreapi/workspace/webhook"
 	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/awanku/awanku/pkg/mailer"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-pg/pg/v9"
@@ -15,7 +27,14 @@ type Server struct {
 	router              chi.Router
 	db                  *hansip.Cluster
 	oauthTokenSecretKey []byte
+	oauthClients        map[string]string
 	githubAppConfig     *core.GithubAppConfig
+	secretKeys          envelope.KeyProvider
+	janitor             *janitor.Janitor
+	dataExportWorker    *userDataExport.Worker
+	webhookWorker       *workspaceWebhook.Worker
+	invoiceWorker       *workspaceBilling.Worker
+	mailer              mailer.Mailer
 
 	Config *Config
 }
@@ -26,6 +45,12 @@ func (s *Server) Init() error {
 
 	s.oauthTokenSecretKey = []byte(s.Config.OAuthSecretKey)
 
+	oauthClients, err := s.Config.OAuthClientCredentials()
+	if err != nil {
+		panic(err)
+	}
+	s.oauthClients = oauthClients
+
 	db, err := initDB(s.Config.DatabaseURL)
 	if err != nil {
 		panic(err)
@@ -38,14 +63,51 @@ func (s *Server) Init() error {
 	}
 	s.githubAppConfig = githubAppConfig
 
+	secretKeys, err := s.Config.SecretKeyProvider()
+	if err != nil {
+		panic(err)
+	}
+	s.secretKeys = secretKeys
+
+	s.mailer = s.Config.Mailer()
+
+	janitorConfig := janitor.Config{
+		Interval:  s.Config.JanitorInterval,
+		BatchSize: s.Config.JanitorBatchSize,
+	}
+	s.janitor = janitor.New(s.db, janitorConfig, s.janitorTasks()...)
+	s.dataExportWorker = userDataExport.NewWorker(s.db, s.Config.DataExportWorkerInterval)
+	s.webhookWorker = workspaceWebhook.NewWorker(s.db, s.secretKeys, s.Config.WebhookWorkerInterval)
+	s.invoiceWorker = workspaceBilling.NewWorker(s.db, s.Config.InvoiceWorkerInterval)
+
 	s.initRoutes()
 	return nil
 }
 
 func (s *Server) Start() error {
+	go s.janitor.Start(context.Background())
+	go s.dataExportWorker.Start(context.Background())
+	go s.webhookWorker.Start(context.Background())
+	go s.invoiceWorker.Start(context.Background())
 	return http.ListenAndServe("0.0.0.0:3000", s.router)
 }
 
+func (s *Server) janitorTasks() []janitor.Task {
+	return []janitor.Task{
+		{Name: "oauth_authorization_codes", Run: auth.PurgeExpiredOauthAuthorizationCodes},
+		{Name: "oauth_tokens", Run: auth.PurgeExpiredOauthTokens(s.Config.JanitorOauthTokenRetention)},
+		{Name: "email_verifications", Run: emailverification.PurgeExpired},
+		{Name: "deleted_users", Run: user.AnonymizeDeletedUsers(s.Config.JanitorDeletedUserGracePeriod)},
+		{Name: "user_data_export_archives", Run: userDataExport.PurgeExpiredArchives},
+		// resources go first, projects are only purged once their resources are gone
+		{Name: "trashed_resources", Run: workspaceTrash.PurgeExpiredResources},
+		{Name: "trashed_projects", Run: workspaceTrash.PurgeExpiredProjects},
+		{Name: "deleted_project_environments", Run: workspaceProjectEnvironment.PurgeDeleted},
+		{Name: "trashed_repository_connections", Run: workspaceTrash.PurgeExpiredRepositoryConnections},
+		{Name: "webhook_deliveries", Run: workspaceWebhook.PurgeOldDeliveries(s.Config.JanitorWebhookDeliveryRetention)},
+	}
+}
+
 func initDB(dbURL string) (*hansip.Cluster, error) {
 	opt, err := pg.ParseURL(dbURL)
 	if err != nil {
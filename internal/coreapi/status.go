@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/janitor"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
 )
 
@@ -21,3 +22,14 @@ func statusHandler(db *hansip.Cluster) http.HandlerFunc {
 		apihelper.JSON(w, http.StatusOK, db.Health())
 	}
 }
+
+// @Id api.status.janitor
+// @Summary Get number of rows removed by janitor since process start
+// @Router /status/janitor [get]
+// @Produce json
+// @Success 200 {object} janitor.Stats
+func janitorStatusHandler(j *janitor.Janitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apihelper.JSON(w, http.StatusOK, j.Stats())
+	}
+}
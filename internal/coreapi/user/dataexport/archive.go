@@ -0,0 +1,154 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// archiveFormatVersion must be incremented whenever dataExportArchive changes in a backward incompatible way
+const archiveFormatVersion = 2
+
+// dataExportArchive is the content of personal data archive.
+// JSON archive contains this object as is. Zip archive contains manifest.json which holds
+// format_version, generated_at and files, plus one JSON file for every other field, e.g. sessions.json.
+type dataExportArchive struct {
+	FormatVersion  int                     `json:"format_version"`
+	GeneratedAt    time.Time               `json:"generated_at"`
+	Profile        *core.User              `json:"profile"`
+	Identities     []*archiveIdentity      `json:"identities"`
+	Sessions       []*archiveSession       `json:"sessions"`
+	Workspaces     []*archiveWorkspace     `json:"workspaces"`
+	Activities     []*archiveActivity      `json:"activities"`
+	SecurityEvents []*archiveSecurityEvent `json:"security_events"`
+}
+
+// archiveManifest is manifest.json of zip archive
+type archiveManifest struct {
+	FormatVersion int       `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Files         []string  `json:"files"`
+}
+
+// archiveIdentity represents third party account linked for sign in
+type archiveIdentity struct {
+	Provider   string `json:"provider"`
+	Identifier string `json:"identifier"`
+}
+
+// archiveSession represents issued access token
+type archiveSession struct {
+	ID                 int64      `json:"id"`
+	RequesterIP        string     `json:"requester_ip"`
+	RequesterUserAgent string     `json:"requester_user_agent"`
+	ExpiresAt          time.Time  `json:"expires_at"`
+	RevokedAt          *time.Time `json:"revoked_at"`
+	Impersonated       bool       `json:"impersonated"`
+}
+
+// archiveWorkspace represents workspace membership
+type archiveWorkspace struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	Name        string    `json:"name"`
+	AccessLevel string    `json:"access_level"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// archiveActivity represents workspace activity made by the user
+type archiveActivity struct {
+	WorkspaceID int64                  `json:"workspace_id"`
+	Verb        string                 `json:"verb"`
+	TargetType  string                 `json:"target_type"`
+	TargetID    *int64                 `json:"target_id"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// archiveSecurityEvent represents security relevant event on the account
+type archiveSecurityEvent struct {
+	Type       string            `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Details    map[string]string `json:"details"`
+}
+
+type archiveSection struct {
+	name  string
+	fetch func(ctx context.Context, archive *dataExportArchive, userID int64) error
+}
+
+var archiveSections = []archiveSection{
+	{name: "profile", fetch: fetchProfile},
+	{name: "identities", fetch: fetchIdentities},
+	{name: "sessions", fetch: fetchSessions},
+	{name: "workspaces", fetch: fetchWorkspaces},
+	{name: "activities", fetch: fetchActivities},
+	{name: "security_events", fetch: fetchSecurityEvents},
+}
+
+// buildArchive collects user data, progress is called with percentage after each section is collected
+func buildArchive(ctx context.Context, export *core.DataExport, progress func(int) error) ([]byte, error) {
+	archive := &dataExportArchive{
+		FormatVersion: archiveFormatVersion,
+		GeneratedAt:   time.Now(),
+	}
+	for i, section := range archiveSections {
+		if err := section.fetch(ctx, archive, export.UserID); err != nil {
+			return nil, err
+		}
+		if err := progress((i + 1) * 100 / (len(archiveSections) + 1)); err != nil {
+			return nil, err
+		}
+	}
+
+	if export.Format == core.DataExportFormatZip {
+		return encodeZip(archive)
+	}
+	return json.MarshalIndent(archive, "", "  ")
+}
+
+func encodeZip(archive *dataExportArchive) ([]byte, error) {
+	files := map[string]interface{}{
+		"profile.json":         archive.Profile,
+		"identities.json":      archive.Identities,
+		"sessions.json":        archive.Sessions,
+		"workspaces.json":      archive.Workspaces,
+		"activities.json":      archive.Activities,
+		"security_events.json": archive.SecurityEvents,
+	}
+	manifest := archiveManifest{
+		FormatVersion: archive.FormatVersion,
+		GeneratedAt:   archive.GeneratedAt,
+	}
+	for _, section := range archiveSections {
+		manifest.Files = append(manifest.Files, section.name+".json")
+	}
+
+	var buff bytes.Buffer
+	writer := zip.NewWriter(&buff)
+	if err := writeZipJSON(writer, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	for _, name := range manifest.Files {
+		if err := writeZipJSON(writer, name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+func writeZipJSON(writer *zip.Writer, name string, content interface{}) error {
+	file, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(content)
+}
@@ -0,0 +1,308 @@
+package dataexport
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var errExportInProgress = errors.New("another data export is in progress")
+
+func createExport(ctx context.Context, export *core.DataExport) error {
+	db := appctx.Database(ctx)
+
+	// only one unfinished export is allowed per user, unique index makes concurrent requests conflict
+	var query = `
+        insert into user_data_exports (user_id, format, created_at)
+        values (?, ?, now())
+        on conflict (user_id) where status in ('pending', 'running') do nothing
+        returning *
+    `
+	err := db.WriterQuery(export, query, export.UserID, export.Format)
+	if err != nil {
+		return err
+	}
+	if export.ID == 0 {
+		return errExportInProgress
+	}
+	return nil
+}
+
+func getExport(ctx context.Context, userID, id int64) (*core.DataExport, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select id, user_id, format, status, progress, expires_at, created_at, started_at, completed_at
+        from user_data_exports
+        where id = ? and user_id = ?
+    `
+	var export core.DataExport
+	err := db.Query(&export, query, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.ID == 0 {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+func getDownloadableExport(ctx context.Context, id int64) (*core.DataExport, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from user_data_exports
+        where
+            id = ?
+            and status = 'completed'
+            and archive is not null
+            and expires_at > now()
+    `
+	var export core.DataExport
+	err := db.Query(&export, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.ID == 0 {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+// claimPendingExport marks oldest pending export as running, exports which have been running
+// for too long since they were started are considered abandoned by crashed worker and claimed again
+func claimPendingExport(ctx context.Context) (*core.DataExport, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update user_data_exports
+        set status = 'running', progress = 0, started_at = now()
+        where id = (
+            select id
+            from user_data_exports
+            where
+                status = 'pending'
+                or (status = 'running' and started_at < now() - interval '1 hour')
+            order by created_at
+            limit 1
+            for update skip locked
+        )
+        returning id, user_id, format, status, progress, created_at, started_at
+    `
+	var export core.DataExport
+	err := db.WriterQuery(&export, query)
+	if err != nil {
+		return nil, err
+	}
+	if export.ID == 0 {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+func updateExportProgress(ctx context.Context, id int64, progress int) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update user_data_exports
+        set progress = ?
+        where id = ? and status = 'running'
+    `
+	return db.WriterExec(query, progress, id)
+}
+
+func completeExport(ctx context.Context, id int64, archive []byte) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update user_data_exports
+        set
+            status = 'completed',
+            progress = 100,
+            archive = ?,
+            completed_at = now(),
+            expires_at = now() + make_interval(secs => ?)
+        where id = ?
+    `
+	return db.WriterExec(query, archive, core.DataExportMaxDuration.Seconds(), id)
+}
+
+func failExport(ctx context.Context, id int64, reason string) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update user_data_exports
+        set status = 'failed', error = ?, completed_at = now()
+        where id = ?
+    `
+	return db.WriterExec(query, reason, id)
+}
+
+// PurgeExpiredArchives removes at most batchSize archives which are past their expiry
+func PurgeExpiredArchives(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with purged as (
+            update user_data_exports
+            set archive = null
+            where id in (
+                select id
+                from user_data_exports
+                where expires_at < now() and archive is not null
+                limit ?
+            )
+            returning 1
+        )
+        select count(*) as count from purged
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, batchSize)
+	return returned.Count, err
+}
+
+func fetchProfile(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from users
+        where id = ?
+    `
+	var user core.User
+	if err := db.Query(&user, query, userID); err != nil {
+		return err
+	}
+	archive.Profile = &user
+	return nil
+}
+
+func fetchIdentities(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	archive.Identities = []*archiveIdentity{}
+	if archive.Profile.GithubLoginUsername != nil {
+		archive.Identities = append(archive.Identities, &archiveIdentity{
+			Provider:   core.OauthProviderGithub,
+			Identifier: *archive.Profile.GithubLoginUsername,
+		})
+	}
+	if archive.Profile.GoogleLoginEmail != nil {
+		archive.Identities = append(archive.Identities, &archiveIdentity{
+			Provider:   core.OauthProviderGoogle,
+			Identifier: *archive.Profile.GoogleLoginEmail,
+		})
+	}
+	return nil
+}
+
+func fetchSessions(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            id,
+            host(requester_ip) as requester_ip,
+            requester_user_agent,
+            expires_at,
+            deleted_at as revoked_at,
+            impersonator_user_id is not null as impersonated
+        from oauth_tokens
+        where user_id = ?
+        order by id
+    `
+	archive.Sessions = []*archiveSession{}
+	return db.Query(&archive.Sessions, query, userID)
+}
+
+func fetchWorkspaces(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            workspaces.id as workspace_id,
+            workspaces.name,
+            workspace_users.access_level,
+            workspace_users.created_at as joined_at
+        from workspace_users
+        join workspaces on workspaces.id = workspace_users.workspace_id
+        where workspace_users.user_id = ? and workspace_users.deleted_at is null
+        order by workspace_users.created_at
+    `
+	archive.Workspaces = []*archiveWorkspace{}
+	return db.Query(&archive.Workspaces, query, userID)
+}
+
+func fetchActivities(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select workspace_id, verb, target_type, target_id, metadata, created_at
+        from workspace_activity_logs
+        where actor_user_id = ?
+        order by id
+    `
+	archive.Activities = []*archiveActivity{}
+	return db.Query(&archive.Activities, query, userID)
+}
+
+func fetchSecurityEvents(ctx context.Context, archive *dataExportArchive, userID int64) error {
+	db := appctx.Database(ctx)
+
+	archive.SecurityEvents = []*archiveSecurityEvent{}
+
+	var impersonations []struct {
+		Method    string
+		Path      string
+		CreatedAt time.Time
+	}
+	var impersonationQuery = `
+        select method, path, created_at
+        from user_impersonation_logs
+        where user_id = ?
+        order by created_at
+    `
+	if err := db.Query(&impersonations, impersonationQuery, userID); err != nil {
+		return err
+	}
+	for _, entry := range impersonations {
+		archive.SecurityEvents = append(archive.SecurityEvents, &archiveSecurityEvent{
+			Type:       "support_impersonation",
+			OccurredAt: entry.CreatedAt,
+			Details: map[string]string{
+				"method": entry.Method,
+				"path":   entry.Path,
+			},
+		})
+	}
+
+	var emailChanges []struct {
+		Email      string
+		VerifiedAt time.Time
+	}
+	var emailChangeQuery = `
+        select email, verified_at
+        from email_verifications
+        where
+            purpose = ?
+            and payload->>'user_id' = ?
+            and verified_at is not null
+        order by verified_at
+    `
+	if err := db.Query(&emailChanges, emailChangeQuery, core.EmailVerificationPurposeChangeEmail, strconv.FormatInt(userID, 10)); err != nil {
+		return err
+	}
+	for _, entry := range emailChanges {
+		archive.SecurityEvents = append(archive.SecurityEvents, &archiveSecurityEvent{
+			Type:       "email_changed",
+			OccurredAt: entry.VerifiedAt,
+			Details: map[string]string{
+				"email": entry.Email,
+			},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateExport(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	export := &core.DataExport{UserID: user.ID, Format: core.DataExportFormatJSON}
+	err := createExport(ctx, export)
+	assert.NoError(t, err)
+	assert.True(t, export.ID > 0)
+	assert.Equal(t, core.DataExportStatusPending, export.Status)
+
+	// only one unfinished export per user
+	err = createExport(ctx, &core.DataExport{UserID: user.ID, Format: core.DataExportFormatZip})
+	assert.Equal(t, errExportInProgress, err)
+}
+
+func TestClaimPendingExport(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	export := &core.DataExport{UserID: user.ID, Format: core.DataExportFormatJSON}
+	assert.NoError(t, createExport(ctx, export))
+
+	// export which waited long in queue is not reclaimed once it started running
+	err := appctx.Database(ctx).WriterExec("update user_data_exports set created_at = now() - interval '2 hours' where id = ?", export.ID)
+	assert.NoError(t, err)
+
+	var claimed *core.DataExport
+	for {
+		next, err := claimPendingExport(ctx)
+		assert.NoError(t, err)
+		if next == nil || err != nil {
+			break
+		}
+		if next.ID == export.ID {
+			claimed = next
+		}
+	}
+	if assert.NotNil(t, claimed) {
+		assert.NotNil(t, claimed.StartedAt)
+	}
+
+	next, err := claimPendingExport(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+}
+
+func TestProcessExport(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, user.ID, "owner")
+	testutil.OauthTokenFactory(ctx, user.ID, "secret")
+
+	for _, format := range []string{core.DataExportFormatJSON, core.DataExportFormatZip} {
+		t.Run(format, func(t *testing.T) {
+			export := &core.DataExport{UserID: user.ID, Format: format}
+			err := createExport(ctx, export)
+			assert.NoError(t, err)
+
+			worker := &Worker{}
+			for {
+				processed, err := worker.processNext(ctx)
+				assert.NoError(t, err)
+				if !processed {
+					break
+				}
+			}
+
+			retrieved, err := getDownloadableExport(ctx, export.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, core.DataExportStatusCompleted, retrieved.Status)
+			assert.Equal(t, 100, retrieved.Progress)
+
+			var archive dataExportArchive
+			switch format {
+			case core.DataExportFormatJSON:
+				err = json.Unmarshal(retrieved.Archive, &archive)
+				assert.NoError(t, err)
+				assert.Equal(t, archiveFormatVersion, archive.FormatVersion)
+				assert.Equal(t, user.ID, archive.Profile.ID)
+				assert.Len(t, archive.Sessions, 1)
+				assert.Len(t, archive.Workspaces, 1)
+				assert.Len(t, archive.Identities, 2)
+			case core.DataExportFormatZip:
+				reader, err := zip.NewReader(bytes.NewReader(retrieved.Archive), int64(len(retrieved.Archive)))
+				assert.NoError(t, err)
+				assert.Len(t, reader.File, len(archiveSections)+1)
+				assert.Equal(t, "manifest.json", reader.File[0].Name)
+			}
+		})
+	}
+}
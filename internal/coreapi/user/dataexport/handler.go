@@ -0,0 +1,206 @@
+package dataexport
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// downloadLinkMaxDuration is how long a download link stays valid after it is issued
+const downloadLinkMaxDuration = 15 * time.Minute
+
+type dataExportResponse struct {
+	*core.DataExport
+	// DownloadURL is only set when archive is ready, it expires shortly after being issued
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// @Id api.v1.users.export.create
+// @Summary Start building personal data archive
+// @Tags Users
+// @Security oauthAccessToken
+// @Accept json
+// @Param param body createExportParam true "Request body"
+// @Router /v1/users/me/export [post]
+// @Produce json
+// @Success 202 {object} dataExportResponse
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	user := appctx.AuthenticatedUser(r.Context())
+
+	param := createExportParam{
+		Format: core.DataExportFormatJSON,
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+	}
+	if err := param.Validate(r.Context()); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	export := &core.DataExport{
+		UserID: user.ID,
+		Format: param.Format,
+	}
+	err := createExport(r.Context(), export)
+	if err == errExportInProgress {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"export": "another export is in progress",
+		})
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusAccepted, dataExportResponse{DataExport: export})
+}
+
+// @Id api.v1.users.export.get
+// @Summary Get personal data export status
+// @Tags Users
+// @Security oauthAccessToken
+// @Param export_id path integer true "Export id"
+// @Router /v1/users/me/exports/{export_id} [get]
+// @Produce json
+// @Success 200 {object} dataExportResponse
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(secretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		user := appctx.AuthenticatedUser(r.Context())
+
+		exportID, _ := strconv.ParseInt(chi.URLParam(r, "export_id"), 10, 64)
+		if exportID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"export_id": "invalid",
+			})
+			return
+		}
+
+		export, err := getExport(r.Context(), user.ID, exportID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if export == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		resp := dataExportResponse{DataExport: export}
+		if export.Status == core.DataExportStatusCompleted && export.ExpiresAt != nil && export.ExpiresAt.After(time.Now()) {
+			resp.DownloadURL, err = buildDownloadURL(secretKey, export)
+			if err != nil {
+				apihelper.InternalServerErrResp(w, err)
+				return
+			}
+		}
+		apihelper.JSON(w, http.StatusOK, resp)
+	}
+	return http.HandlerFunc(handler)
+}
+
+// @Id api.v1.dataExports.download
+// @Summary Download personal data archive using signed link
+// @Tags Users
+// @Param export_id path integer true "Export id"
+// @Param expires query integer true "Link expiry as unix timestamp"
+// @Param signature query string true "Link signature"
+// @Router /v1/data-exports/{export_id}/download [get]
+// @Produce json
+// @Produce application/zip
+// @Success 200 {object} dataExportArchive
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDownload(secretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		exportID, _ := strconv.ParseInt(chi.URLParam(r, "export_id"), 10, 64)
+		expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		signature, err := base64.URLEncoding.DecodeString(r.URL.Query().Get("signature"))
+		if exportID <= 0 || expires <= 0 || err != nil {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"download_link": "invalid",
+			})
+			return
+		}
+
+		valid, err := core.ValidateHMAC(secretKey, downloadSignaturePayload(exportID, expires), signature)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if !valid || time.Unix(expires, 0).Before(time.Now()) {
+			apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+				"download_link": "invalid or expired",
+			})
+			return
+		}
+
+		export, err := getDownloadableExport(r.Context(), exportID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if export == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		contentType := "application/json; charset=utf-8"
+		if export.Format == core.DataExportFormatZip {
+			contentType = "application/zip"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="awanku-data-export-%d.%s"`, export.ID, export.Format))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(export.Archive)
+	}
+	return http.HandlerFunc(handler)
+}
+
+func buildDownloadURL(secretKey []byte, export *core.DataExport) (string, error) {
+	expiresAt := time.Now().Add(downloadLinkMaxDuration)
+	if export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+	expires := expiresAt.Unix()
+
+	signature, err := core.HashHMAC(secretKey, downloadSignaturePayload(export.ID, expires))
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", base64.URLEncoding.EncodeToString(signature))
+	return fmt.Sprintf("/v1/data-exports/%d/download?%s", export.ID, query.Encode()), nil
+}
+
+func downloadSignaturePayload(exportID, expires int64) []byte {
+	return []byte(fmt.Sprintf("data-export:%d:%d", exportID, expires))
+}
@@ -0,0 +1,18 @@
+package dataexport
+
+import (
+	"context"
+
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type createExportParam struct {
+	Format string `json:"format" validate:"required" enums:"json,zip"`
+}
+
+func (p createExportParam) Validate(ctx context.Context) error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Format, validation.Required, validation.In(core.DataExportFormatJSON, core.DataExportFormatZip)),
+	)
+}
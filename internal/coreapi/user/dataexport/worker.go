@@ -0,0 +1,72 @@
+package dataexport
+
+import (
+	"context"
+	"log"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+)
+
+// Worker builds pending personal data archives in background
+type Worker struct {
+	db       *hansip.Cluster
+	interval time.Duration
+}
+
+// NewWorker creates new data export worker
+func NewWorker(db *hansip.Cluster, interval time.Duration) *Worker {
+	return &Worker{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Start processes pending exports until ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	ctx = context.WithValue(ctx, appctx.KeyDatabase, w.db)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// drain all pending exports before waiting for next tick
+		for {
+			processed, err := w.processNext(ctx)
+			if err != nil {
+				log.Println("data export worker failed:", err)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) processNext(ctx context.Context) (bool, error) {
+	export, err := claimPendingExport(ctx)
+	if err != nil {
+		return false, err
+	}
+	if export == nil {
+		return false, nil
+	}
+
+	archive, err := buildArchive(ctx, export, func(progress int) error {
+		return updateExportProgress(ctx, export.ID, progress)
+	})
+	if err != nil {
+		if failErr := failExport(ctx, export.ID, err.Error()); failErr != nil {
+			return true, failErr
+		}
+		return true, err
+	}
+	return true, completeExport(ctx, export.ID, archive)
+}
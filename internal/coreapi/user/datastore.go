@@ -0,0 +1,300 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/contract"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-pg/pg/v9"
+)
+
+type userStore struct {
+	db *hansip.Cluster
+}
+
+func newUserStore(ctx context.Context) contract.UserStore {
+	return &userStore{db: appctx.Database(ctx)}
+}
+
+func (s *userStore) GetOrCreateByEmail(user *core.User) error {
+	var query = `
+        insert into users (name, email, google_login_email, github_login_username)
+        values (?, ?, ?, ?)
+        on conflict (email) do update set updated_at = now()
+        returning *
+    `
+	return s.db.WriterQuery(user, query, user.Name, user.Email, user.GoogleLoginEmail, user.GithubLoginUsername)
+}
+
+func (s *userStore) GetByID(id int64) (*core.User, error) {
+	var query = `
+        select *
+        from users
+        where id = ? and deleted_at is null
+    `
+	var returned core.User
+	err := s.db.Query(&returned, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if returned.ID == 0 {
+		return nil, nil
+	}
+	return &returned, nil
+}
+
+// Save updates editable user fields. user.UpdatedAt must be the value that was read,
+// otherwise ErrUserModified is returned.
+func (s *userStore) Save(user *core.User) error {
+	var query = `
+        update users
+        set
+            name = ?,
+            email = ?,
+            pending_email = ?,
+            preferences = ?,
+            updated_at = now()
+        where
+            id = ?
+            and deleted_at is null
+            and updated_at is not distinct from ?
+        returning updated_at
+    `
+	var returned struct {
+		UpdatedAt *time.Time
+	}
+	err := s.db.WriterQuery(&returned, query, user.Name, user.Email, user.PendingEmail, user.Preferences, user.ID, user.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return contract.ErrEmailTaken
+		}
+		return err
+	}
+	if returned.UpdatedAt == nil {
+		return contract.ErrUserModified
+	}
+	user.UpdatedAt = returned.UpdatedAt
+	return nil
+}
+
+func isEmailTaken(ctx context.Context, email string, exceptUserID int64) (bool, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select exists (
+            select 1
+            from users
+            where email = ? and id != ?
+        ) as taken
+    `
+	var returned struct {
+		Taken bool
+	}
+	err := db.Query(&returned, query, email, exceptUserID)
+	return returned.Taken, err
+}
+
+// soleOwnedWorkspace represents workspace where the user is the only owner
+type soleOwnedWorkspace struct {
+	core.Workspace
+	OtherMembers    int `json:"other_members"`
+	ActiveResources int `json:"active_resources"`
+}
+
+var soleOwnedWorkspacesQuery = `
+    select
+        workspaces.*,
+        (
+            select count(*)
+            from workspace_users others
+            where
+                others.workspace_id = workspaces.id
+                and others.user_id != workspace_users.user_id
+                and others.deleted_at is null
+        ) as other_members,
+        (
+            select count(*)
+            from resources
+            join projects on projects.id = resources.project_id
+            where
+                projects.workspace_id = workspaces.id
+                and projects.deleted_at is null
+                and resources.deleted_at is null
+        ) as active_resources
+    from workspaces
+    join workspace_users on workspaces.id = workspace_users.workspace_id
+    where
+        workspace_users.user_id = ?
+        and workspace_users.access_level = 'owner'
+        and workspace_users.deleted_at is null
+        and workspaces.deleted_at is null
+        and not exists (
+            select 1
+            from workspace_users owners
+            where
+                owners.workspace_id = workspaces.id
+                and owners.user_id != workspace_users.user_id
+                and owners.access_level = 'owner'
+                and owners.deleted_at is null
+        )
+`
+
+func getSoleOwnedWorkspaces(ctx context.Context, userID int64) ([]*soleOwnedWorkspace, error) {
+	db := appctx.Database(ctx)
+
+	var workspaces []*soleOwnedWorkspace
+	err := db.Query(&workspaces, soleOwnedWorkspacesQuery, userID)
+	if err != nil {
+		return []*soleOwnedWorkspace{}, err
+	}
+	return workspaces, nil
+}
+
+var (
+	errDeletionPreviewChanged = errors.New("owned workspaces changed since deletion was previewed")
+	errActiveResources        = errors.New("owned workspaces still have active resources")
+)
+
+// deleteAccount soft deletes user, revokes all tokens, removes memberships and
+// soft deletes given workspaces together with their projects and repository connections,
+// projects are unlinked from repositories so connections can be purged later.
+// Solely owned workspaces are checked again inside the transaction, deletion is refused
+// when they differ from confirmed workspaces or still have active resources.
+func deleteAccount(ctx context.Context, userID int64, workspaceIDs []int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var workspaces []*soleOwnedWorkspace
+	if err = tx.Query(&workspaces, soleOwnedWorkspacesQuery+" for update of workspaces", userID); err != nil {
+		return err
+	}
+	confirmed := make(map[int64]bool, len(workspaceIDs))
+	for _, id := range workspaceIDs {
+		confirmed[id] = true
+	}
+	if len(workspaces) != len(confirmed) {
+		return errDeletionPreviewChanged
+	}
+	for _, workspace := range workspaces {
+		if workspace.OtherMembers > 0 || !confirmed[workspace.ID] {
+			return errDeletionPreviewChanged
+		}
+		if workspace.ActiveResources > 0 {
+			return errActiveResources
+		}
+	}
+
+	if len(workspaceIDs) > 0 {
+		var queries = []string{
+			`update projects set repository_connection_id = null, repository_name = null, repository_branch = null, repository_root_dir = null where workspace_id in (?) and repository_connection_id is not null`,
+			`update workspace_repository_connections set deleted_at = now() where workspace_id in (?) and deleted_at is null`,
+			`update projects set deleted_at = now() where workspace_id in (?) and deleted_at is null`,
+			`update workspaces set deleted_at = now() where id in (?) and deleted_at is null`,
+		}
+		for _, query := range queries {
+			if err = tx.Exec(query, pg.In(workspaceIDs)); err != nil {
+				return err
+			}
+		}
+	}
+
+	var queries = []string{
+		`update oauth_tokens set deleted_at = now() where user_id = ? and deleted_at is null`,
+		`update workspace_users set deleted_at = now() where user_id = ? and deleted_at is null`,
+		`update project_users set deleted_at = now() where user_id = ? and deleted_at is null`,
+		`update users set deleted_at = now() where id = ? and deleted_at is null`,
+	}
+	for _, query := range queries {
+		if err = tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnonymizeDeletedUsers returns function which anonymizes at most batchSize users
+// which have been deleted for longer than gracePeriod
+func AnonymizeDeletedUsers(gracePeriod time.Duration) func(ctx context.Context, batchSize int) (int64, error) {
+	return func(ctx context.Context, batchSize int) (int64, error) {
+		db := appctx.Database(ctx)
+
+		// every statement sees users before anonymization, so their original email can still be matched
+		var query = `
+            with targets as (
+                select id, email, 'deleted-' || id || '@users.awanku.invalid' as anonymized_email
+                from users
+                where
+                    deleted_at < now() - make_interval(secs => ?)
+                    and anonymized_at is null
+                limit ?
+                for update skip locked
+            ),
+            anonymized as (
+                update users
+                set
+                    name = 'Deleted user',
+                    email = targets.anonymized_email,
+                    google_login_email = null,
+                    github_login_username = null,
+                    pending_email = null,
+                    preferences = '{}',
+                    anonymized_at = now()
+                from targets
+                where users.id = targets.id
+                returning 1
+            ),
+            anonymized_tokens as (
+                update oauth_tokens
+                set requester_ip = '0.0.0.0', requester_user_agent = ''
+                where user_id in (select id from targets)
+            ),
+            anonymized_impersonation_logs as (
+                update user_impersonation_logs
+                set requester_ip = '0.0.0.0'
+                where user_id in (select id from targets) or actor_user_id in (select id from targets)
+            ),
+            deleted_email_verifications as (
+                delete from email_verifications
+                using targets
+                where
+                    email_verifications.payload->>'user_id' = targets.id::text
+                    or lower(email_verifications.email) = lower(targets.email)
+            ),
+            -- pending invitations are addressed to the email, not to the deleted account, so they are left as is
+            anonymized_invitations as (
+                update workspace_invitations
+                set email = targets.anonymized_email
+                from targets
+                where
+                    workspace_invitations.accepted_user_id = targets.id
+                    or (workspace_invitations.status != 'pending' and lower(workspace_invitations.email) = lower(targets.email))
+            ),
+            anonymized_activities as (
+                update workspace_activity_logs
+                set metadata = jsonb_set(metadata, '{email}', to_jsonb(targets.anonymized_email))
+                from targets
+                where
+                    workspace_activity_logs.target_type = 'invitation'
+                    and lower(workspace_activity_logs.metadata->>'email') = lower(targets.email)
+            )
+            select count(*) as count from anonymized
+        `
+		var returned struct{ Count int64 }
+		err := db.WriterQuery(&returned, query, gracePeriod.Seconds(), batchSize)
+		return returned.Count, err
+	}
+}
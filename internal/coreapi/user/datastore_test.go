@@ -0,0 +1,222 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/contract"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStoreSave(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	store := newUserStore(ctx)
+
+	t.Run("update profile", func(t *testing.T) {
+		user := testutil.UserFactory(ctx, 1)[0]
+		user.Name = "new name"
+		user.Preferences = core.UserPreferences{Theme: "dark", Language: "id", Timezone: "Asia/Jakarta"}
+
+		err := store.Save(user)
+		assert.NoError(t, err)
+		assert.NotNil(t, user.UpdatedAt)
+
+		retrieved, err := store.GetByID(user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "new name", retrieved.Name)
+		assert.Equal(t, user.Preferences, retrieved.Preferences)
+		assert.True(t, user.UpdatedAt.Equal(*retrieved.UpdatedAt))
+	})
+
+	t.Run("stale user", func(t *testing.T) {
+		user := testutil.UserFactory(ctx, 1)[0]
+		stale := *user
+
+		err := store.Save(user)
+		assert.NoError(t, err)
+
+		stale.Name = "stale name"
+		err = store.Save(&stale)
+		assert.Equal(t, contract.ErrUserModified, err)
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		users := testutil.UserFactory(ctx, 2)
+		users[0].Email = users[1].Email
+
+		err := store.Save(users[0])
+		assert.Equal(t, contract.ErrEmailTaken, err)
+	})
+}
+
+func TestIsEmailTaken(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	users := testutil.UserFactory(ctx, 2)
+
+	taken, err := isEmailTaken(ctx, users[1].Email, users[0].ID)
+	assert.NoError(t, err)
+	assert.True(t, taken)
+
+	taken, err = isEmailTaken(ctx, users[0].Email, users[0].ID)
+	assert.NoError(t, err)
+	assert.False(t, taken)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	users := testutil.UserFactory(ctx, 2)
+	user, other := users[0], users[1]
+	workspaces := testutil.WorkspaceFactory(ctx, 3)
+	alone, shared, coOwned := workspaces[0], workspaces[1], workspaces[2]
+	testutil.WorkspaceUserFactory(ctx, alone.ID, user.ID, "owner")
+	testutil.WorkspaceUserFactory(ctx, shared.ID, user.ID, "owner")
+	testutil.WorkspaceUserFactory(ctx, shared.ID, other.ID, "viewer")
+	testutil.WorkspaceUserFactory(ctx, coOwned.ID, user.ID, "owner")
+	testutil.WorkspaceUserFactory(ctx, coOwned.ID, other.ID, "owner")
+	token := testutil.OauthTokenFactory(ctx, user.ID, "secret")
+	connection := testutil.RepositoryConnectionFactory(ctx, alone.ID)
+	project := testutil.ProjectFactory(ctx, alone.ID)
+	err := appctx.Database(ctx).WriterExec("update projects set repository_connection_id = ?, repository_name = 'awanku/app', repository_branch = 'master', repository_root_dir = '/' where id = ?", connection.ID, project.ID)
+	assert.NoError(t, err)
+
+	soleOwned, err := getSoleOwnedWorkspaces(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, soleOwned, 2)
+	for _, workspace := range soleOwned {
+		switch workspace.ID {
+		case alone.ID:
+			assert.Equal(t, 0, workspace.OtherMembers)
+		case shared.ID:
+			assert.Equal(t, 1, workspace.OtherMembers)
+		default:
+			t.Errorf("unexpected workspace %d", workspace.ID)
+		}
+	}
+
+	// ownership of shared workspace has to be transferred before account can be deleted
+	err = deleteAccount(ctx, user.ID, []int64{alone.ID})
+	assert.Equal(t, errDeletionPreviewChanged, err)
+	err = appctx.Database(ctx).WriterExec("update workspace_users set access_level = 'owner' where workspace_id = ? and user_id = ?", shared.ID, other.ID)
+	assert.NoError(t, err)
+
+	err = deleteAccount(ctx, user.ID, []int64{alone.ID})
+	assert.NoError(t, err)
+
+	retrieved, err := newUserStore(ctx).GetByID(user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved)
+
+	var state struct {
+		TokenDeleted     bool
+		WorkspaceDeleted bool
+		Memberships      int
+		ProjectLinked    bool
+	}
+	err = appctx.Database(ctx).Query(&state, `
+        select
+            (select deleted_at is not null from oauth_tokens where id = ?) as token_deleted,
+            (select deleted_at is not null from workspaces where id = ?) as workspace_deleted,
+            (select count(*) from workspace_users where user_id = ? and deleted_at is null) as memberships,
+            (select repository_connection_id is not null from projects where id = ?) as project_linked
+    `, token.ID, alone.ID, user.ID, project.ID)
+	assert.NoError(t, err)
+	assert.True(t, state.TokenDeleted)
+	assert.True(t, state.WorkspaceDeleted)
+	assert.Equal(t, 0, state.Memberships)
+	assert.False(t, state.ProjectLinked)
+}
+
+func TestDeleteAccountRechecksOwnedWorkspaces(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, user.ID, "owner")
+
+	err := deleteAccount(ctx, user.ID, nil)
+	assert.Equal(t, errDeletionPreviewChanged, err)
+
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	err = appctx.Database(ctx).WriterExec("insert into resources (name, type, payload, project_id, environment_id) select 'db', 'postgres', '{}', project_id, id from project_environments where project_id = ?", project.ID)
+	assert.NoError(t, err)
+
+	err = deleteAccount(ctx, user.ID, []int64{workspace.ID})
+	assert.Equal(t, errActiveResources, err)
+
+	retrieved, err := newUserStore(ctx).GetByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, retrieved)
+}
+
+func TestAnonymizeDeletedUsers(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	users := testutil.UserFactory(ctx, 2)
+	user, admin := users[0], users[1]
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+
+	invitation := testutil.WorkspaceInvitationFactory(ctx, workspace.ID, admin.ID, user.Email)
+	err := db.WriterExec("update workspace_invitations set status = 'accepted', accepted_user_id = ?, accepted_at = now() where id = ?", user.ID, invitation.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into workspace_activity_logs (workspace_id, actor_user_id, verb, target_type, target_id, metadata, created_at)
+        values (?, ?, 'created', 'invitation', ?, jsonb_build_object('email', ?::text, 'access_level', 'viewer'), now())
+    `, workspace.ID, admin.ID, invitation.ID, user.Email)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into email_verifications (purpose, email, code_hash, payload, expires_at, verified_at)
+        values (?, 'new@example.com', 'code', jsonb_build_object('user_id', ?::text), now() - interval '1 day', now() - interval '2 days')
+    `, core.EmailVerificationPurposeChangeEmail, user.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into user_impersonation_logs (oauth_token_id, actor_user_id, user_id, method, path, requester_ip)
+        values (1, ?, ?, 'GET', '/v1/users/me', '10.1.2.3')
+    `, admin.ID, user.ID)
+	assert.NoError(t, err)
+
+	err = db.WriterExec("update users set deleted_at = now() - interval '2 days' where id = ?", user.ID)
+	assert.NoError(t, err)
+
+	_, err = AnonymizeDeletedUsers(24*time.Hour)(ctx, 1000)
+	assert.NoError(t, err)
+
+	var retrieved core.User
+	err = db.Query(&retrieved, "select * from users where id = ?", user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleted user", retrieved.Name)
+	assert.NotEqual(t, user.Email, retrieved.Email)
+	assert.Nil(t, retrieved.GithubLoginUsername)
+	assert.Nil(t, retrieved.GoogleLoginEmail)
+
+	var count struct{ Count int }
+	err = db.Query(&count, "select count(*) as count from email_verifications where payload->>'user_id' = ?::text", user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count.Count)
+
+	var impersonationLog struct{ RequesterIP string }
+	err = db.Query(&impersonationLog, "select host(requester_ip) as requester_ip from user_impersonation_logs where user_id = ?", user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.0.0.0", impersonationLog.RequesterIP)
+
+	var retrievedInvitation core.WorkspaceInvitation
+	err = db.Query(&retrievedInvitation, "select * from workspace_invitations where id = ?", invitation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, retrieved.Email, retrievedInvitation.Email)
+
+	var activity struct{ Email string }
+	err = db.Query(&activity, "select metadata->>'email' as email from workspace_activity_logs where target_type = 'invitation' and target_id = ?", invitation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, retrieved.Email, activity.Email)
+}
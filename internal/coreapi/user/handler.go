@@ -1,10 +1,17 @@
 package user
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/contract"
+	"github.com/awanku/awanku/internal/coreapi/emailverification"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
 )
 
 // @Id api.v1.users.getMe
@@ -21,3 +28,314 @@ func HandleGetMe(w http.ResponseWriter, r *http.Request) {
 	user := appctx.AuthenticatedUser(r.Context())
 	apihelper.JSON(w, http.StatusOK, user)
 }
+
+// updateMeResp is current user, with id of verification to confirm when email change was started
+type updateMeResp struct {
+	*core.User
+	EmailVerificationID int64 `json:"email_verification_id,omitempty"`
+}
+
+// @Id api.v1.users.updateMe
+// @Summary Update current user profile, new email takes effect after it is confirmed
+// @Tags Users
+// @Security oauthAccessToken
+// @Accept json
+// @Param param body updateMeParam true "Request body"
+// @Router /v1/users/me [patch]
+// @Produce json
+// @Success 200 {object} updateMeResp
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdateMe(oauthTokenSecretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		store := newUserStore(r.Context())
+
+		user, err := store.GetByID(appctx.AuthenticatedUser(r.Context()).ID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		var param updateMeParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		if !sameTime(user.UpdatedAt, param.UpdatedAt) {
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"updated_at": "user has been modified, reload and try again",
+			})
+			return
+		}
+
+		if param.Name != nil {
+			user.Name = *param.Name
+		}
+		if param.Preferences != nil {
+			user.Preferences = *param.Preferences
+		}
+
+		var startEmailVerification bool
+		if param.Email != nil && *param.Email != user.Email {
+			taken, err := isEmailTaken(r.Context(), *param.Email, user.ID)
+			if err != nil {
+				apihelper.InternalServerErrResp(w, err)
+				return
+			}
+			if taken {
+				apihelper.ValidationErrResp(w, map[string]string{
+					"email": "already used by another user",
+				})
+				return
+			}
+			user.PendingEmail = param.Email
+			startEmailVerification = true
+		}
+
+		err = store.Save(user)
+		if err == contract.ErrUserModified {
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"updated_at": "user has been modified, reload and try again",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		resp := updateMeResp{User: user}
+		if startEmailVerification {
+			verification := &core.EmailVerification{
+				Purpose: core.EmailVerificationPurposeChangeEmail,
+				Email:   *user.PendingEmail,
+				Payload: map[string]string{
+					"user_id": strconv.FormatInt(user.ID, 10),
+				},
+			}
+			if err := emailverification.Start(r.Context(), oauthTokenSecretKey, verification); err != nil {
+				apihelper.InternalServerErrResp(w, err)
+				return
+			}
+			resp.EmailVerificationID = verification.ID
+		}
+
+		apihelper.JSON(w, http.StatusOK, resp)
+	}
+	return http.HandlerFunc(handler)
+}
+
+// @Id api.v1.users.confirmEmailChange
+// @Summary Confirm new email using code sent to the new email address
+// @Tags Users
+// @Security oauthAccessToken
+// @Accept json
+// @Param verification_id path integer true "Email verification id"
+// @Param param body confirmEmailChangeParam true "Request body"
+// @Router /v1/users/me/email-verifications/{verification_id} [post]
+// @Produce json
+// @Success 200 {object} core.User
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleConfirmEmailChange(oauthTokenSecretKey []byte) http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		store := newUserStore(r.Context())
+
+		verificationID, _ := strconv.ParseInt(chi.URLParam(r, "verification_id"), 10, 64)
+		if verificationID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"verification_id": "invalid",
+			})
+			return
+		}
+
+		var param confirmEmailChangeParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		user, err := store.GetByID(appctx.AuthenticatedUser(r.Context()).ID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		verification, err := emailverification.Confirm(r.Context(), oauthTokenSecretKey, verificationID, core.EmailVerificationPurposeChangeEmail, param.Code)
+		if err == emailverification.ErrInvalidCode {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"code": "invalid",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		// only the latest requested email can be confirmed
+		if verification.Payload["user_id"] != strconv.FormatInt(user.ID, 10) || user.PendingEmail == nil || *user.PendingEmail != verification.Email {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"code": "invalid",
+			})
+			return
+		}
+
+		user.Email = verification.Email
+		user.PendingEmail = nil
+		err = store.Save(user)
+		if err == contract.ErrEmailTaken {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"email": "already used by another user",
+			})
+			return
+		}
+		if err == contract.ErrUserModified {
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"updated_at": "user has been modified, try again",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusOK, user)
+	}
+	return http.HandlerFunc(handler)
+}
+
+type accountDeletionPreview struct {
+	// WorkspacesToDelete are solely owned workspaces without other members, they are deleted together with the account
+	WorkspacesToDelete []*soleOwnedWorkspace `json:"workspaces_to_delete"`
+	// BlockingWorkspaces are solely owned workspaces with other members, ownership must be transferred first
+	BlockingWorkspaces []*soleOwnedWorkspace `json:"blocking_workspaces"`
+}
+
+func buildAccountDeletionPreview(r *http.Request, userID int64) (*accountDeletionPreview, error) {
+	workspaces, err := getSoleOwnedWorkspaces(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := accountDeletionPreview{
+		WorkspacesToDelete: []*soleOwnedWorkspace{},
+		BlockingWorkspaces: []*soleOwnedWorkspace{},
+	}
+	for _, workspace := range workspaces {
+		if workspace.OtherMembers > 0 {
+			preview.BlockingWorkspaces = append(preview.BlockingWorkspaces, workspace)
+		} else {
+			preview.WorkspacesToDelete = append(preview.WorkspacesToDelete, workspace)
+		}
+	}
+	return &preview, nil
+}
+
+// @Id api.v1.users.previewDeleteMe
+// @Summary Show what will happen when current user account is deleted
+// @Tags Users
+// @Security oauthAccessToken
+// @Router /v1/users/me/deletion [get]
+// @Produce json
+// @Success 200 {object} accountDeletionPreview
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandlePreviewDeleteMe(w http.ResponseWriter, r *http.Request) {
+	user := appctx.AuthenticatedUser(r.Context())
+
+	preview, err := buildAccountDeletionPreview(r, user.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, preview)
+}
+
+// @Id api.v1.users.deleteMe
+// @Summary Delete current user account
+// @Tags Users
+// @Security oauthAccessToken
+// @Accept json
+// @Param param body deleteMeParam true "Request body"
+// @Router /v1/users/me [delete]
+// @Produce json
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 409 {object} accountDeletionPreview
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	user := appctx.AuthenticatedUser(r.Context())
+
+	var param deleteMeParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+
+	preview, err := buildAccountDeletionPreview(r, user.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if len(preview.BlockingWorkspaces) > 0 {
+		apihelper.JSON(w, http.StatusConflict, preview)
+		return
+	}
+	if err := param.Validate(user, preview); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	var workspaceIDs []int64
+	for _, workspace := range preview.WorkspacesToDelete {
+		workspaceIDs = append(workspaceIDs, workspace.ID)
+	}
+	err = deleteAccount(r.Context(), user.ID, workspaceIDs)
+	switch {
+	case err == errDeletionPreviewChanged:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"workspaces": "owned workspaces have changed, preview deletion again",
+		})
+		return
+	case err == errActiveResources:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"resources": "owned workspaces still have active resources",
+		})
+		return
+	case err != nil:
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
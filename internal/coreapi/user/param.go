@@ -0,0 +1,79 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type updateMeParam struct {
+	Name        *string               `json:"name"`
+	Email       *string               `json:"email"`
+	Preferences *core.UserPreferences `json:"preferences"`
+	// UpdatedAt must be equal to updated_at of the user being edited
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func (p updateMeParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.NilOrNotEmpty, validation.Length(1, 300)),
+		validation.Field(&p.Email, validation.NilOrNotEmpty, validation.Length(1, 500), is.EmailFormat),
+		validation.Field(&p.Preferences, validation.By(func(value interface{}) error {
+			if p.Preferences == nil {
+				return nil
+			}
+			return validatePreferences(p.Preferences)
+		})),
+	)
+}
+
+func validatePreferences(p *core.UserPreferences) error {
+	return validation.ValidateStruct(p,
+		validation.Field(&p.Theme, validation.In("light", "dark", "system")),
+		validation.Field(&p.Language, validation.In("en", "id")),
+		validation.Field(&p.Timezone, validation.Length(0, 100), validation.By(validateTimezone)),
+	)
+}
+
+func validateTimezone(value interface{}) error {
+	timezone, _ := value.(string)
+	if timezone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.New("unknown timezone")
+	}
+	return nil
+}
+
+type confirmEmailChangeParam struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (p confirmEmailChangeParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
+
+type deleteMeParam struct {
+	// Email must be equal to current user email to confirm account deletion
+	Email string `json:"email" validate:"required"`
+	// DeleteWorkspaces must be true when there are workspaces which will be deleted together with the account
+	DeleteWorkspaces bool `json:"delete_workspaces"`
+}
+
+func (p deleteMeParam) Validate(user *core.User, preview *accountDeletionPreview) error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Email, validation.Required, validation.In(user.Email).Error("does not match current email")),
+		validation.Field(&p.DeleteWorkspaces, validation.By(func(value interface{}) error {
+			if len(preview.WorkspacesToDelete) > 0 && !p.DeleteWorkspaces {
+				return errors.New("must be confirmed, owned workspaces will be deleted")
+			}
+			return nil
+		})),
+	)
+}
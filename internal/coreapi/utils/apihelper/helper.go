@@ -40,6 +40,20 @@ func UnauthorizedAccessResp(w http.ResponseWriter, errType string, payload inter
 	})
 }
 
+func ForbiddenErrResp(w http.ResponseWriter, errType string, payload interface{}) {
+	JSON(w, http.StatusForbidden, map[string]interface{}{
+		"type":   errType,
+		"errors": payload,
+	})
+}
+
+func ConflictErrResp(w http.ResponseWriter, errType string, payload interface{}) {
+	JSON(w, http.StatusConflict, map[string]interface{}{
+		"type":   errType,
+		"errors": payload,
+	})
+}
+
 type InternalServerError struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,24 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// Encode returns opaque pagination cursor pointing at given id
+func Encode(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// Decode returns id from pagination cursor, or 0 when cursor is invalid
+func Decode(cursor string) int64 {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	id, err := strconv.ParseInt(string(decoded), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,83 @@
+package activity
+
+import (
+	"context"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+)
+
+// activity target types
+const (
+	TargetMember               = "member"
+	TargetInvitation           = "invitation"
+	TargetOwnershipTransfer    = "ownership_transfer"
+	TargetRepositoryConnection = "repository_connection"
+	TargetProject              = "project"
+	TargetProjectMember        = "project_member"
+	TargetProjectEnvironment   = "project_environment"
+	TargetResource             = "resource"
+	TargetSecret               = "secret"
+	TargetWebhook              = "webhook"
+)
+
+// activity verbs
+const (
+	VerbCreated            = "created"
+	VerbUpdated            = "updated"
+	VerbDeleted            = "deleted"
+	VerbJoined             = "joined"
+	VerbLeft               = "left"
+	VerbRemoved            = "removed"
+	VerbAccessLevelChanged = "access_level_changed"
+	VerbRevoked            = "revoked"
+	VerbProposed           = "proposed"
+	VerbCancelled          = "cancelled"
+	VerbAccepted           = "accepted"
+	VerbRevealed           = "revealed"
+	VerbRestored           = "restored"
+	VerbRepositoryLinked   = "repository_linked"
+	VerbRepositoryUnlinked = "repository_unlinked"
+	VerbMovedIn            = "moved_in"
+	VerbMovedOut           = "moved_out"
+)
+
+// Event describes what happened in a workspace, e.g. actor 1 removed member 2
+type Event struct {
+	ActorID    int64
+	Verb       string
+	TargetType string
+	TargetID   int64
+	Metadata   map[string]interface{}
+}
+
+const insertQuery = `
+    insert into workspace_activity_logs (workspace_id, actor_user_id, verb, target_type, target_id, metadata, created_at)
+    values (?, ?, ?, ?, ?, ?, now())
+`
+
+// Record writes workspace activity inside the given transaction,
+// so the activity is only stored when the change itself is committed
+func Record(tx hansip.Transaction, workspaceID int64, event *Event) error {
+	return tx.Exec(insertQuery, event.args(workspaceID)...)
+}
+
+// RecordNow writes workspace activity for change which is not made inside a transaction
+func RecordNow(ctx context.Context, workspaceID int64, event *Event) error {
+	return appctx.Database(ctx).WriterExec(insertQuery, event.args(workspaceID)...)
+}
+
+func (e *Event) args(workspaceID int64) []interface{} {
+	metadata := e.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	return []interface{}{workspaceID, nullableID(e.ActorID), e.Verb, e.TargetType, nullableID(e.TargetID), metadata}
+}
+
+func nullableID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
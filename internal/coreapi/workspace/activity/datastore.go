@@ -0,0 +1,63 @@
+package activity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/cursor"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// listActivities returns newest activities first, one extra row is fetched to know whether next page exists
+func listActivities(ctx context.Context, workspaceID int64, p *listActivitiesParam) (activities []*core.WorkspaceActivity, nextCursor string, err error) {
+	db := appctx.Database(ctx)
+
+	conditions := []string{"workspace_id = ?"}
+	args := []interface{}{workspaceID}
+	if p.beforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, p.beforeID)
+	}
+	if p.ActorID > 0 {
+		conditions = append(conditions, "actor_user_id = ?")
+		args = append(args, p.ActorID)
+	}
+	if p.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, p.TargetType)
+	}
+	if p.TargetID > 0 {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, p.TargetID)
+	}
+	if p.sinceTime != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *p.sinceTime)
+	}
+	if p.untilTime != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *p.untilTime)
+	}
+	args = append(args, p.Limit+1)
+
+	var query = `
+        select *
+        from workspace_activity_logs
+        where ` + strings.Join(conditions, " and ") + `
+        order by id desc
+        limit ?
+    `
+	err = db.Query(&activities, query, args...)
+	if err != nil {
+		return []*core.WorkspaceActivity{}, "", err
+	}
+	if activities == nil {
+		activities = []*core.WorkspaceActivity{}
+	}
+	if len(activities) > p.Limit {
+		activities = activities[:p.Limit]
+		nextCursor = cursor.Encode(activities[len(activities)-1].ID)
+	}
+	return activities, nextCursor, nil
+}
@@ -0,0 +1,90 @@
+package activity
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListActivities(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+
+	events := []*Event{
+		{ActorID: users[0].ID, Verb: VerbCreated, TargetType: TargetInvitation, TargetID: 10},
+		{ActorID: users[1].ID, Verb: VerbJoined, TargetType: TargetMember, TargetID: users[1].ID},
+		{ActorID: users[0].ID, Verb: VerbCreated, TargetType: TargetRepositoryConnection, TargetID: 20, Metadata: map[string]interface{}{"identifier": "awanku"}},
+	}
+	for _, event := range events {
+		assert.NoError(t, RecordNow(ctx, workspace.ID, event))
+	}
+
+	t.Run("paginate newest first", func(t *testing.T) {
+		p := parseListActivitiesParam(url.Values{"limit": {"2"}})
+		assert.NoError(t, p.Validate())
+
+		activities, cursor, err := listActivities(ctx, workspace.ID, p)
+		assert.NoError(t, err)
+		if assert.Len(t, activities, 2) {
+			assert.Equal(t, TargetRepositoryConnection, activities[0].TargetType)
+			assert.Equal(t, "awanku", activities[0].Metadata["identifier"])
+			assert.Equal(t, TargetMember, activities[1].TargetType)
+		}
+		assert.NotEmpty(t, cursor)
+
+		p = parseListActivitiesParam(url.Values{"limit": {"2"}, "cursor": {cursor}})
+		assert.NoError(t, p.Validate())
+
+		activities, cursor, err = listActivities(ctx, workspace.ID, p)
+		assert.NoError(t, err)
+		if assert.Len(t, activities, 1) {
+			assert.Equal(t, TargetInvitation, activities[0].TargetType)
+			assert.Equal(t, users[0].ID, *activities[0].ActorUserID)
+		}
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		testcases := []struct {
+			query url.Values
+			count int
+		}{
+			{url.Values{"actor_id": {strconv.FormatInt(users[0].ID, 10)}}, 2},
+			{url.Values{"target_type": {TargetMember}}, 1},
+			{url.Values{"target_type": {TargetInvitation}, "target_id": {"10"}}, 1},
+			{url.Values{"since": {time.Now().Add(time.Hour).Format(time.RFC3339)}}, 0},
+			{url.Values{"until": {time.Now().Add(time.Hour).Format(time.RFC3339)}}, 3},
+		}
+		for _, testcase := range testcases {
+			p := parseListActivitiesParam(testcase.query)
+			assert.NoError(t, p.Validate())
+
+			activities, _, err := listActivities(ctx, workspace.ID, p)
+			assert.NoError(t, err)
+			assert.Len(t, activities, testcase.count, testcase.query.Encode())
+		}
+	})
+}
+
+func TestListActivitiesParamValidate(t *testing.T) {
+	invalid := []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"limit": {"ten"}},
+		{"cursor": {"!!"}},
+		{"since": {"yesterday"}},
+		{"target_id": {"1"}},
+	}
+	for _, query := range invalid {
+		assert.Error(t, parseListActivitiesParam(query).Validate(), query.Encode())
+	}
+
+	assert.NoError(t, parseListActivitiesParam(url.Values{}).Validate())
+}
@@ -0,0 +1,55 @@
+package activity
+
+import (
+	"net/http"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+type activityPage struct {
+	Activities []*core.WorkspaceActivity `json:"activities"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// @Id api.v1.workspace.activity.listAll
+// @Summary List workspace activities, newest first
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param cursor query string false "Cursor from next_cursor of previous page"
+// @Param limit query integer false "Page size, 50 by default, at most 100"
+// @Param actor_id query integer false "Only activities made by this user"
+// @Param target_type query string false "Only activities on this target type, e.g. member or repository_connection"
+// @Param target_id query integer false "Only activities on this target, requires target_type"
+// @Param since query string false "Only activities at or after this RFC 3339 timestamp"
+// @Param until query string false "Only activities before this RFC 3339 timestamp"
+// @Router /v1/workspaces/{workspace_id}/activities [get]
+// @Produce json
+// @Success 200 {object} activityPage
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	param := parseListActivitiesParam(r.URL.Query())
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	activities, nextCursor, err := listActivities(r.Context(), currentWorkspace.ID, param)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, activityPage{
+		Activities: activities,
+		NextCursor: nextCursor,
+	})
+}
@@ -0,0 +1,95 @@
+package activity
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/utils/cursor"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+type listActivitiesParam struct {
+	Cursor     string `json:"cursor"`
+	Limit      int    `json:"limit"`
+	ActorID    int64  `json:"actor_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+	Since      string `json:"since"`
+	Until      string `json:"until"`
+
+	beforeID   int64
+	sinceTime  *time.Time
+	untilTime  *time.Time
+	parseError map[string]string
+}
+
+// parseListActivitiesParam reads filters from query string, malformed values are reported by Validate
+func parseListActivitiesParam(query url.Values) *listActivitiesParam {
+	p := &listActivitiesParam{
+		Cursor:     query.Get("cursor"),
+		Limit:      defaultLimit,
+		TargetType: query.Get("target_type"),
+		Since:      query.Get("since"),
+		Until:      query.Get("until"),
+		parseError: map[string]string{},
+	}
+
+	parseInt := func(name string) int64 {
+		raw := query.Get(name)
+		if raw == "" {
+			return 0
+		}
+		val, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			p.parseError[name] = "must be a number"
+		}
+		return val
+	}
+	if query.Get("limit") != "" {
+		p.Limit = int(parseInt("limit"))
+	}
+	p.ActorID = parseInt("actor_id")
+	p.TargetID = parseInt("target_id")
+
+	if p.Cursor != "" {
+		p.beforeID = cursor.Decode(p.Cursor)
+		if p.beforeID <= 0 {
+			p.parseError["cursor"] = "invalid"
+		}
+	}
+
+	parseTime := func(name, raw string) *time.Time {
+		if raw == "" {
+			return nil
+		}
+		val, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			p.parseError[name] = "must be RFC 3339 timestamp"
+			return nil
+		}
+		return &val
+	}
+	p.sinceTime = parseTime("since", p.Since)
+	p.untilTime = parseTime("until", p.Until)
+	return p
+}
+
+func (p listActivitiesParam) Validate() error {
+	if len(p.parseError) > 0 {
+		errs := validation.Errors{}
+		for name, msg := range p.parseError {
+			errs[name] = validation.NewError("validation_invalid_format", msg)
+		}
+		return errs
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Limit, validation.Required, validation.Min(1), validation.Max(maxLimit)),
+		validation.Field(&p.TargetType, validation.When(p.TargetID != 0, validation.Required.Error("required when filtering by target_id"))),
+	)
+}
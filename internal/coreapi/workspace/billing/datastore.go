@@ -0,0 +1,195 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-pg/pg/v9"
+)
+
+func getInvoices(ctx context.Context, workspaceID int64) ([]*core.Invoice, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from invoices
+        where workspace_id = ?
+        order by period_start desc
+    `
+	var invoices []*core.Invoice
+	err := db.Query(&invoices, query, workspaceID)
+	if err != nil {
+		return []*core.Invoice{}, err
+	}
+	if invoices == nil {
+		invoices = []*core.Invoice{}
+	}
+	return invoices, nil
+}
+
+func getInvoice(ctx context.Context, workspaceID, id int64) (*core.Invoice, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from invoices
+        where id = ? and workspace_id = ?
+    `
+	var invoice core.Invoice
+	err := db.Query(&invoice, query, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.ID == 0 {
+		return nil, nil
+	}
+	return &invoice, nil
+}
+
+// GenerateMonthlyInvoices creates invoices of previous month for at most batchSize workspaces
+// which existed in that month and do not have one yet
+func GenerateMonthlyInvoices(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+	periodEnd, _ := monthPeriod(time.Now())
+	periodStart := periodEnd.AddDate(0, -1, 0)
+
+	var query = `
+        select id
+        from workspaces
+        where
+            created_at < ?
+            and (deleted_at is null or deleted_at >= ?)
+            and not exists (
+                select 1
+                from invoices
+                where invoices.workspace_id = workspaces.id and invoices.period_start = ?
+            )
+        order by id
+        limit ?
+    `
+	var workspaces []struct{ ID int64 }
+	err := db.WriterQuery(&workspaces, query, periodEnd, periodStart, periodStart, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var generated int64
+	for _, workspace := range workspaces {
+		if _, err := generateInvoice(ctx, workspace.ID, periodStart); err != nil {
+			return generated, err
+		}
+		generated++
+	}
+	return generated, nil
+}
+
+// generateInvoice creates invoice of month starting at periodStart, existing invoice of the month is kept as is
+func generateInvoice(ctx context.Context, workspaceID int64, periodStart time.Time) (invoice *core.Invoice, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	periodStart, periodEnd := monthPeriod(periodStart)
+
+	var queryPlan = `
+        select plans.*
+        from plans
+        join workspaces on workspaces.plan = plans.code
+        where workspaces.id = ?
+    `
+	var plan core.Plan
+	err = tx.Query(&plan, queryPlan, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := getBilledResources(tx, workspaceID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice = buildInvoice(workspaceID, &plan, periodStart, periodEnd, resources)
+
+	var query = `
+        insert into invoices (workspace_id, number, period_start, period_end, plan, currency, line_items, total, created_at)
+        values (?, ?, ?, ?, ?, ?, ?, ?, now())
+        on conflict (workspace_id, period_start) do nothing
+        returning *
+    `
+	err = tx.Query(invoice, query, invoice.WorkspaceID, invoice.Number, invoice.PeriodStart, invoice.PeriodEnd, invoice.Plan, invoice.Currency, invoice.LineItems, invoice.Total)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.ID == 0 {
+		var queryExisting = `
+            select *
+            from invoices
+            where workspace_id = ? and period_start = ?
+        `
+		invoice = &core.Invoice{}
+		err = tx.Query(invoice, queryExisting, workspaceID, periodStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return invoice, nil
+}
+
+// getBilledResources returns resources metered in workspace before periodEnd with their events ordered by time
+func getBilledResources(tx hansip.Transaction, workspaceID int64, periodEnd time.Time) ([]*billedResource, error) {
+	var queryEvents = `
+        select *
+        from resource_metering_events
+        where workspace_id = ? and occurred_at < ?
+        order by resource_id, occurred_at, id
+    `
+	var events []*core.ResourceMeteringEvent
+	err := tx.Query(&events, queryEvents, workspaceID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return []*billedResource{}, nil
+	}
+
+	resourceIDs := []int64{}
+	for _, event := range events {
+		if len(resourceIDs) == 0 || resourceIDs[len(resourceIDs)-1] != event.ResourceID {
+			resourceIDs = append(resourceIDs, event.ResourceID)
+		}
+	}
+
+	var queryResources = `
+        select id, name, type, deleted_at
+        from resources
+        where id in (?)
+        order by id
+    `
+	var resources []*billedResource
+	err = tx.Query(&resources, queryResources, pg.In(resourceIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int64]*billedResource{}
+	for _, resource := range resources {
+		byID[resource.ID] = resource
+	}
+	for _, event := range events {
+		if resource, ok := byID[event.ResourceID]; ok {
+			resource.Events = append(resource.Events, event)
+		}
+	}
+	return resources, nil
+}
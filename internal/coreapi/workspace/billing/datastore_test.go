@@ -0,0 +1,65 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateInvoice(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	err := db.WriterExec("update workspaces set plan = 'pro' where id = ?", workspace.ID)
+	assert.NoError(t, err)
+
+	var project struct{ ID int64 }
+	err = db.WriterQuery(&project, "insert into projects (name, workspace_id) values ('project', ?) returning id", workspace.ID)
+	assert.NoError(t, err)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeProduction)
+	var resource struct{ ID int64 }
+	err = db.WriterQuery(&resource, "insert into resources (name, type, payload, project_id, environment_id) values ('db', 'postgres', '{}', ?, ?) returning id", project.ID, environment.ID)
+	assert.NoError(t, err)
+
+	periodStart := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	tx, err := db.NewTransaction()
+	assert.NoError(t, err)
+	assert.NoError(t, RecordStateChange(tx, workspace.ID, resource.ID, core.ResourceStateUnknown, core.ResourceStateProvisioningSuccess))
+	assert.NoError(t, tx.Commit())
+	err = db.WriterExec("update resource_metering_events set occurred_at = ? where resource_id = ?", periodStart.Add(-time.Hour), resource.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into resource_metering_events (resource_id, workspace_id, previous_state, state, occurred_at)
+        values (?, ?, 'provisioning_success', 'unknown', ?)
+    `, resource.ID, workspace.ID, periodStart.Add(3*time.Hour+time.Minute))
+	assert.NoError(t, err)
+
+	invoice, err := generateInvoice(ctx, workspace.ID, periodStart)
+	assert.NoError(t, err)
+	assert.True(t, invoice.ID > 0)
+	assert.Equal(t, "pro", invoice.Plan)
+	if assert.Len(t, invoice.LineItems, 1) {
+		assert.Equal(t, int64(4), invoice.LineItems[0].Hours)
+	}
+	assert.Equal(t, int64(600), invoice.Total)
+
+	// generating the same month again keeps the issued invoice
+	again, err := generateInvoice(ctx, workspace.ID, periodStart)
+	assert.NoError(t, err)
+	assert.Equal(t, invoice.ID, again.ID)
+
+	invoices, err := getInvoices(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Len(t, invoices, 1)
+
+	other := testutil.WorkspaceFactory(ctx, 1)[0]
+	found, err := getInvoice(ctx, other.ID, invoice.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
@@ -0,0 +1,109 @@
+package billing
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.invoice.listAll
+// @Summary List workspace invoices, newest first
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/invoices [get]
+// @Produce json
+// @Success 200 {array} core.Invoice
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	invoices, err := getInvoices(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, invoices)
+}
+
+// @Id api.v1.workspace.invoice.get
+// @Summary Get invoice with its line items
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param invoice_id path integer true "Invoice id"
+// @Router /v1/workspaces/{workspace_id}/invoices/{invoice_id} [get]
+// @Produce json
+// @Success 200 {object} core.Invoice
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := currentInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, invoice)
+}
+
+// @Id api.v1.workspace.invoice.download
+// @Summary Download invoice as printable html document
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param invoice_id path integer true "Invoice id"
+// @Router /v1/workspaces/{workspace_id}/invoices/{invoice_id}/download [get]
+// @Produce html
+// @Success 200
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDownload(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := currentInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := renderInvoice(&buf, appctx.CurrentWorkspace(r.Context()), invoice); err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.html"`, invoice.Number))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func currentInvoice(w http.ResponseWriter, r *http.Request) (*core.Invoice, bool) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	invoiceID, _ := strconv.ParseInt(chi.URLParam(r, "invoice_id"), 10, 64)
+
+	invoice, err := getInvoice(r.Context(), currentWorkspace.ID, invoiceID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return nil, false
+	}
+	if invoice == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return invoice, true
+}
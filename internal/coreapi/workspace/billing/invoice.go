@@ -0,0 +1,59 @@
+package billing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// billedResource is a resource with metering events recorded for the workspace
+type billedResource struct {
+	ID        int64
+	Name      string
+	Type      string
+	DeletedAt *time.Time
+	Events    []*core.ResourceMeteringEvent `pg:"-"`
+}
+
+// monthPeriod returns start and end of calendar month in UTC containing t
+func monthPeriod(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func invoiceNumber(workspaceID int64, periodStart time.Time) string {
+	return fmt.Sprintf("AWK-%s-%06d", periodStart.Format("200601"), workspaceID)
+}
+
+// buildInvoice charges every started billable hour of resources in period with plan price,
+// resources without billable hours in period are left out
+func buildInvoice(workspaceID int64, plan *core.Plan, periodStart, periodEnd time.Time, resources []*billedResource) *core.Invoice {
+	invoice := core.Invoice{
+		WorkspaceID: workspaceID,
+		Number:      invoiceNumber(workspaceID, periodStart),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Plan:        plan.Code,
+		Currency:    plan.Currency,
+		LineItems:   []core.InvoiceLineItem{},
+	}
+	for _, resource := range resources {
+		hours := billedHours(billableDuration(resource.Events, resource.DeletedAt, periodStart, periodEnd))
+		if hours == 0 {
+			continue
+		}
+		item := core.InvoiceLineItem{
+			ResourceID:   resource.ID,
+			ResourceName: resource.Name,
+			ResourceType: resource.Type,
+			Hours:        hours,
+			UnitPrice:    plan.ResourceHourPrice,
+			Amount:       hours * plan.ResourceHourPrice,
+		}
+		invoice.LineItems = append(invoice.LineItems, item)
+		invoice.Total += item.Amount
+	}
+	return &invoice
+}
@@ -0,0 +1,94 @@
+package billing
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func event(state string, occurredAt time.Time) *core.ResourceMeteringEvent {
+	return &core.ResourceMeteringEvent{State: state, OccurredAt: occurredAt}
+}
+
+func TestBillableDuration(t *testing.T) {
+	start := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	// running since previous month, stopped mid month
+	events := []*core.ResourceMeteringEvent{
+		event(core.ResourceStateProvisioning, start.Add(-48*time.Hour)),
+		event(core.ResourceStateProvisioningSuccess, start.Add(-24*time.Hour)),
+		event(core.ResourceStateProvisioningFailed, start.Add(10*time.Hour)),
+	}
+	assert.Equal(t, 10*time.Hour, billableDuration(events, nil, start, end))
+
+	// still running at end of month
+	events = []*core.ResourceMeteringEvent{
+		event(core.ResourceStateProvisioningSuccess, end.Add(-90*time.Minute)),
+	}
+	assert.Equal(t, 90*time.Minute, billableDuration(events, nil, start, end))
+
+	// deleted while running
+	deletedAt := end.Add(-30 * time.Minute)
+	assert.Equal(t, 60*time.Minute, billableDuration(events, &deletedAt, start, end))
+
+	// stopped before period
+	events = []*core.ResourceMeteringEvent{
+		event(core.ResourceStateProvisioningSuccess, start.Add(-48*time.Hour)),
+		event(core.ResourceStateUnknown, start.Add(-24*time.Hour)),
+	}
+	assert.Equal(t, time.Duration(0), billableDuration(events, nil, start, end))
+}
+
+func TestBilledHours(t *testing.T) {
+	assert.Equal(t, int64(0), billedHours(0))
+	assert.Equal(t, int64(1), billedHours(time.Minute))
+	assert.Equal(t, int64(1), billedHours(time.Hour))
+	assert.Equal(t, int64(2), billedHours(time.Hour+time.Second))
+}
+
+func TestMonthPeriod(t *testing.T) {
+	start, end := monthPeriod(time.Date(2020, 12, 15, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestBuildInvoice(t *testing.T) {
+	start, end := monthPeriod(time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC))
+	plan := &core.Plan{Code: "pro", Currency: "IDR", ResourceHourPrice: 150}
+	resources := []*billedResource{
+		{ID: 1, Name: "db", Type: "postgres", Events: []*core.ResourceMeteringEvent{
+			event(core.ResourceStateProvisioningSuccess, start.Add(-time.Hour)),
+			event(core.ResourceStateUnknown, start.Add(90*time.Minute)),
+		}},
+		{ID: 2, Name: "cache", Type: "redis", Events: []*core.ResourceMeteringEvent{
+			event(core.ResourceStateProvisioningFailed, start.Add(time.Hour)),
+		}},
+	}
+
+	invoice := buildInvoice(10, plan, start, end, resources)
+	assert.Equal(t, "AWK-202008-000010", invoice.Number)
+	assert.Equal(t, "IDR", invoice.Currency)
+	if assert.Len(t, invoice.LineItems, 1) {
+		assert.Equal(t, int64(1), invoice.LineItems[0].ResourceID)
+		assert.Equal(t, int64(2), invoice.LineItems[0].Hours)
+		assert.Equal(t, int64(300), invoice.LineItems[0].Amount)
+	}
+	assert.Equal(t, int64(300), invoice.Total)
+
+	var buf bytes.Buffer
+	assert.NoError(t, renderInvoice(&buf, &core.Workspace{Name: "<acme>"}, invoice))
+	assert.Contains(t, buf.String(), "AWK-202008-000010")
+	assert.Contains(t, buf.String(), "&lt;acme&gt;")
+	assert.Contains(t, buf.String(), "31 August 2020")
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "0", formatAmount(0))
+	assert.Equal(t, "150", formatAmount(150))
+	assert.Equal(t, "1,500", formatAmount(1500))
+	assert.Equal(t, "-1,500,000", formatAmount(-1500000))
+}
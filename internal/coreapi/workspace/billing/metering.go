@@ -0,0 +1,93 @@
+package billing
+
+import (
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// billableStates lists resource states charged by the hour
+var billableStates = map[string]bool{
+	core.ResourceStateProvisioningSuccess: true,
+}
+
+// RecordStateChange records metering event in the transaction changing resource state
+func RecordStateChange(tx hansip.Transaction, workspaceID, resourceID int64, previousState, state string) error {
+	var query = `
+        insert into resource_metering_events (resource_id, workspace_id, previous_state, state, occurred_at)
+        values (?, ?, ?, ?, now())
+    `
+	return tx.Exec(query, resourceID, workspaceID, previousState, state)
+}
+
+// RecordRestore records resource as stopped while it was deleted, so time in trash is not billed after restore
+func RecordRestore(tx hansip.Transaction, workspaceID, resourceID int64, state string, deletedAt time.Time) error {
+	var query = `
+        insert into resource_metering_events (resource_id, workspace_id, previous_state, state, occurred_at)
+        values (?0, ?1, ?2, 'unknown', ?3), (?0, ?1, 'unknown', ?2, now())
+    `
+	return tx.Exec(query, resourceID, workspaceID, state, deletedAt)
+}
+
+// RecordTransfer stops metering resource in the workspace it leaves and starts it in the workspace it joins,
+// so each workspace is billed only for the time it owned the resource
+func RecordTransfer(tx hansip.Transaction, fromWorkspaceID, toWorkspaceID, resourceID int64, state string) error {
+	var query = `
+        insert into resource_metering_events (resource_id, workspace_id, previous_state, state, occurred_at)
+        values (?0, ?1, ?3, 'unknown', now()), (?0, ?2, 'unknown', ?3, now())
+    `
+	return tx.Exec(query, resourceID, fromWorkspaceID, toWorkspaceID, state)
+}
+
+// billableDuration sums time resource spent in billable states within [start, end).
+// Events must belong to one resource and be ordered by time, deleted resource stops being billed when deleted.
+func billableDuration(events []*core.ResourceMeteringEvent, deletedAt *time.Time, start, end time.Time) time.Duration {
+	var total time.Duration
+	var billableSince *time.Time
+	for _, event := range events {
+		if deletedAt != nil && event.OccurredAt.After(*deletedAt) {
+			break
+		}
+		billable := billableStates[event.State]
+		if billable && billableSince == nil {
+			occurredAt := event.OccurredAt
+			billableSince = &occurredAt
+		}
+		if !billable && billableSince != nil {
+			total += overlap(*billableSince, event.OccurredAt, start, end)
+			billableSince = nil
+		}
+	}
+	if billableSince != nil {
+		stop := end
+		if deletedAt != nil && deletedAt.Before(stop) {
+			stop = *deletedAt
+		}
+		total += overlap(*billableSince, stop, start, end)
+	}
+	return total
+}
+
+// overlap returns length of [from, to) falling within [start, end)
+func overlap(from, to, start, end time.Time) time.Duration {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// billedHours rounds duration up to started hours
+func billedHours(duration time.Duration) int64 {
+	hours := int64(duration / time.Hour)
+	if duration%time.Hour > 0 {
+		hours++
+	}
+	return hours
+}
@@ -0,0 +1,74 @@
+package billing
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": formatAmount,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Invoice.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 40px; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-top: 24px; }
+th, td { border-bottom: 1px solid #ddd; padding: 8px; text-align: left; }
+td.number, th.number { text-align: right; }
+tfoot td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Invoice.Number}}</h1>
+<p>
+Workspace: {{.Workspace.Name}}<br>
+Plan: {{.Invoice.Plan}}<br>
+Period: {{.Invoice.PeriodStart.Format "2 January 2006"}} - {{.LastDay.Format "2 January 2006"}}<br>
+Issued: {{.Invoice.CreatedAt.Format "2 January 2006"}}
+</p>
+<table>
+<thead>
+<tr><th>Resource</th><th>Type</th><th class="number">Hours</th><th class="number">Unit price</th><th class="number">Amount</th></tr>
+</thead>
+<tbody>
+{{- range .Invoice.LineItems}}
+<tr><td>{{.ResourceName}}</td><td>{{.ResourceType}}</td><td class="number">{{.Hours}}</td><td class="number">{{amount .UnitPrice}}</td><td class="number">{{amount .Amount}}</td></tr>
+{{- else}}
+<tr><td colspan="5">No billable usage in this period</td></tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="4">Total</td><td class="number">{{.Invoice.Currency}} {{amount .Invoice.Total}}</td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// renderInvoice writes printable html document of invoice
+func renderInvoice(w io.Writer, workspace *core.Workspace, invoice *core.Invoice) error {
+	return invoiceTemplate.Execute(w, map[string]interface{}{
+		"Workspace": workspace,
+		"Invoice":   invoice,
+		// period end is exclusive
+		"LastDay": invoice.PeriodEnd.AddDate(0, 0, -1),
+	})
+}
+
+// formatAmount groups digits by thousands, e.g. 1500000 becomes 1,500,000
+func formatAmount(amount int64) string {
+	digits := strconv.FormatInt(amount, 10)
+	sign := ""
+	if amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return sign + digits
+}
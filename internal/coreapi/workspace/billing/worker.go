@@ -0,0 +1,56 @@
+package billing
+
+import (
+	"context"
+	"log"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+)
+
+const invoiceBatchSize = 100
+
+// Worker generates monthly invoices in background
+type Worker struct {
+	db       *hansip.Cluster
+	interval time.Duration
+}
+
+// NewWorker creates new invoice worker
+func NewWorker(db *hansip.Cluster, interval time.Duration) *Worker {
+	return &Worker{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Start generates invoices of previous month until ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	ctx = context.WithValue(ctx, appctx.KeyDatabase, w.db)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// keep generating until every workspace of previous month is invoiced before waiting for next tick
+		for {
+			generated, err := GenerateMonthlyInvoices(ctx, invoiceBatchSize)
+			if err != nil {
+				log.Println("invoice worker failed:", err)
+			}
+			if generated > 0 {
+				log.Printf("invoice worker: invoices_generated=%d", generated)
+			}
+			if generated == 0 || err != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
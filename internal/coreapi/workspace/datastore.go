@@ -2,11 +2,18 @@ package workspace
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/pkg/core"
 )
 
+// querier is implemented by both database cluster and transaction
+type querier interface {
+	Query(dest interface{}, query string, args ...interface{}) error
+}
+
 func getWorkspaceByID(ctx context.Context, id int64) (*core.Workspace, error) {
 	db := appctx.Database(ctx)
 
@@ -26,17 +33,109 @@ func getWorkspaceByID(ctx context.Context, id int64) (*core.Workspace, error) {
 	return &workspace, nil
 }
 
-func getUserWorkspaces(ctx context.Context, userID int64) ([]*core.Workspace, error) {
+func getWorkspaceBySlug(ctx context.Context, slug string) (*core.Workspace, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from workspaces
+        where slug = ? and deleted_at is null
+    `
+	var workspace core.Workspace
+	err := db.Query(&workspace, query, slug)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.ID == 0 {
+		return nil, nil
+	}
+	return &workspace, nil
+}
+
+// getWorkspaceBySlugRedirect returns workspace which used the slug before being renamed, while redirect is not expired
+func getWorkspaceBySlugRedirect(ctx context.Context, slug string) (*core.Workspace, error) {
 	db := appctx.Database(ctx)
 
 	var query = `
         select workspaces.*
         from workspaces
-        join workspace_users on workspaces.id = workspace_users.workspace_id
+        join workspace_slug_redirects on workspace_slug_redirects.workspace_id = workspaces.id
         where
-            workspace_users.user_id = ?
+            workspace_slug_redirects.slug = ?
+            and workspace_slug_redirects.expires_at > now()
             and workspaces.deleted_at is null
-            and workspace_users.deleted_at is null
+    `
+	var workspace core.Workspace
+	err := db.Query(&workspace, query, slug)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.ID == 0 {
+		return nil, nil
+	}
+	return &workspace, nil
+}
+
+// slugAvailable returns false when slug is used by another workspace, either currently or as unexpired redirect
+func slugAvailable(db querier, slug string, workspaceID int64) (bool, error) {
+	var query = `
+        select
+            (
+                select count(*)
+                from workspaces
+                where slug = ?0 and id <> ?1 and deleted_at is null
+            ) + (
+                select count(*)
+                from workspace_slug_redirects
+                join workspaces on workspaces.id = workspace_slug_redirects.workspace_id
+                where
+                    workspace_slug_redirects.slug = ?0
+                    and workspace_slug_redirects.workspace_id <> ?1
+                    and workspace_slug_redirects.expires_at > now()
+                    and workspaces.deleted_at is null
+            ) as count
+    `
+	var returned struct{ Count int }
+	err := db.Query(&returned, query, slug, workspaceID)
+	if err != nil {
+		return false, err
+	}
+	return returned.Count == 0, nil
+}
+
+func isSlugConflict(err error) bool {
+	return strings.Contains(err.Error(), "unique_slug_on_workspaces")
+}
+
+func getUserWorkspaces(ctx context.Context, userID int64) ([]*core.Workspace, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select workspaces.*
+        from workspaces
+        where
+            workspaces.deleted_at is null
+            and (
+                exists (
+                    select 1
+                    from workspace_users
+                    where
+                        workspace_users.workspace_id = workspaces.id
+                        and workspace_users.user_id = ?0
+                        and workspace_users.deleted_at is null
+                )
+                or exists (
+                    select 1
+                    from project_users
+                    join projects on projects.id = project_users.project_id
+                    where
+                        projects.workspace_id = workspaces.id
+                        and project_users.user_id = ?0
+                        and project_users.deleted_at is null
+                        and projects.deleted_at is null
+                )
+            )
+        order by workspaces.id
     `
 	var workspaces []*core.Workspace
 	err := db.Query(&workspaces, query, userID)
@@ -45,3 +144,226 @@ func getUserWorkspaces(ctx context.Context, userID int64) ([]*core.Workspace, er
 	}
 	return workspaces, nil
 }
+
+// getWorkspaceAccessLevel returns guest access level when user is not member of the workspace
+// but has access to some of its projects, and empty string when user has no access at all
+func getWorkspaceAccessLevel(ctx context.Context, workspaceID, userID int64) (string, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            (
+                select access_level
+                from workspace_users
+                where
+                    workspace_id = ?0
+                    and user_id = ?1
+                    and deleted_at is null
+            ) as access_level,
+            exists (
+                select 1
+                from project_users
+                join projects on projects.id = project_users.project_id
+                where
+                    projects.workspace_id = ?0
+                    and project_users.user_id = ?1
+                    and project_users.deleted_at is null
+                    and projects.deleted_at is null
+            ) as has_project_access
+    `
+	var returned struct {
+		AccessLevel      *string
+		HasProjectAccess bool
+	}
+	err := db.Query(&returned, query, workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if returned.AccessLevel != nil {
+		return *returned.AccessLevel, nil
+	}
+	if returned.HasProjectAccess {
+		return core.WorkspaceAccessLevelGuest, nil
+	}
+	return "", nil
+}
+
+func createWorkspace(ctx context.Context, workspace *core.Workspace, ownerID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if workspace.Slug == "" {
+		workspace.Slug, err = GenerateSlug(tx, workspace.Name)
+		if err != nil {
+			return err
+		}
+	} else {
+		available, err := slugAvailable(tx, workspace.Slug, 0)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return errSlugTaken
+		}
+	}
+
+	var queryWorkspace = `
+        insert into workspaces (name, slug, created_at)
+        values (?, ?, now())
+        returning *
+    `
+	err = tx.Query(workspace, queryWorkspace, workspace.Name, workspace.Slug)
+	if err != nil {
+		if isSlugConflict(err) {
+			return errSlugTaken
+		}
+		return err
+	}
+
+	var queryWorkspaceUser = `
+        insert into workspace_users (workspace_id, user_id, access_level, created_at)
+        values (?, ?, 'owner', now())
+    `
+	err = tx.Exec(queryWorkspaceUser, workspace.ID, ownerID)
+	return
+}
+
+// updateWorkspace saves workspace name and slug, previous slug keeps redirecting to the workspace for a while
+func updateWorkspace(ctx context.Context, workspace *core.Workspace) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryCurrent = `
+        select slug
+        from workspaces
+        where id = ? and deleted_at is null
+        for update
+    `
+	var current struct{ Slug string }
+	err = tx.Query(&current, queryCurrent, workspace.ID)
+	if err != nil {
+		return err
+	}
+
+	if workspace.Slug == "" {
+		workspace.Slug = current.Slug
+	}
+	if current.Slug != "" && workspace.Slug != current.Slug {
+		available, err := slugAvailable(tx, workspace.Slug, workspace.ID)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return errSlugTaken
+		}
+
+		var queryReclaim = `
+            delete from workspace_slug_redirects
+            where slug = ?
+        `
+		err = tx.Exec(queryReclaim, workspace.Slug)
+		if err != nil {
+			return err
+		}
+
+		var queryRedirect = `
+            insert into workspace_slug_redirects (slug, workspace_id, expires_at, created_at)
+            values (?, ?, ?, now())
+            on conflict (slug) do update
+            set workspace_id = excluded.workspace_id, expires_at = excluded.expires_at, created_at = excluded.created_at
+        `
+		err = tx.Exec(queryRedirect, current.Slug, workspace.ID, time.Now().Add(core.WorkspaceSlugRedirectDuration))
+		if err != nil {
+			return err
+		}
+	}
+
+	var query = `
+        update workspaces
+        set name = ?, slug = ?, updated_at = now()
+        where id = ? and deleted_at is null
+        returning *
+    `
+	err = tx.Query(workspace, query, workspace.Name, workspace.Slug, workspace.ID)
+	if err != nil && isSlugConflict(err) {
+		return errSlugTaken
+	}
+	return err
+}
+
+// deleteWorkspace soft deletes workspace together with its projects and repository connections,
+// projects are unlinked from repositories so connections can be purged later.
+// Workspace with active resources is not deleted and their count is returned instead,
+// the workspace row is locked first so resources can not be created while deleting.
+func deleteWorkspace(ctx context.Context, workspaceID int64) (activeResources int, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var lockQuery = `
+        select id
+        from workspaces
+        where id = ?
+        for update
+    `
+	var locked struct{ ID int64 }
+	if err = tx.Query(&locked, lockQuery, workspaceID); err != nil {
+		return 0, err
+	}
+
+	var countQuery = `
+        select count(*) as count
+        from resources
+        join projects on projects.id = resources.project_id
+        where
+            projects.workspace_id = ?
+            and projects.deleted_at is null
+            and resources.deleted_at is null
+    `
+	var returned struct{ Count int }
+	if err = tx.Query(&returned, countQuery, workspaceID); err != nil {
+		return 0, err
+	}
+	if returned.Count > 0 {
+		return returned.Count, nil
+	}
+
+	var queries = []string{
+		`update projects set repository_connection_id = null, repository_name = null, repository_branch = null, repository_root_dir = null where workspace_id = ? and repository_connection_id is not null`,
+		`update workspace_repository_connections set deleted_at = now() where workspace_id = ? and deleted_at is null`,
+		`update projects set deleted_at = now() where workspace_id = ? and deleted_at is null`,
+		`update workspaces set deleted_at = now() where id = ? and deleted_at is null`,
+	}
+	for _, query := range queries {
+		if err = tx.Exec(query, workspaceID); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,160 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWorkspace(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	workspace := &core.Workspace{Name: "my workspace"}
+	err := createWorkspace(ctx, workspace, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, workspace.ID > 0)
+
+	accessLevel, err := getWorkspaceAccessLevel(ctx, workspace.ID, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, core.WorkspaceAccessLevelOwner, accessLevel)
+
+	other := testutil.UserFactory(ctx, 1)[0]
+	accessLevel, err = getWorkspaceAccessLevel(ctx, workspace.ID, other.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, accessLevel)
+}
+
+func TestUpdateWorkspace(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	workspace.Name = "renamed"
+
+	err := updateWorkspace(ctx, workspace)
+	assert.NoError(t, err)
+	assert.NotNil(t, workspace.UpdatedAt)
+
+	retrieved, err := getWorkspaceByID(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", retrieved.Name)
+}
+
+func TestDeleteWorkspace(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+
+	var project struct{ ID int64 }
+	err := db.WriterQuery(&project, "insert into projects (name, workspace_id) values ('project', ?) returning id", workspace.ID)
+	assert.NoError(t, err)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeProduction)
+	err = db.WriterExec("insert into resources (name, type, payload, project_id, environment_id) values ('db', 'postgres', '{}', ?, ?)", project.ID, environment.ID)
+	assert.NoError(t, err)
+
+	// workspace with active resource is kept
+	activeResources, err := deleteWorkspace(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, activeResources)
+	retrieved, err := getWorkspaceByID(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, retrieved)
+
+	err = db.WriterExec("update resources set deleted_at = now() where project_id = ?", project.ID)
+	assert.NoError(t, err)
+	activeResources, err = deleteWorkspace(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, activeResources)
+
+	retrieved, err = getWorkspaceByID(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved)
+
+	var projectState struct{ Deleted bool }
+	err = db.Query(&projectState, "select deleted_at is not null as deleted from projects where id = ?", project.ID)
+	assert.NoError(t, err)
+	assert.True(t, projectState.Deleted)
+}
+
+func TestCreateWorkspaceSlug(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	user := testutil.UserFactory(ctx, 1)[0]
+	name := "Slug " + faker.UUIDDigit()
+
+	first := &core.Workspace{Name: name}
+	err := createWorkspace(ctx, first, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, slugify(name), first.Slug)
+
+	second := &core.Workspace{Name: name}
+	err = createWorkspace(ctx, second, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, slugify(name)+"-2", second.Slug)
+
+	err = createWorkspace(ctx, &core.Workspace{Name: "other", Slug: first.Slug}, user.ID)
+	assert.Equal(t, errSlugTaken, err)
+}
+
+func TestUpdateWorkspaceSlug(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspaces := testutil.WorkspaceFactory(ctx, 2)
+	workspace, other := workspaces[0], workspaces[1]
+	oldSlug := workspace.Slug
+
+	renamedSlug := "renamed-" + faker.UUIDDigit()
+	workspace.Slug = renamedSlug
+	err := updateWorkspace(ctx, workspace)
+	assert.NoError(t, err)
+
+	retrieved, err := getWorkspaceBySlug(ctx, workspace.Slug)
+	assert.NoError(t, err)
+	if assert.NotNil(t, retrieved) {
+		assert.Equal(t, workspace.ID, retrieved.ID)
+	}
+
+	retrieved, err = getWorkspaceBySlug(ctx, oldSlug)
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved)
+
+	redirected, err := getWorkspaceBySlugRedirect(ctx, oldSlug)
+	assert.NoError(t, err)
+	if assert.NotNil(t, redirected) {
+		assert.Equal(t, workspace.ID, redirected.ID)
+	}
+
+	// old slug is held by redirect
+	other.Slug = oldSlug
+	err = updateWorkspace(ctx, other)
+	assert.Equal(t, errSlugTaken, err)
+
+	// workspace can take back its old slug
+	workspace.Slug = oldSlug
+	err = updateWorkspace(ctx, workspace)
+	assert.NoError(t, err)
+	redirected, err = getWorkspaceBySlugRedirect(ctx, oldSlug)
+	assert.NoError(t, err)
+	assert.Nil(t, redirected)
+
+	// expired redirect frees the slug
+	available, err := slugAvailable(appctx.Database(ctx), renamedSlug, other.ID)
+	assert.NoError(t, err)
+	assert.False(t, available)
+	err = appctx.Database(ctx).WriterExec("update workspace_slug_redirects set expires_at = now() - interval '1 minute' where workspace_id = ?", workspace.ID)
+	assert.NoError(t, err)
+	available, err = slugAvailable(appctx.Database(ctx), renamedSlug, other.ID)
+	assert.NoError(t, err)
+	assert.True(t, available)
+}
@@ -1,10 +1,13 @@
 package workspace
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
 )
 
 // @Id api.v1.workspace.listAll
@@ -28,3 +31,148 @@ func HandleListAll(w http.ResponseWriter, r *http.Request) {
 
 	apihelper.JSON(w, http.StatusOK, workspaces)
 }
+
+// @Id api.v1.workspace.create
+// @Summary Create workspace owned by current authenticated user
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param param body saveWorkspaceParam true "Request body"
+// @Router /v1/workspaces [post]
+// @Produce json
+// @Success 201 {object} core.Workspace
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	currentUser := appctx.AuthenticatedUser(r.Context())
+
+	var param saveWorkspaceParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	workspace := &core.Workspace{Name: param.Name, Slug: param.Slug}
+	err := createWorkspace(r.Context(), workspace, currentUser.ID)
+	if err == errSlugTaken {
+		slugTakenResp(w)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusCreated, workspace)
+}
+
+// @Id api.v1.workspace.get
+// @Summary Get workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id} [get]
+// @Produce json
+// @Success 200 {object} core.Workspace
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	apihelper.JSON(w, http.StatusOK, currentWorkspace)
+}
+
+// @Id api.v1.workspace.update
+// @Summary Rename workspace, previous slug keeps redirecting for 90 days
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body saveWorkspaceParam true "Request body"
+// @Router /v1/workspaces/{workspace_id} [patch]
+// @Produce json
+// @Success 200 {object} core.Workspace
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	var param saveWorkspaceParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	workspace := *currentWorkspace
+	workspace.Name = param.Name
+	if param.Slug != "" {
+		workspace.Slug = param.Slug
+	}
+	err := updateWorkspace(r.Context(), &workspace)
+	if err == errSlugTaken {
+		slugTakenResp(w)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, workspace)
+}
+
+// @Id api.v1.workspace.delete
+// @Summary Delete workspace together with its projects and repository connections
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	activeResources, err := deleteWorkspace(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if activeResources > 0 {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"resources": fmt.Sprintf("workspace still has %d active resources", activeResources),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func slugTakenResp(w http.ResponseWriter) {
+	apihelper.ConflictErrResp(w, "conflict", map[string]string{
+		"slug": "already taken",
+	})
+}
@@ -0,0 +1,331 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/internal/coreapi/workspace/webhook"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errAlreadyMember     = errors.New("user is already member of the workspace")
+	errPendingInvitation = errors.New("email already has pending invitation")
+	errInvalidInvitation = errors.New("invitation does not exist, expired, revoked or already accepted")
+)
+
+func getPendingInvitations(ctx context.Context, workspaceID int64) ([]*core.WorkspaceInvitation, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from workspace_invitations
+        where workspace_id = ? and status = 'pending'
+        order by created_at desc, id desc
+    `
+	var invitations []*core.WorkspaceInvitation
+	err := db.Query(&invitations, query, workspaceID)
+	if err != nil {
+		return []*core.WorkspaceInvitation{}, err
+	}
+	if invitations == nil {
+		invitations = []*core.WorkspaceInvitation{}
+	}
+	return invitations, nil
+}
+
+// createInvitation stores new pending invitation, expired pending invitation for the same email is revoked first
+func createInvitation(ctx context.Context, inv *core.WorkspaceInvitation, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryMember = `
+        select count(*) as count
+        from workspace_users
+        join users on users.id = workspace_users.user_id
+        where
+            workspace_users.workspace_id = ?
+            and workspace_users.deleted_at is null
+            and lower(users.email) = lower(?)
+    `
+	var member struct{ Count int }
+	err = tx.Query(&member, queryMember, inv.WorkspaceID, inv.Email)
+	if err != nil {
+		return err
+	}
+	if member.Count > 0 {
+		return errAlreadyMember
+	}
+
+	var queryRevokeExpired = `
+        update workspace_invitations
+        set status = 'revoked', updated_at = now()
+        where
+            workspace_id = ?
+            and lower(email) = lower(?)
+            and status = 'pending'
+            and expires_at <= now()
+    `
+	err = tx.Exec(queryRevokeExpired, inv.WorkspaceID, inv.Email)
+	if err != nil {
+		return err
+	}
+
+	var queryPending = `
+        select count(*) as count
+        from workspace_invitations
+        where
+            workspace_id = ?
+            and lower(email) = lower(?)
+            and status = 'pending'
+    `
+	var pending struct{ Count int }
+	err = tx.Query(&pending, queryPending, inv.WorkspaceID, inv.Email)
+	if err != nil {
+		return err
+	}
+	if pending.Count > 0 {
+		return errPendingInvitation
+	}
+
+	err = quota.Reserve(tx, inv.WorkspaceID, quota.Request{Invitations: 1})
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        insert into workspace_invitations (workspace_id, inviter_user_id, email, access_level, token_hash, expires_at, created_at)
+        values (?, ?, ?, ?, ?, ?, now())
+        returning id, status, created_at
+    `
+	err = tx.Query(inv, query, inv.WorkspaceID, inv.InviterUserID, inv.Email, inv.AccessLevel, inv.TokenHash, inv.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, inv.WorkspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbCreated,
+		TargetType: activity.TargetInvitation,
+		TargetID:   inv.ID,
+		Metadata: map[string]interface{}{
+			"email":        inv.Email,
+			"access_level": inv.AccessLevel,
+		},
+	})
+}
+
+// renewInvitation replaces token and expiry of a pending invitation, returns nil when it is not pending
+func renewInvitation(ctx context.Context, workspaceID, id int64, tokenHash []byte, expiresAt time.Time) (*core.WorkspaceInvitation, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update workspace_invitations
+        set token_hash = ?, expires_at = ?, updated_at = now()
+        where id = ? and workspace_id = ? and status = 'pending'
+        returning *
+    `
+	var inv core.WorkspaceInvitation
+	err := db.WriterQuery(&inv, query, tokenHash, expiresAt, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.ID == 0 {
+		return nil, nil
+	}
+	return &inv, nil
+}
+
+// revokeInvitation returns false when invitation is not pending
+func revokeInvitation(ctx context.Context, workspaceID, id, actorID int64) (revoked bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update workspace_invitations
+        set status = 'revoked', updated_at = now()
+        where id = ? and workspace_id = ? and status = 'pending'
+        returning *
+    `
+	var inv core.WorkspaceInvitation
+	err = tx.Query(&inv, query, id, workspaceID)
+	if err != nil {
+		return false, err
+	}
+	if inv.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbRevoked,
+		TargetType: activity.TargetInvitation,
+		TargetID:   inv.ID,
+		Metadata: map[string]interface{}{
+			"email": inv.Email,
+		},
+	})
+	return err == nil, err
+}
+
+// acceptInvitation accepts invitation identified by its token hash on behalf of user
+func acceptInvitation(ctx context.Context, tokenHash []byte, userID int64) (inv *core.WorkspaceInvitation, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        select workspace_invitations.*
+        from workspace_invitations
+        join workspaces on workspaces.id = workspace_invitations.workspace_id
+        where
+            workspace_invitations.token_hash = ?
+            and workspace_invitations.status = 'pending'
+            and workspace_invitations.expires_at > now()
+            and workspaces.deleted_at is null
+        for update of workspace_invitations
+    `
+	var returned core.WorkspaceInvitation
+	err = tx.Query(&returned, query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if returned.ID == 0 {
+		return nil, errInvalidInvitation
+	}
+
+	err = claim(tx, &returned, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &returned, nil
+}
+
+// ClaimPending accepts all pending invitations sent to the user email,
+// it is called inside sign up transaction so invited user lands in the workspaces right away.
+// Invitations to workspaces which are already full stay pending instead of failing the sign up.
+func ClaimPending(tx hansip.Transaction, user *core.User) error {
+	var query = `
+        select workspace_invitations.*
+        from workspace_invitations
+        join workspaces on workspaces.id = workspace_invitations.workspace_id
+        where
+            lower(workspace_invitations.email) = lower(?)
+            and workspace_invitations.status = 'pending'
+            and workspace_invitations.expires_at > now()
+            and workspaces.deleted_at is null
+        order by workspace_invitations.id
+        for update of workspace_invitations
+    `
+	var invitations []*core.WorkspaceInvitation
+	err := tx.Query(&invitations, query, user.Email)
+	if err != nil {
+		return err
+	}
+
+	for _, inv := range invitations {
+		err := claim(tx, inv, user.ID)
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim adds user to the invitation workspace and marks invitation as accepted,
+// existing membership is kept as is
+func claim(tx hansip.Transaction, inv *core.WorkspaceInvitation, userID int64) error {
+	var queryExisting = `
+        select count(*) as count
+        from workspace_users
+        where workspace_id = ? and user_id = ? and deleted_at is null
+    `
+	var existing struct{ Count int }
+	err := tx.Query(&existing, queryExisting, inv.WorkspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if existing.Count == 0 {
+		err = quota.ReserveMember(tx, inv.WorkspaceID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var queryMember = `
+        insert into workspace_users (workspace_id, user_id, access_level, created_at)
+        values (?, ?, ?, now())
+        on conflict (workspace_id, user_id) where deleted_at is null do nothing
+    `
+	err = tx.Exec(queryMember, inv.WorkspaceID, userID, inv.AccessLevel)
+	if err != nil {
+		return err
+	}
+
+	var queryInvitation = `
+        update workspace_invitations
+        set status = 'accepted', accepted_user_id = ?, accepted_at = now(), updated_at = now()
+        where id = ?
+        returning *
+    `
+	err = tx.Query(inv, queryInvitation, userID, inv.ID)
+	if err != nil {
+		return err
+	}
+
+	if existing.Count == 0 {
+		err = webhook.Enqueue(tx, inv.WorkspaceID, core.WebhookEventMemberJoined, map[string]interface{}{
+			"user_id":      userID,
+			"access_level": inv.AccessLevel,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return activity.Record(tx, inv.WorkspaceID, &activity.Event{
+		ActorID:    userID,
+		Verb:       activity.VerbJoined,
+		TargetType: activity.TargetMember,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"invitation_id": inv.ID,
+			"access_level":  inv.AccessLevel,
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package invitation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateInvitation(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	owner := testutil.UserFactory(ctx, 1)[0]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+
+	newInvitation := func(email string) *core.WorkspaceInvitation {
+		_, tokenHash, err := core.BuildWorkspaceInvitationToken([]byte("secret"), tokenLength)
+		assert.NoError(t, err)
+		return &core.WorkspaceInvitation{
+			WorkspaceID:   workspace.ID,
+			InviterUserID: owner.ID,
+			Email:         email,
+			AccessLevel:   core.WorkspaceAccessLevelEditor,
+			TokenHash:     tokenHash,
+			ExpiresAt:     time.Now().Add(core.WorkspaceInvitationMaxDuration),
+		}
+	}
+
+	email := faker.Word() + "_" + faker.Email()
+	inv := newInvitation(email)
+	err := createInvitation(ctx, inv, owner.ID)
+	assert.NoError(t, err)
+	assert.True(t, inv.ID > 0)
+	assert.Equal(t, core.WorkspaceInvitationStatusPending, inv.Status)
+
+	err = createInvitation(ctx, newInvitation(email), owner.ID)
+	assert.Equal(t, errPendingInvitation, err)
+
+	err = createInvitation(ctx, newInvitation(owner.Email), owner.ID)
+	assert.Equal(t, errAlreadyMember, err)
+
+	// expired invitation does not block new one
+	err = appctx.Database(ctx).WriterExec("update workspace_invitations set expires_at = now() - interval '1 minute' where id = ?", inv.ID)
+	assert.NoError(t, err)
+	err = createInvitation(ctx, newInvitation(email), owner.ID)
+	assert.NoError(t, err)
+
+	invitations, err := getPendingInvitations(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Len(t, invitations, 1)
+}
+
+func TestRenewAndRevokeInvitation(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	owner := testutil.UserFactory(ctx, 1)[0]
+	inv := testutil.WorkspaceInvitationFactory(ctx, workspace.ID, owner.ID, faker.Email())
+
+	expiresAt := time.Now().Add(time.Hour)
+	renewed, err := renewInvitation(ctx, workspace.ID, inv.ID, []byte("renewed"), expiresAt)
+	assert.NoError(t, err)
+	if assert.NotNil(t, renewed) {
+		assert.Equal(t, []byte("renewed"), renewed.TokenHash)
+		assert.WithinDuration(t, expiresAt, renewed.ExpiresAt, time.Second)
+	}
+
+	revoked, err := revokeInvitation(ctx, workspace.ID, inv.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = revokeInvitation(ctx, workspace.ID, inv.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	renewed, err = renewInvitation(ctx, workspace.ID, inv.ID, []byte("again"), expiresAt)
+	assert.NoError(t, err)
+	assert.Nil(t, renewed)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	owner, invitee := users[0], users[1]
+	inv := testutil.WorkspaceInvitationFactory(ctx, workspace.ID, owner.ID, faker.Email())
+
+	accepted, err := acceptInvitation(ctx, inv.TokenHash, invitee.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, accepted) {
+		assert.Equal(t, core.WorkspaceInvitationStatusAccepted, accepted.Status)
+		assert.Equal(t, invitee.ID, *accepted.AcceptedUserID)
+	}
+
+	var member struct{ AccessLevel string }
+	err = appctx.Database(ctx).Query(&member, "select access_level from workspace_users where workspace_id = ? and user_id = ? and deleted_at is null", workspace.ID, invitee.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, inv.AccessLevel, member.AccessLevel)
+
+	_, err = acceptInvitation(ctx, inv.TokenHash, invitee.ID)
+	assert.Equal(t, errInvalidInvitation, err)
+
+	_, err = acceptInvitation(ctx, []byte("unknown"), invitee.ID)
+	assert.Equal(t, errInvalidInvitation, err)
+}
+
+func TestClaimPending(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspaces := testutil.WorkspaceFactory(ctx, 2)
+	users := testutil.UserFactory(ctx, 2)
+	owner, invitee := users[0], users[1]
+	testutil.WorkspaceInvitationFactory(ctx, workspaces[0].ID, owner.ID, invitee.Email)
+	expired := testutil.WorkspaceInvitationFactory(ctx, workspaces[1].ID, owner.ID, invitee.Email)
+	err := appctx.Database(ctx).WriterExec("update workspace_invitations set expires_at = now() - interval '1 minute' where id = ?", expired.ID)
+	assert.NoError(t, err)
+
+	tx, err := appctx.Database(ctx).NewTransaction()
+	assert.NoError(t, err)
+	err = ClaimPending(tx, invitee)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+
+	var members []struct{ WorkspaceID int64 }
+	err = appctx.Database(ctx).Query(&members, "select workspace_id from workspace_users where user_id = ? and deleted_at is null", invitee.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, workspaces[0].ID, members[0].WorkspaceID)
+	}
+}
@@ -0,0 +1,256 @@
+package invitation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.invitation.listAll
+// @Summary List pending invitations of a workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/invitations [get]
+// @Produce json
+// @Success 200 {array} core.WorkspaceInvitation
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	invitations, err := getPendingInvitations(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, invitations)
+}
+
+// @Id api.v1.workspace.invitation.create
+// @Summary Invite an email address to join workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body createInvitationParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/invitations [post]
+// @Produce json
+// @Success 201 {object} core.WorkspaceInvitation
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(secretKey []byte, acceptURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := appctx.AuthenticatedUser(r.Context())
+		currentWorkspace := appctx.CurrentWorkspace(r.Context())
+		actor := appctx.Actor(r.Context())
+
+		var param createInvitationParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		param.Email = strings.TrimSpace(param.Email)
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		token, tokenHash, err := core.BuildWorkspaceInvitationToken(secretKey, tokenLength)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		inv := &core.WorkspaceInvitation{
+			WorkspaceID:   currentWorkspace.ID,
+			InviterUserID: currentUser.ID,
+			Email:         param.Email,
+			AccessLevel:   param.AccessLevel,
+			Token:         token,
+			TokenHash:     tokenHash,
+			ExpiresAt:     time.Now().Add(core.WorkspaceInvitationMaxDuration),
+		}
+		err = createInvitation(r.Context(), inv, actor.ID)
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+			return
+		}
+		switch err {
+		case nil:
+		case errAlreadyMember:
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"email": "already member of the workspace",
+			})
+			return
+		case errPendingInvitation:
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"email": "already invited, resend the pending invitation instead",
+			})
+			return
+		default:
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		if err := sendInvitation(r.Context(), inv, currentWorkspace, currentUser, acceptURL); err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusCreated, inv)
+	}
+}
+
+// @Id api.v1.workspace.invitation.resend
+// @Summary Resend pending invitation with a new link and expiry
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param invitation_id path integer true "Invitation id"
+// @Router /v1/workspaces/{workspace_id}/invitations/{invitation_id}/resend [post]
+// @Produce json
+// @Success 200 {object} core.WorkspaceInvitation
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleResend(secretKey []byte, acceptURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := appctx.AuthenticatedUser(r.Context())
+		currentWorkspace := appctx.CurrentWorkspace(r.Context())
+		invitationID, _ := strconv.ParseInt(chi.URLParam(r, "invitation_id"), 10, 64)
+
+		token, tokenHash, err := core.BuildWorkspaceInvitationToken(secretKey, tokenLength)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		inv, err := renewInvitation(r.Context(), currentWorkspace.ID, invitationID, tokenHash, time.Now().Add(core.WorkspaceInvitationMaxDuration))
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if inv == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		inv.Token = token
+
+		if err := sendInvitation(r.Context(), inv, currentWorkspace, currentUser, acceptURL); err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusOK, inv)
+	}
+}
+
+// @Id api.v1.workspace.invitation.delete
+// @Summary Revoke pending invitation
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param invitation_id path integer true "Invitation id"
+// @Router /v1/workspaces/{workspace_id}/invitations/{invitation_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+	invitationID, _ := strconv.ParseInt(chi.URLParam(r, "invitation_id"), 10, 64)
+
+	revoked, err := revokeInvitation(r.Context(), currentWorkspace.ID, invitationID, actor.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !revoked {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspaceInvitation.accept
+// @Summary Accept workspace invitation as current authenticated user
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param param body acceptInvitationParam true "Request body"
+// @Router /v1/workspace-invitations/accept [post]
+// @Produce json
+// @Success 200 {object} core.WorkspaceInvitation
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleAccept(secretKey []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentUser := appctx.AuthenticatedUser(r.Context())
+
+		var param acceptInvitationParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		tokenHash, err := core.HashHMAC(secretKey, []byte(param.Token))
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		inv, err := acceptInvitation(r.Context(), tokenHash, currentUser.ID)
+		if err == errInvalidInvitation {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"token": "invitation is invalid or expired",
+			})
+			return
+		}
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusOK, inv)
+	}
+}
@@ -0,0 +1,38 @@
+package invitation
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/mailer"
+)
+
+const tokenLength = 32
+
+// sendInvitation mails the invitation link, token is only known right after it is generated
+func sendInvitation(ctx context.Context, inv *core.WorkspaceInvitation, workspace *core.Workspace, inviter *core.User, acceptURL string) error {
+	link, err := url.Parse(acceptURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", inv.Token)
+	link.RawQuery = query.Encode()
+
+	msg := &mailer.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("%s invited you to %s on Awanku", inviter.Name, workspace.Name),
+		Body: fmt.Sprintf(
+			"%s invited you to join workspace %s on Awanku as %s.\n\nAccept the invitation here: %s\n\nThe invitation expires on %s. If you were not expecting this, you can ignore this email.",
+			inviter.Name,
+			workspace.Name,
+			inv.AccessLevel,
+			link.String(),
+			inv.ExpiresAt.UTC().Format("2 January 2006 15:04 MST"),
+		),
+	}
+	return appctx.Mailer(ctx).Send(ctx, msg)
+}
@@ -0,0 +1,33 @@
+package invitation
+
+import (
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type createInvitationParam struct {
+	Email       string `json:"email" validate:"required"`
+	AccessLevel string `json:"access_level" validate:"required"`
+}
+
+func (p createInvitationParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Email, validation.Required, validation.Length(1, 500), is.EmailFormat),
+		validation.Field(&p.AccessLevel, validation.Required, validation.In(
+			core.WorkspaceAccessLevelOwner,
+			core.WorkspaceAccessLevelEditor,
+			core.WorkspaceAccessLevelViewer,
+		)),
+	)
+}
+
+type acceptInvitationParam struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (p acceptInvitationParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Token, validation.Required),
+	)
+}
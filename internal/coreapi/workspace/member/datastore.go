@@ -0,0 +1,446 @@
+package member
+
+import (
+	"context"
+	"errors"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/webhook"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errNotMember = errors.New("user is not member of the workspace")
+	errLastOwner = errors.New("workspace must have at least one owner")
+
+	errInvalidTransferRecipient = errors.New("ownership can only be transferred to a member who is not an owner")
+	errTransferNotApplicable    = errors.New("ownership transfer no longer applies to current members")
+)
+
+func getMembers(ctx context.Context, workspaceID int64) ([]*core.WorkspaceMember, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            users.id as user_id,
+            users.name,
+            users.email,
+            workspace_users.access_level,
+            workspace_users.created_at as joined_at
+        from workspace_users
+        join users on users.id = workspace_users.user_id
+        where
+            workspace_users.workspace_id = ?
+            and workspace_users.deleted_at is null
+            and users.deleted_at is null
+        order by workspace_users.created_at asc, users.id asc
+    `
+	var members []*core.WorkspaceMember
+	err := db.Query(&members, query, workspaceID)
+	if err != nil {
+		return []*core.WorkspaceMember{}, err
+	}
+	if members == nil {
+		members = []*core.WorkspaceMember{}
+	}
+	return members, nil
+}
+
+func getMember(ctx context.Context, workspaceID, userID int64) (*core.WorkspaceMember, error) {
+	members, err := getMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.UserID == userID {
+			return member, nil
+		}
+	}
+	return nil, nil
+}
+
+// getTransferMembers returns proposing owner and recipient of ownership transfer as seen by tx
+func getTransferMembers(tx hansip.Transaction, transfer *core.WorkspaceOwnershipTransfer) (from, to *core.WorkspaceMember, err error) {
+	var query = `
+        select
+            users.id as user_id,
+            users.name,
+            users.email,
+            workspace_users.access_level,
+            workspace_users.created_at as joined_at
+        from workspace_users
+        join users on users.id = workspace_users.user_id
+        where
+            workspace_users.workspace_id = ?
+            and workspace_users.user_id in (?, ?)
+            and workspace_users.deleted_at is null
+            and users.deleted_at is null
+    `
+	var members []*core.WorkspaceMember
+	err = tx.Query(&members, query, transfer.WorkspaceID, transfer.FromUserID, transfer.ToUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, member := range members {
+		switch member.UserID {
+		case transfer.FromUserID:
+			from = member
+		case transfer.ToUserID:
+			to = member
+		}
+	}
+	if from == nil || to == nil {
+		return nil, nil, errTransferNotApplicable
+	}
+	return from, to, nil
+}
+
+// lockMembers locks membership rows of the workspace so owners can not be changed concurrently,
+// it returns access level of each member keyed by user id
+func lockMembers(tx hansip.Transaction, workspaceID int64) (map[int64]string, error) {
+	var query = `
+        select user_id, access_level
+        from workspace_users
+        where workspace_id = ? and deleted_at is null
+        for update
+    `
+	var rows []struct {
+		UserID      int64
+		AccessLevel string
+	}
+	err := tx.Query(&rows, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[int64]string{}
+	for _, row := range rows {
+		members[row.UserID] = row.AccessLevel
+	}
+	return members, nil
+}
+
+func countOwners(members map[int64]string) int {
+	var count int
+	for _, accessLevel := range members {
+		if accessLevel == core.WorkspaceAccessLevelOwner {
+			count++
+		}
+	}
+	return count
+}
+
+func changeMemberAccessLevel(ctx context.Context, workspaceID, actorID, userID int64, accessLevel string) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	members, err := lockMembers(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	previous, ok := members[userID]
+	if !ok {
+		return errNotMember
+	}
+	if previous == accessLevel {
+		return nil
+	}
+	if previous == core.WorkspaceAccessLevelOwner && countOwners(members) == 1 {
+		return errLastOwner
+	}
+
+	var query = `
+        update workspace_users
+        set access_level = ?, updated_at = now()
+        where workspace_id = ? and user_id = ? and deleted_at is null
+    `
+	err = tx.Exec(query, accessLevel, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbAccessLevelChanged,
+		TargetType: activity.TargetMember,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"access_level":          accessLevel,
+			"previous_access_level": previous,
+		},
+	})
+}
+
+// removeMember removes user from workspace together with its access to workspace projects,
+// the last owner can not be removed
+func removeMember(ctx context.Context, workspaceID, actorID, userID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	members, err := lockMembers(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	accessLevel, ok := members[userID]
+	if !ok {
+		return errNotMember
+	}
+	if accessLevel == core.WorkspaceAccessLevelOwner && countOwners(members) == 1 {
+		return errLastOwner
+	}
+
+	var queries = []string{
+		`update workspace_users set deleted_at = now() where workspace_id = ? and user_id = ? and deleted_at is null`,
+		`update project_users set deleted_at = now() where project_id in (select id from projects where workspace_id = ?) and user_id = ? and deleted_at is null`,
+	}
+	for _, query := range queries {
+		if err = tx.Exec(query, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	err = webhook.Enqueue(tx, workspaceID, core.WebhookEventMemberRemoved, map[string]interface{}{
+		"user_id": userID,
+		"left":    actorID == userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	verb := activity.VerbRemoved
+	if actorID == userID {
+		verb = activity.VerbLeft
+	}
+	return activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetMember,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"access_level": accessLevel,
+		},
+	})
+}
+
+func getPendingOwnershipTransfer(ctx context.Context, workspaceID int64) (*core.WorkspaceOwnershipTransfer, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from workspace_ownership_transfers
+        where workspace_id = ? and status = 'pending'
+    `
+	var transfer core.WorkspaceOwnershipTransfer
+	err := db.Query(&transfer, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ID == 0 {
+		return nil, nil
+	}
+	return &transfer, nil
+}
+
+// proposeOwnershipTransfer stores new pending transfer, replacing the pending one of the workspace if any,
+// it returns proposing owner and recipient read while their membership is locked
+func proposeOwnershipTransfer(ctx context.Context, transfer *core.WorkspaceOwnershipTransfer, actorID int64) (from, to *core.WorkspaceMember, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	members, err := lockMembers(tx, transfer.WorkspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if members[transfer.FromUserID] != core.WorkspaceAccessLevelOwner {
+		return nil, nil, errTransferNotApplicable
+	}
+	if accessLevel, ok := members[transfer.ToUserID]; !ok || accessLevel == core.WorkspaceAccessLevelOwner {
+		return nil, nil, errInvalidTransferRecipient
+	}
+
+	var queryCancel = `
+        update workspace_ownership_transfers
+        set status = 'cancelled', updated_at = now()
+        where workspace_id = ? and status = 'pending'
+    `
+	err = tx.Exec(queryCancel, transfer.WorkspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var query = `
+        insert into workspace_ownership_transfers (workspace_id, from_user_id, to_user_id, created_at)
+        values (?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(transfer, query, transfer.WorkspaceID, transfer.FromUserID, transfer.ToUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = activity.Record(tx, transfer.WorkspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbProposed,
+		TargetType: activity.TargetOwnershipTransfer,
+		TargetID:   transfer.ID,
+		Metadata: map[string]interface{}{
+			"from_user_id": transfer.FromUserID,
+			"to_user_id":   transfer.ToUserID,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return getTransferMembers(tx, transfer)
+}
+
+// cancelOwnershipTransfer returns false when the transfer is no longer pending
+func cancelOwnershipTransfer(ctx context.Context, transfer *core.WorkspaceOwnershipTransfer, actorID int64) (cancelled bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update workspace_ownership_transfers
+        set status = 'cancelled', updated_at = now()
+        where id = ? and status = 'pending'
+        returning *
+    `
+	var returned core.WorkspaceOwnershipTransfer
+	err = tx.Query(&returned, query, transfer.ID)
+	if err != nil {
+		return false, err
+	}
+	if returned.ID == 0 {
+		return false, nil
+	}
+	*transfer = returned
+
+	err = activity.Record(tx, transfer.WorkspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbCancelled,
+		TargetType: activity.TargetOwnershipTransfer,
+		TargetID:   transfer.ID,
+	})
+	return err == nil, err
+}
+
+// acceptOwnershipTransfer makes the recipient an owner and demotes the proposing owner to editor in a single transaction,
+// it returns nil when the user has no pending transfer to accept, otherwise also the members with their new access levels
+func acceptOwnershipTransfer(ctx context.Context, workspaceID, userID, actorID int64) (transfer *core.WorkspaceOwnershipTransfer, from, to *core.WorkspaceMember, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryTransfer = `
+        select *
+        from workspace_ownership_transfers
+        where workspace_id = ? and to_user_id = ? and status = 'pending'
+        for update
+    `
+	var returned core.WorkspaceOwnershipTransfer
+	err = tx.Query(&returned, queryTransfer, workspaceID, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if returned.ID == 0 {
+		return nil, nil, nil, nil
+	}
+
+	members, err := lockMembers(tx, workspaceID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if _, ok := members[returned.ToUserID]; !ok || members[returned.FromUserID] != core.WorkspaceAccessLevelOwner {
+		return nil, nil, nil, errTransferNotApplicable
+	}
+
+	var queryMember = `
+        update workspace_users
+        set access_level = ?, updated_at = now()
+        where workspace_id = ? and user_id = ? and deleted_at is null
+    `
+	err = tx.Exec(queryMember, core.WorkspaceAccessLevelOwner, workspaceID, returned.ToUserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	err = tx.Exec(queryMember, core.WorkspaceAccessLevelEditor, workspaceID, returned.FromUserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var queryAccept = `
+        update workspace_ownership_transfers
+        set status = 'accepted', accepted_at = now(), updated_at = now()
+        where id = ?
+        returning *
+    `
+	err = tx.Query(&returned, queryAccept, returned.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbAccepted,
+		TargetType: activity.TargetOwnershipTransfer,
+		TargetID:   returned.ID,
+		Metadata: map[string]interface{}{
+			"from_user_id": returned.FromUserID,
+			"to_user_id":   returned.ToUserID,
+		},
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	from, to, err = getTransferMembers(tx, &returned)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return &returned, from, to, nil
+}
@@ -0,0 +1,188 @@
+package member
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func countActivities(t *testing.T, ctx context.Context, workspaceID int64) int {
+	var returned struct{ Count int }
+	err := appctx.Database(ctx).Query(&returned, "select count(*) as count from workspace_activity_logs where workspace_id = ?", workspaceID)
+	assert.NoError(t, err)
+	return returned.Count
+}
+
+func TestGetMembers(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, users[0].ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, users[1].ID, core.WorkspaceAccessLevelViewer)
+
+	members, err := getMembers(ctx, workspace.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, users[0].ID, members[0].UserID)
+		assert.Equal(t, core.WorkspaceAccessLevelOwner, members[0].AccessLevel)
+		assert.Equal(t, users[1].Email, members[1].Email)
+		assert.Equal(t, core.WorkspaceAccessLevelViewer, members[1].AccessLevel)
+	}
+}
+
+func TestChangeMemberAccessLevel(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	owner, viewer := users[0], users[1]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, viewer.ID, core.WorkspaceAccessLevelViewer)
+
+	err := changeMemberAccessLevel(ctx, workspace.ID, owner.ID, owner.ID, core.WorkspaceAccessLevelEditor)
+	assert.Equal(t, errLastOwner, err)
+
+	err = changeMemberAccessLevel(ctx, workspace.ID, owner.ID, viewer.ID, core.WorkspaceAccessLevelOwner)
+	assert.NoError(t, err)
+	member, err := getMember(ctx, workspace.ID, viewer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, core.WorkspaceAccessLevelOwner, member.AccessLevel)
+
+	err = changeMemberAccessLevel(ctx, workspace.ID, viewer.ID, owner.ID, core.WorkspaceAccessLevelEditor)
+	assert.NoError(t, err)
+
+	other := testutil.UserFactory(ctx, 1)[0]
+	err = changeMemberAccessLevel(ctx, workspace.ID, owner.ID, other.ID, core.WorkspaceAccessLevelEditor)
+	assert.Equal(t, errNotMember, err)
+
+	assert.Equal(t, 2, countActivities(t, ctx, workspace.ID))
+}
+
+func TestRemoveMember(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 3)
+	owner, editor, viewer := users[0], users[1], users[2]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, editor.ID, core.WorkspaceAccessLevelEditor)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, viewer.ID, core.WorkspaceAccessLevelViewer)
+
+	err := removeMember(ctx, workspace.ID, owner.ID, owner.ID)
+	assert.Equal(t, errLastOwner, err)
+
+	err = removeMember(ctx, workspace.ID, owner.ID, viewer.ID)
+	assert.NoError(t, err)
+
+	err = removeMember(ctx, workspace.ID, editor.ID, editor.ID)
+	assert.NoError(t, err)
+
+	err = removeMember(ctx, workspace.ID, owner.ID, viewer.ID)
+	assert.Equal(t, errNotMember, err)
+
+	members, err := getMembers(ctx, workspace.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, owner.ID, members[0].UserID)
+	}
+
+	// removed user can be added back
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, viewer.ID, core.WorkspaceAccessLevelViewer)
+	err = removeMember(ctx, workspace.ID, owner.ID, viewer.ID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, countActivities(t, ctx, workspace.ID))
+}
+
+func TestOwnershipTransfer(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 3)
+	owner, editor, outsider := users[0], users[1], users[2]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, editor.ID, core.WorkspaceAccessLevelEditor)
+
+	t.Run("recipient must be non owner member", func(t *testing.T) {
+		for _, userID := range []int64{owner.ID, outsider.ID} {
+			transfer := &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: owner.ID, ToUserID: userID}
+			_, _, err := proposeOwnershipTransfer(ctx, transfer, owner.ID)
+			assert.Equal(t, errInvalidTransferRecipient, err)
+		}
+	})
+
+	t.Run("new proposal replaces pending one", func(t *testing.T) {
+		first := &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: owner.ID, ToUserID: editor.ID}
+		_, _, err := proposeOwnershipTransfer(ctx, first, owner.ID)
+		assert.NoError(t, err)
+
+		second := &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: owner.ID, ToUserID: editor.ID}
+		from, to, err := proposeOwnershipTransfer(ctx, second, owner.ID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, from) && assert.NotNil(t, to) {
+			assert.Equal(t, owner.ID, from.UserID)
+			assert.Equal(t, editor.ID, to.UserID)
+		}
+
+		pending, err := getPendingOwnershipTransfer(ctx, workspace.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, second.ID, pending.ID)
+
+		cancelled, err := cancelOwnershipTransfer(ctx, pending, editor.ID)
+		assert.NoError(t, err)
+		assert.True(t, cancelled)
+		assert.Equal(t, core.WorkspaceOwnershipTransferStatusCancelled, pending.Status)
+	})
+
+	t.Run("only recipient can accept", func(t *testing.T) {
+		transfer := &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: owner.ID, ToUserID: editor.ID}
+		_, _, err := proposeOwnershipTransfer(ctx, transfer, owner.ID)
+		assert.NoError(t, err)
+
+		accepted, _, _, err := acceptOwnershipTransfer(ctx, workspace.ID, owner.ID, owner.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, accepted)
+
+		accepted, from, to, err := acceptOwnershipTransfer(ctx, workspace.ID, editor.ID, editor.ID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, accepted) {
+			assert.Equal(t, core.WorkspaceOwnershipTransferStatusAccepted, accepted.Status)
+			assert.NotNil(t, accepted.AcceptedAt)
+			assert.Equal(t, core.WorkspaceAccessLevelEditor, from.AccessLevel)
+			assert.Equal(t, core.WorkspaceAccessLevelOwner, to.AccessLevel)
+		}
+
+		previousOwner, err := getMember(ctx, workspace.ID, owner.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, core.WorkspaceAccessLevelEditor, previousOwner.AccessLevel)
+
+		newOwner, err := getMember(ctx, workspace.ID, editor.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, core.WorkspaceAccessLevelOwner, newOwner.AccessLevel)
+	})
+
+	t.Run("transfer is void when proposing owner is demoted", func(t *testing.T) {
+		testutil.WorkspaceUserFactory(ctx, workspace.ID, outsider.ID, core.WorkspaceAccessLevelOwner)
+		transfer := &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: outsider.ID, ToUserID: owner.ID}
+		_, _, err := proposeOwnershipTransfer(ctx, transfer, outsider.ID)
+		assert.NoError(t, err)
+
+		err = changeMemberAccessLevel(ctx, workspace.ID, editor.ID, outsider.ID, core.WorkspaceAccessLevelViewer)
+		assert.NoError(t, err)
+
+		_, _, err = proposeOwnershipTransfer(ctx, &core.WorkspaceOwnershipTransfer{WorkspaceID: workspace.ID, FromUserID: outsider.ID, ToUserID: owner.ID}, outsider.ID)
+		assert.Equal(t, errTransferNotApplicable, err)
+
+		_, _, _, err = acceptOwnershipTransfer(ctx, workspace.ID, owner.ID, owner.ID)
+		assert.Equal(t, errTransferNotApplicable, err)
+	})
+}
@@ -0,0 +1,333 @@
+package member
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.member.listAll
+// @Summary List all members of a workspace with their access level
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/members [get]
+// @Produce json
+// @Success 200 {array} core.WorkspaceMember
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	members, err := getMembers(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, members)
+}
+
+// @Id api.v1.workspace.member.update
+// @Summary Change access level of a workspace member
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param user_id path integer true "User id"
+// @Param param body changeAccessLevelParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/members/{user_id} [patch]
+// @Produce json
+// @Success 200 {object} core.WorkspaceMember
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+
+	var param changeAccessLevelParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	err := changeMemberAccessLevel(r.Context(), currentWorkspace.ID, actor.ID, userID, param.AccessLevel)
+	if !handleMembershipErr(w, err) {
+		return
+	}
+
+	member, err := getMember(r.Context(), currentWorkspace.ID, userID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, member)
+}
+
+// @Id api.v1.workspace.member.delete
+// @Summary Remove a member from workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param user_id path integer true "User id"
+// @Router /v1/workspaces/{workspace_id}/members/{user_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+
+	err := removeMember(r.Context(), currentWorkspace.ID, actor.ID, userID)
+	if !handleMembershipErr(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspace.member.leave
+// @Summary Leave workspace as current authenticated user
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/leave [post]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleLeave(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	currentUser := appctx.AuthenticatedUser(r.Context())
+	actor := appctx.Actor(r.Context())
+
+	err := removeMember(r.Context(), currentWorkspace.ID, actor.ID, currentUser.ID)
+	if !handleMembershipErr(w, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMembershipErr writes error response and returns false when err is not nil
+func handleMembershipErr(w http.ResponseWriter, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case errNotMember:
+		w.WriteHeader(http.StatusNotFound)
+	case errLastOwner:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"access_level": "workspace must have at least one owner",
+		})
+	default:
+		apihelper.InternalServerErrResp(w, err)
+	}
+	return false
+}
+
+// @Id api.v1.workspace.ownershipTransfer.get
+// @Summary Get pending ownership transfer of a workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/ownership-transfer [get]
+// @Produce json
+// @Success 200 {object} core.WorkspaceOwnershipTransfer
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGetOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	transfer, err := getPendingOwnershipTransfer(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if transfer == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, transfer)
+}
+
+// @Id api.v1.workspace.ownershipTransfer.propose
+// @Summary Propose transferring workspace ownership to another member
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body proposeOwnershipTransferParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/ownership-transfer [post]
+// @Produce json
+// @Success 201 {object} core.WorkspaceOwnershipTransfer
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleProposeOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	currentUser := appctx.AuthenticatedUser(r.Context())
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+
+	var param proposeOwnershipTransferParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	transfer := &core.WorkspaceOwnershipTransfer{
+		WorkspaceID: currentWorkspace.ID,
+		FromUserID:  currentUser.ID,
+		ToUserID:    param.UserID,
+	}
+	from, to, err := proposeOwnershipTransfer(r.Context(), transfer, actor.ID)
+	if err == errInvalidTransferRecipient {
+		apihelper.ValidationErrResp(w, map[string]string{
+			"user_id": "must be a member who is not an owner",
+		})
+		return
+	}
+	if err == errTransferNotApplicable {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"ownership_transfer": "current user is no longer an owner of the workspace",
+		})
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	if err := notifyOwnershipProposed(r.Context(), currentWorkspace, from, to); err != nil {
+		log.Println("failed sending ownership transfer proposal:", err)
+	}
+
+	apihelper.JSON(w, http.StatusCreated, transfer)
+}
+
+// @Id api.v1.workspace.ownershipTransfer.accept
+// @Summary Accept ownership transfer proposed to current authenticated user
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/ownership-transfer/accept [post]
+// @Produce json
+// @Success 200 {object} core.WorkspaceOwnershipTransfer
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleAcceptOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	currentUser := appctx.AuthenticatedUser(r.Context())
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+
+	transfer, from, to, err := acceptOwnershipTransfer(r.Context(), currentWorkspace.ID, currentUser.ID, actor.ID)
+	if err == errTransferNotApplicable {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"ownership_transfer": "proposing owner or recipient membership has changed, ask for a new transfer",
+		})
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if transfer == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err := notifyOwnershipTransferred(r.Context(), currentWorkspace, from, to); err != nil {
+		log.Println("failed sending ownership transfer notification:", err)
+	}
+
+	apihelper.JSON(w, http.StatusOK, transfer)
+}
+
+// @Id api.v1.workspace.ownershipTransfer.cancel
+// @Summary Cancel pending ownership transfer, as an owner or as the recipient declining it
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/ownership-transfer [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCancelOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	currentUser := appctx.AuthenticatedUser(r.Context())
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+
+	transfer, err := getPendingOwnershipTransfer(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if transfer == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	accessLevel := appctx.WorkspaceAccessLevel(r.Context())
+	if accessLevel != core.WorkspaceAccessLevelOwner && transfer.ToUserID != currentUser.ID {
+		apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+			"access_level": "only owners or the recipient can cancel ownership transfer",
+		})
+		return
+	}
+
+	cancelled, err := cancelOwnershipTransfer(r.Context(), transfer, actor.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !cancelled {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package member
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/mailer"
+)
+
+func notifyOwnershipProposed(ctx context.Context, workspace *core.Workspace, from, to *core.WorkspaceMember) error {
+	msg := &mailer.Message{
+		To:      to.Email,
+		Subject: fmt.Sprintf("%s wants to transfer ownership of %s to you", from.Name, workspace.Name),
+		Body: fmt.Sprintf(
+			"%s proposed to make you the owner of workspace %s on Awanku.\n\nOpen the workspace on Awanku to accept or decline the transfer. Nothing changes until you accept it.",
+			from.Name,
+			workspace.Name,
+		),
+	}
+	return appctx.Mailer(ctx).Send(ctx, msg)
+}
+
+// notifyOwnershipTransferred sends confirmation to both previous and new owner
+func notifyOwnershipTransferred(ctx context.Context, workspace *core.Workspace, from, to *core.WorkspaceMember) error {
+	messages := []*mailer.Message{
+		{
+			To:      from.Email,
+			Subject: fmt.Sprintf("Ownership of %s has been transferred", workspace.Name),
+			Body: fmt.Sprintf(
+				"%s accepted your ownership transfer of workspace %s on Awanku. You are now an editor of the workspace.",
+				to.Name,
+				workspace.Name,
+			),
+		},
+		{
+			To:      to.Email,
+			Subject: fmt.Sprintf("You are now the owner of %s", workspace.Name),
+			Body: fmt.Sprintf(
+				"You accepted ownership of workspace %s on Awanku from %s. You are now an owner of the workspace.",
+				workspace.Name,
+				from.Name,
+			),
+		},
+	}
+	for _, msg := range messages {
+		if err := appctx.Mailer(ctx).Send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
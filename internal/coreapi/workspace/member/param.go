@@ -0,0 +1,30 @@
+package member
+
+import (
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type changeAccessLevelParam struct {
+	AccessLevel string `json:"access_level" validate:"required"`
+}
+
+func (p changeAccessLevelParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.AccessLevel, validation.Required, validation.In(
+			core.WorkspaceAccessLevelOwner,
+			core.WorkspaceAccessLevelEditor,
+			core.WorkspaceAccessLevelViewer,
+		)),
+	)
+}
+
+type proposeOwnershipTransferParam struct {
+	UserID int64 `json:"user_id" validate:"required"`
+}
+
+func (p proposeOwnershipTransferParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UserID, validation.Required, validation.Min(int64(1))),
+	)
+}
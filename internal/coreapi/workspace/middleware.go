@@ -4,24 +4,29 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
 	"github.com/go-chi/chi"
 )
 
+// CurrentWorkspaceMiddleware loads workspace from url by its id or slug, and authenticated user access level on it.
+// Workspace is reported as not found when authenticated user is neither a member nor granted access to any of its projects.
+// Previous slug of a renamed workspace is redirected to the current one.
 func CurrentWorkspaceMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentUser := appctx.AuthenticatedUser(r.Context())
+
 		workspaceID := chi.URLParam(r, "workspace_id")
-		parsedWorkspaceID, _ := strconv.ParseInt(workspaceID, 10, 64)
-		if parsedWorkspaceID <= 0 {
+		workspace, redirected, err := resolveWorkspace(r.Context(), workspaceID)
+		if err == errInvalidWorkspaceID {
 			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
 				"workspace_id": "invalid",
 			})
 			return
 		}
-
-		workspace, err := getWorkspaceByID(r.Context(), parsedWorkspaceID)
 		if err != nil {
 			apihelper.InternalServerErrResp(w, err)
 			return
@@ -31,7 +36,66 @@ func CurrentWorkspaceMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		accessLevel, err := getWorkspaceAccessLevel(r.Context(), workspace.ID, currentUser.ID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if accessLevel == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if redirected {
+			location := *r.URL
+			location.Path = strings.Replace(r.URL.Path, "/workspaces/"+workspaceID, "/workspaces/"+workspace.Slug, 1)
+			location.RawPath = ""
+			// temporary, old slug may be taken by another workspace once redirect expires
+			w.Header().Add("Location", location.RequestURI())
+			w.WriteHeader(http.StatusTemporaryRedirect)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), appctx.KeyCurrentWorkspace, workspace)
+		ctx = context.WithValue(ctx, appctx.KeyWorkspaceAccess, accessLevel)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// resolveWorkspace finds workspace by numeric id or slug, redirected is true when slug is a previous slug of the workspace
+func resolveWorkspace(ctx context.Context, workspaceID string) (workspace *core.Workspace, redirected bool, err error) {
+	if parsedWorkspaceID, err := strconv.ParseInt(workspaceID, 10, 64); err == nil {
+		if parsedWorkspaceID <= 0 {
+			return nil, false, errInvalidWorkspaceID
+		}
+		workspace, err := getWorkspaceByID(ctx, parsedWorkspaceID)
+		return workspace, false, err
+	}
+
+	if !slugPattern.MatchString(workspaceID) || len(workspaceID) > slugMaxLength {
+		return nil, false, errInvalidWorkspaceID
+	}
+	workspace, err = getWorkspaceBySlug(ctx, workspaceID)
+	if err != nil || workspace != nil {
+		return workspace, false, err
+	}
+	workspace, err = getWorkspaceBySlugRedirect(ctx, workspaceID)
+	return workspace, workspace != nil, err
+}
+
+// RequireAccessLevel only allows members having at least the required access level on current workspace,
+// it must be used after CurrentWorkspaceMiddleware
+func RequireAccessLevel(required string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessLevel := appctx.WorkspaceAccessLevel(r.Context())
+			if !core.WorkspaceAccessLevelAtLeast(accessLevel, required) {
+				apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+					"access_level": required + " access required",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package workspace
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type saveWorkspaceParam struct {
+	Name string `json:"name" validate:"required"`
+	// Slug is generated from name on create and kept as is on update when empty
+	Slug string `json:"slug"`
+}
+
+func (p saveWorkspaceParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 310)),
+		validation.Field(&p.Slug, slugRule),
+	)
+}
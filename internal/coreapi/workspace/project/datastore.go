@@ -0,0 +1,545 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/cursor"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errNameTaken           = errors.New("project with same name already exists")
+	errConnectionNotFound  = errors.New("repository connection does not exist")
+	errDestinationNotFound = errors.New("destination workspace does not exist")
+	errNotDestinationOwner = errors.New("owner access is required on destination workspace")
+	errSameWorkspace       = errors.New("project already belongs to the workspace")
+	errConnectionMissing   = errors.New("destination workspace has no matching repository connection")
+)
+
+// listProjects returns projects in creation order, one extra row is fetched to know whether next page exists.
+// Only projects granted to the user are returned when grantedToUserID is set.
+func listProjects(ctx context.Context, workspaceID, grantedToUserID int64, p *listProjectsParam) (projects []*core.Project, nextCursor string, err error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from projects
+        where
+            workspace_id = ?
+            and id > ?
+            and deleted_at is null
+            and (
+                ? = 0
+                or exists (
+                    select 1
+                    from project_users
+                    where project_users.project_id = projects.id and project_users.user_id = ? and project_users.deleted_at is null
+                )
+            )
+        order by id
+        limit ?
+    `
+	err = db.Query(&projects, query, workspaceID, p.afterID, grantedToUserID, grantedToUserID, p.Limit+1)
+	if err != nil {
+		return []*core.Project{}, "", err
+	}
+	if projects == nil {
+		projects = []*core.Project{}
+	}
+	if len(projects) > p.Limit {
+		projects = projects[:p.Limit]
+		nextCursor = cursor.Encode(projects[len(projects)-1].ID)
+	}
+	return projects, nextCursor, nil
+}
+
+func getProject(ctx context.Context, workspaceID, id int64) (*core.Project, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from projects
+        where id = ? and workspace_id = ? and deleted_at is null
+    `
+	var project core.Project
+	err := db.Query(&project, query, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if project.ID == 0 {
+		return nil, nil
+	}
+	return &project, nil
+}
+
+// getProjectAccessLevel returns access level granted on the project only, empty string when there is none
+func getProjectAccessLevel(ctx context.Context, projectID, userID int64) (string, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select access_level
+        from project_users
+        where project_id = ? and user_id = ? and deleted_at is null
+    `
+	var returned struct {
+		AccessLevel string
+	}
+	err := db.Query(&returned, query, projectID, userID)
+	if err != nil {
+		return "", err
+	}
+	return returned.AccessLevel, nil
+}
+
+// createProject saves project together with its default protected production environment
+func createProject(ctx context.Context, project *core.Project, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	err = quota.Reserve(tx, project.WorkspaceID, quota.Request{Projects: 1})
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        insert into projects (workspace_id, name, created_at)
+        values (?, ?, now())
+        returning *
+    `
+	err = tx.Query(project, query, project.WorkspaceID, project.Name)
+	if err != nil {
+		if isNameConflict(err) {
+			return errNameTaken
+		}
+		return err
+	}
+
+	var queryEnvironment = `
+        insert into project_environments (project_id, name, type, protected, created_at)
+        values (?, ?, ?, true, now())
+    `
+	err = tx.Exec(queryEnvironment, project.ID, core.DefaultProjectEnvironmentName, core.ProjectEnvironmentTypeProduction)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, project.WorkspaceID, projectEvent(project, actorID, activity.VerbCreated))
+}
+
+// renameProject returns nil when project does not exist
+func renameProject(ctx context.Context, workspaceID, id int64, name string, actorID int64) (project *core.Project, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryCurrent = `
+        select name
+        from projects
+        where id = ? and workspace_id = ? and deleted_at is null
+        for update
+    `
+	var current struct{ Name string }
+	err = tx.Query(&current, queryCurrent, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var query = `
+        update projects
+        set name = ?, updated_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	var updated core.Project
+	err = tx.Query(&updated, query, name, id, workspaceID)
+	if err != nil {
+		if isNameConflict(err) {
+			return nil, errNameTaken
+		}
+		return nil, err
+	}
+	if updated.ID == 0 {
+		return nil, nil
+	}
+
+	event := projectEvent(&updated, actorID, activity.VerbUpdated)
+	event.Metadata["previous_name"] = current.Name
+	err = activity.Record(tx, workspaceID, event)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// deleteProject moves project and its resources to trash, they are restored together from there
+func deleteProject(ctx context.Context, workspaceID, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update projects
+        set deleted_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	var project core.Project
+	err = tx.Query(&project, query, id, workspaceID)
+	if err != nil {
+		return false, err
+	}
+	if project.ID == 0 {
+		return false, nil
+	}
+
+	// now() is the same within transaction, so resources share deletion time with the project
+	err = tx.Exec("update resources set deleted_at = now() where project_id = ? and deleted_at is null", project.ID)
+	if err != nil {
+		return false, err
+	}
+
+	err = activity.Record(tx, workspaceID, projectEvent(&project, actorID, activity.VerbDeleted))
+	return err == nil, err
+}
+
+func isNameConflict(err error) bool {
+	return strings.Contains(err.Error(), "unique_name_on_projects")
+}
+
+// linkRepository sets project repository, connection is locked so it can not be deleted while linking.
+// It returns nil when project does not exist.
+func linkRepository(ctx context.Context, project *core.Project, connectionID int64, repositoryName, branch, rootDir string, actorID int64) (linked *core.Project, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryConnection = `
+        select id
+        from workspace_repository_connections
+        where id = ? and workspace_id = ? and deleted_at is null
+        for share
+    `
+	var conn struct{ ID int64 }
+	err = tx.Query(&conn, queryConnection, connectionID, project.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.ID == 0 {
+		return nil, errConnectionNotFound
+	}
+
+	var query = `
+        update projects
+        set
+            repository_connection_id = ?,
+            repository_name = ?,
+            repository_branch = ?,
+            repository_root_dir = ?,
+            updated_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	var updated core.Project
+	err = tx.Query(&updated, query, connectionID, repositoryName, branch, rootDir, project.ID, project.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if updated.ID == 0 {
+		return nil, nil
+	}
+
+	event := projectEvent(&updated, actorID, activity.VerbRepositoryLinked)
+	event.Metadata["repository_connection_id"] = connectionID
+	event.Metadata["repository_name"] = repositoryName
+	event.Metadata["repository_branch"] = branch
+	event.Metadata["repository_root_dir"] = rootDir
+	err = activity.Record(tx, project.WorkspaceID, event)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// unlinkRepository returns false when project is not linked to any repository
+func unlinkRepository(ctx context.Context, project *core.Project, actorID int64) (unlinked bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update projects
+        set
+            repository_connection_id = null,
+            repository_name = null,
+            repository_branch = null,
+            repository_root_dir = null,
+            updated_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null and repository_connection_id is not null
+        returning *
+    `
+	var updated core.Project
+	err = tx.Query(&updated, query, project.ID, project.WorkspaceID)
+	if err != nil {
+		return false, err
+	}
+	if updated.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, projectEvent(&updated, actorID, activity.VerbRepositoryUnlinked))
+	return err == nil, err
+}
+
+// transferProject moves project together with its environments, resources, secrets and repository link to another workspace.
+// Actor must be owner of the destination workspace, ownership of the source workspace is checked by the caller.
+// Destination must have quota for the project and its active resources, and a connection to the linked repository.
+// Project member grants are revoked because they were given within the source workspace.
+func transferProject(ctx context.Context, project *core.Project, destinationID, actorID int64) (moved *core.Project, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if destinationID == project.WorkspaceID {
+		return nil, errSameWorkspace
+	}
+
+	var queryAccess = `
+        select workspace_users.access_level
+        from workspace_users
+        join workspaces on workspaces.id = workspace_users.workspace_id
+        where
+            workspace_users.workspace_id = ?
+            and workspace_users.user_id = ?
+            and workspace_users.deleted_at is null
+            and workspaces.deleted_at is null
+        for share of workspace_users
+    `
+	var access struct{ AccessLevel string }
+	err = tx.Query(&access, queryAccess, destinationID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if access.AccessLevel == "" {
+		return nil, errDestinationNotFound
+	}
+	if access.AccessLevel != core.WorkspaceAccessLevelOwner {
+		return nil, errNotDestinationOwner
+	}
+
+	var queryCurrent = `
+        select *
+        from projects
+        where id = ? and workspace_id = ? and deleted_at is null
+        for update
+    `
+	var current core.Project
+	err = tx.Query(&current, queryCurrent, project.ID, project.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if current.ID == 0 {
+		return nil, nil
+	}
+
+	var connectionID *int64
+	if current.RepositoryConnectionID != nil {
+		var queryConnection = `
+            select destination.id
+            from workspace_repository_connections as source
+            join workspace_repository_connections as destination
+                on destination.provider = source.provider and destination.identifier = source.identifier
+            where
+                source.id = ?
+                and destination.workspace_id = ?
+                and destination.deleted_at is null
+            for share of destination
+        `
+		var conn struct{ ID int64 }
+		err = tx.Query(&conn, queryConnection, *current.RepositoryConnectionID, destinationID)
+		if err != nil {
+			return nil, err
+		}
+		if conn.ID == 0 {
+			return nil, errConnectionMissing
+		}
+		connectionID = &conn.ID
+	}
+
+	var queryResources = `
+        select id, type, state, cpu_millicores, memory_mb
+        from resources
+        where project_id = ? and deleted_at is null
+        order by id
+        for update
+    `
+	var resources []struct {
+		ID            int64
+		Type          string
+		State         string
+		CPUMillicores int64 `pg:"cpu_millicores"`
+		MemoryMB      int64 `pg:"memory_mb"`
+	}
+	err = tx.Query(&resources, queryResources, current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := quota.Request{Projects: 1}
+	resourcesPerType := map[string]int64{}
+	for _, resource := range resources {
+		request.CPUMillicores += resource.CPUMillicores
+		request.MemoryMB += resource.MemoryMB
+		resourcesPerType[resource.Type]++
+	}
+	err = quota.Reserve(tx, destinationID, request)
+	if err != nil {
+		return nil, err
+	}
+	for resourceType, count := range resourcesPerType {
+		err = quota.Reserve(tx, destinationID, quota.Request{ResourceType: resourceType, Resources: count})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var query = `
+        update projects
+        set workspace_id = ?, repository_connection_id = ?, updated_at = now()
+        where id = ?
+        returning *
+    `
+	var updated core.Project
+	err = tx.Query(&updated, query, destinationID, connectionID, current.ID)
+	if err != nil {
+		if isNameConflict(err) {
+			return nil, errNameTaken
+		}
+		return nil, err
+	}
+
+	err = tx.Exec("update secrets set workspace_id = ? where project_id = ?", destinationID, current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// grants were given within source workspace, keeping them would give their users access to destination workspace
+	var queryRevoke = `
+        update project_users
+        set deleted_at = now()
+        where project_id = ? and deleted_at is null
+        returning user_id, access_level
+    `
+	var revoked []struct {
+		UserID      int64
+		AccessLevel string
+	}
+	err = tx.Query(&revoked, queryRevoke, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range revoked {
+		err = activity.Record(tx, current.WorkspaceID, &activity.Event{
+			ActorID:    actorID,
+			Verb:       activity.VerbRevoked,
+			TargetType: activity.TargetProjectMember,
+			TargetID:   grant.UserID,
+			Metadata: map[string]interface{}{
+				"project_id":      current.ID,
+				"access_level":    grant.AccessLevel,
+				"to_workspace_id": destinationID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, resource := range resources {
+		err = billing.RecordTransfer(tx, current.WorkspaceID, destinationID, resource.ID, resource.State)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	movedOut := projectEvent(&updated, actorID, activity.VerbMovedOut)
+	movedOut.Metadata["to_workspace_id"] = destinationID
+	err = activity.Record(tx, current.WorkspaceID, movedOut)
+	if err != nil {
+		return nil, err
+	}
+
+	movedIn := projectEvent(&updated, actorID, activity.VerbMovedIn)
+	movedIn.Metadata["from_workspace_id"] = current.WorkspaceID
+	err = activity.Record(tx, destinationID, movedIn)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func projectEvent(project *core.Project, actorID int64, verb string) *activity.Event {
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetProject,
+		TargetID:   project.ID,
+		Metadata: map[string]interface{}{
+			"name": project.Name,
+		},
+	}
+}
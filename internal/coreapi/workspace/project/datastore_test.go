@@ -0,0 +1,273 @@
+package project
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectLifecycle(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	project := &core.Project{WorkspaceID: workspace.ID, Name: "web"}
+	err := createProject(ctx, project, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, project.ID > 0)
+
+	var environment core.ProjectEnvironment
+	err = appctx.Database(ctx).Query(&environment, "select * from project_environments where project_id = ?", project.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, core.DefaultProjectEnvironmentName, environment.Name)
+	assert.Equal(t, core.ProjectEnvironmentTypeProduction, environment.Type)
+	assert.True(t, environment.Protected)
+
+	// names are unique per workspace regardless of case
+	err = createProject(ctx, &core.Project{WorkspaceID: workspace.ID, Name: "Web"}, user.ID)
+	assert.Equal(t, errNameTaken, err)
+
+	other := testutil.WorkspaceFactory(ctx, 1)[0]
+	err = createProject(ctx, &core.Project{WorkspaceID: other.ID, Name: "web"}, user.ID)
+	assert.NoError(t, err)
+
+	api := &core.Project{WorkspaceID: workspace.ID, Name: "api"}
+	assert.NoError(t, createProject(ctx, api, user.ID))
+
+	_, err = renameProject(ctx, workspace.ID, api.ID, "WEB", user.ID)
+	assert.Equal(t, errNameTaken, err)
+
+	renamed, err := renameProject(ctx, workspace.ID, project.ID, "frontend", user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, renamed) {
+		assert.Equal(t, "frontend", renamed.Name)
+	}
+
+	// project of another workspace is not reachable
+	found, err := getProject(ctx, other.ID, project.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	err = appctx.Database(ctx).WriterExec("insert into resources (name, type, payload, project_id, environment_id) values ('db', 'postgres', '{}', ?, ?)", project.ID, environment.ID)
+	assert.NoError(t, err)
+
+	deleted, err := deleteProject(ctx, workspace.ID, project.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	var live struct{ Count int }
+	err = appctx.Database(ctx).Query(&live, "select count(*) as count from resources where project_id = ? and deleted_at is null", project.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, live.Count)
+
+	// name of deleted project can be used again
+	err = createProject(ctx, &core.Project{WorkspaceID: workspace.ID, Name: "frontend"}, user.ID)
+	assert.NoError(t, err)
+}
+
+func TestListProjectsPagination(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	for i := 0; i < 3; i++ {
+		testutil.ProjectFactory(ctx, workspace.ID)
+	}
+
+	p := parseListProjectsParam(url.Values{"limit": {"2"}})
+	assert.NoError(t, p.Validate())
+	projects, cursor, err := listProjects(ctx, workspace.ID, 0, p)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 2)
+	assert.NotEmpty(t, cursor)
+
+	p = parseListProjectsParam(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	assert.NoError(t, p.Validate())
+	projects, cursor, err = listProjects(ctx, workspace.ID, 0, p)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 1)
+	assert.Empty(t, cursor)
+}
+
+func TestLinkRepository(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspaces := testutil.WorkspaceFactory(ctx, 2)
+	workspace, other := workspaces[0], workspaces[1]
+	user := testutil.UserFactory(ctx, 1)[0]
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	conn := testutil.RepositoryConnectionFactory(ctx, workspace.ID)
+	otherConn := testutil.RepositoryConnectionFactory(ctx, other.ID)
+
+	_, err := linkRepository(ctx, project, otherConn.ID, "awanku/awanku", "master", "/", user.ID)
+	assert.Equal(t, errConnectionNotFound, err)
+
+	linked, err := linkRepository(ctx, project, conn.ID, "awanku/awanku", "master", "/web", user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, linked) && assert.NotNil(t, linked.RepositoryConnectionID) {
+		assert.Equal(t, conn.ID, *linked.RepositoryConnectionID)
+		assert.Equal(t, "awanku/awanku", *linked.RepositoryName)
+		assert.Equal(t, "master", *linked.RepositoryBranch)
+		assert.Equal(t, "/web", *linked.RepositoryRootDir)
+	}
+
+	unlinked, err := unlinkRepository(ctx, project, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, unlinked)
+	unlinked, err = unlinkRepository(ctx, project, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, unlinked)
+
+	fetched, err := getProject(ctx, workspace.ID, project.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched.RepositoryConnectionID)
+	assert.Nil(t, fetched.RepositoryName)
+}
+
+func TestTransferProject(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspaces := testutil.WorkspaceFactory(ctx, 2)
+	source, destination := workspaces[0], workspaces[1]
+	users := testutil.UserFactory(ctx, 2)
+	user, contractor := users[0], users[1]
+	testutil.WorkspaceUserFactory(ctx, source.ID, user.ID, core.WorkspaceAccessLevelOwner)
+
+	project := testutil.ProjectFactory(ctx, source.ID)
+	testutil.ProjectUserFactory(ctx, project.ID, contractor.ID, core.ProjectAccessLevelEditor)
+	conn := testutil.RepositoryConnectionFactory(ctx, source.ID)
+	project, err := linkRepository(ctx, project, conn.ID, "awanku/awanku", "master", "/", user.ID)
+	assert.NoError(t, err)
+
+	var resource struct{ ID int64 }
+	err = db.WriterQuery(&resource, "insert into resources (name, type, payload, project_id, environment_id, state) select 'db', 'postgres', '{}', project_id, id, 'provisioning_success' from project_environments where project_id = ? returning id", project.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into secrets (workspace_id, project_id, name, ciphertext, nonce, wrapped_key, master_key_id)
+        values (?, ?, 'DATABASE_URL', 'x', 'x', 'x', 'test')
+    `, source.ID, project.ID)
+	assert.NoError(t, err)
+
+	_, err = transferProject(ctx, project, source.ID, user.ID)
+	assert.Equal(t, errSameWorkspace, err)
+	_, err = transferProject(ctx, project, destination.ID, user.ID)
+	assert.Equal(t, errDestinationNotFound, err)
+
+	err = db.WriterExec("insert into workspace_users (workspace_id, user_id, access_level) values (?, ?, 'editor')", destination.ID, user.ID)
+	assert.NoError(t, err)
+	_, err = transferProject(ctx, project, destination.ID, user.ID)
+	assert.Equal(t, errNotDestinationOwner, err)
+
+	err = db.WriterExec("update workspace_users set access_level = 'owner' where workspace_id = ? and user_id = ?", destination.ID, user.ID)
+	assert.NoError(t, err)
+	_, err = transferProject(ctx, project, destination.ID, user.ID)
+	assert.Equal(t, errConnectionMissing, err)
+
+	var destinationConn struct{ ID int64 }
+	err = db.WriterQuery(&destinationConn, `
+        insert into workspace_repository_connections (workspace_id, identifier, provider, payload)
+        values (?, ?, ?, '{}')
+        returning id
+    `, destination.ID, conn.Identifier, conn.Provider)
+	assert.NoError(t, err)
+
+	taken := &core.Project{WorkspaceID: destination.ID, Name: project.Name}
+	assert.NoError(t, createProject(ctx, taken, user.ID))
+	_, err = transferProject(ctx, project, destination.ID, user.ID)
+	assert.Equal(t, errNameTaken, err)
+	_, err = renameProject(ctx, destination.ID, taken.ID, "taken", user.ID)
+	assert.NoError(t, err)
+
+	moved, err := transferProject(ctx, project, destination.ID, user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, moved) {
+		assert.Equal(t, destination.ID, moved.WorkspaceID)
+		assert.Equal(t, destinationConn.ID, *moved.RepositoryConnectionID)
+		assert.Equal(t, "awanku/awanku", *moved.RepositoryName)
+	}
+
+	var secrets struct{ Count int }
+	err = db.Query(&secrets, "select count(*) as count from secrets where project_id = ? and workspace_id = ?", project.ID, destination.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, secrets.Count)
+
+	// metering stops in source workspace and starts in destination
+	var events []*core.ResourceMeteringEvent
+	err = db.Query(&events, "select * from resource_metering_events where resource_id = ? order by workspace_id", resource.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, source.ID, events[0].WorkspaceID)
+		assert.Equal(t, core.ResourceStateUnknown, events[0].State)
+		assert.Equal(t, destination.ID, events[1].WorkspaceID)
+		assert.Equal(t, core.ResourceStateProvisioningSuccess, events[1].State)
+	}
+
+	for _, workspaceID := range []int64{source.ID, destination.ID} {
+		var logs struct{ Count int }
+		err = db.Query(&logs, "select count(*) as count from workspace_activity_logs where workspace_id = ? and target_id = ? and verb in ('moved_in', 'moved_out')", workspaceID, project.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, logs.Count)
+	}
+
+	var grants struct {
+		Active  int
+		Revoked int
+	}
+	err = db.Query(&grants, `
+        select
+            (select count(*) from project_users where project_id = ?0 and deleted_at is null) as active,
+            (select count(*) from workspace_activity_logs where workspace_id = ?1 and target_id = ?2 and verb = 'revoked') as revoked
+    `, project.ID, source.ID, contractor.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, grants.Active)
+	assert.Equal(t, 1, grants.Revoked)
+
+	found, err := getProject(ctx, source.ID, project.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestParamValidate(t *testing.T) {
+	assert.NoError(t, saveProjectParam{Name: "my-project_1.0"}.Validate())
+	assert.NoError(t, saveProjectParam{Name: "Proyek Awan"}.Validate())
+	assert.Error(t, saveProjectParam{Name: ""}.Validate())
+	assert.Error(t, saveProjectParam{Name: " leading space"}.Validate())
+	assert.Error(t, saveProjectParam{Name: "slash/name"}.Validate())
+
+	for _, query := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"limit": {"ten"}},
+		{"cursor": {"!!"}},
+	} {
+		assert.Error(t, parseListProjectsParam(query).Validate(), "query %v", query)
+	}
+
+	link := linkRepositoryParam{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: "feature/login"}
+	assert.NoError(t, link.Validate())
+	assert.Equal(t, "/", link.CleanRootDir())
+	link.RootDir = "services/api/"
+	assert.NoError(t, link.Validate())
+	assert.Equal(t, "/services/api", link.CleanRootDir())
+
+	for _, invalid := range []linkRepositoryParam{
+		{Repository: "awanku/awanku", Branch: "master"},
+		{RepositoryConnectionID: 1, Repository: "awanku", Branch: "master"},
+		{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: ""},
+		{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: "feature..login"},
+		{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: "has space"},
+		{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: "master.lock"},
+		{RepositoryConnectionID: 1, Repository: "awanku/awanku", Branch: "master", RootDir: "../secrets"},
+	} {
+		assert.Error(t, invalid.Validate(), "param %+v", invalid)
+	}
+}
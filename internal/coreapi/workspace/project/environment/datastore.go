@@ -0,0 +1,226 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errNameTaken    = errors.New("environment with same name already exists")
+	errBranchTaken  = errors.New("branch is already mapped to another environment")
+	errProtected    = errors.New("environment is protected")
+	errHasResources = errors.New("environment still has resources")
+)
+
+func listEnvironments(ctx context.Context, projectID int64) ([]*core.ProjectEnvironment, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from project_environments
+        where project_id = ? and deleted_at is null
+        order by id
+    `
+	var environments []*core.ProjectEnvironment
+	err := db.Query(&environments, query, projectID)
+	if err != nil {
+		return []*core.ProjectEnvironment{}, err
+	}
+	if environments == nil {
+		environments = []*core.ProjectEnvironment{}
+	}
+	return environments, nil
+}
+
+func getEnvironment(ctx context.Context, projectID, id int64) (*core.ProjectEnvironment, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from project_environments
+        where id = ? and project_id = ? and deleted_at is null
+    `
+	var environment core.ProjectEnvironment
+	err := db.Query(&environment, query, id, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if environment.ID == 0 {
+		return nil, nil
+	}
+	return &environment, nil
+}
+
+func createEnvironment(ctx context.Context, project *core.Project, environment *core.ProjectEnvironment, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        insert into project_environments (project_id, name, type, protected, branch, created_at)
+        values (?, ?, ?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(environment, query, project.ID, environment.Name, environment.Type, environment.Protected, environment.Branch)
+	if err != nil {
+		return conflictErr(err)
+	}
+
+	return activity.Record(tx, project.WorkspaceID, environmentEvent(environment, actorID, activity.VerbCreated))
+}
+
+// updateEnvironment returns nil when environment does not exist
+func updateEnvironment(ctx context.Context, project *core.Project, environment *core.ProjectEnvironment, actorID int64) (updated *core.ProjectEnvironment, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update project_environments
+        set name = ?, type = ?, protected = ?, branch = ?, updated_at = now()
+        where id = ? and project_id = ? and deleted_at is null
+        returning *
+    `
+	var saved core.ProjectEnvironment
+	err = tx.Query(&saved, query, environment.Name, environment.Type, environment.Protected, environment.Branch, environment.ID, project.ID)
+	if err != nil {
+		return nil, conflictErr(err)
+	}
+	if saved.ID == 0 {
+		return nil, nil
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, environmentEvent(&saved, actorID, activity.VerbUpdated))
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// deleteEnvironment soft deletes environment without active resources, protected environment must be unprotected first.
+// It returns false when environment does not exist.
+func deleteEnvironment(ctx context.Context, project *core.Project, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryCurrent = `
+        select *
+        from project_environments
+        where id = ? and project_id = ? and deleted_at is null
+        for update
+    `
+	var environment core.ProjectEnvironment
+	err = tx.Query(&environment, queryCurrent, id, project.ID)
+	if err != nil {
+		return false, err
+	}
+	if environment.ID == 0 {
+		return false, nil
+	}
+	if environment.Protected {
+		return false, errProtected
+	}
+
+	var queryResources = `
+        select count(*) as count
+        from resources
+        where environment_id = ? and deleted_at is null
+    `
+	var resources struct{ Count int }
+	err = tx.Query(&resources, queryResources, environment.ID)
+	if err != nil {
+		return false, err
+	}
+	if resources.Count > 0 {
+		return false, errHasResources
+	}
+
+	err = tx.Exec("update project_environments set deleted_at = now() where id = ?", environment.ID)
+	if err != nil {
+		return false, err
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, environmentEvent(&environment, actorID, activity.VerbDeleted))
+	return err == nil, err
+}
+
+// PurgeDeleted hard deletes environments which were deleted longer than trash restore window ago,
+// once resources left in trash are purged too
+func PurgeDeleted(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with purged as (
+            delete from project_environments
+            where id in (
+                select id
+                from project_environments
+                where
+                    deleted_at < ?
+                    and not exists (select 1 from resources where resources.environment_id = project_environments.id)
+                limit ?
+            )
+            returning 1
+        )
+        select count(*) as count from purged
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, time.Now().Add(-core.TrashRetentionDuration), batchSize)
+	return returned.Count, err
+}
+
+func conflictErr(err error) error {
+	switch {
+	case strings.Contains(err.Error(), "unique_name_on_project_environments"):
+		return errNameTaken
+	case strings.Contains(err.Error(), "unique_branch_on_project_environments"):
+		return errBranchTaken
+	}
+	return err
+}
+
+func environmentEvent(environment *core.ProjectEnvironment, actorID int64, verb string) *activity.Event {
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetProjectEnvironment,
+		TargetID:   environment.ID,
+		Metadata: map[string]interface{}{
+			"project_id": environment.ProjectID,
+			"name":       environment.Name,
+			"type":       environment.Type,
+			"protected":  environment.Protected,
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package environment
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentLifecycle(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+
+	main, preview := "main", "feature/*"
+	staging := &core.ProjectEnvironment{ProjectID: project.ID, Name: "staging", Type: core.ProjectEnvironmentTypeStaging, Branch: &main}
+	err := createEnvironment(ctx, project, staging, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, staging.ID > 0)
+
+	err = createEnvironment(ctx, project, &core.ProjectEnvironment{ProjectID: project.ID, Name: "Staging", Type: core.ProjectEnvironmentTypeStaging}, user.ID)
+	assert.Equal(t, errNameTaken, err)
+	err = createEnvironment(ctx, project, &core.ProjectEnvironment{ProjectID: project.ID, Name: "qa", Type: core.ProjectEnvironmentTypeStaging, Branch: &main}, user.ID)
+	assert.Equal(t, errBranchTaken, err)
+
+	previews := &core.ProjectEnvironment{ProjectID: project.ID, Name: "previews", Type: core.ProjectEnvironmentTypePreview, Branch: &preview}
+	assert.NoError(t, createEnvironment(ctx, project, previews, user.ID))
+
+	environments, err := listEnvironments(ctx, project.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, environments, 3) {
+		assert.Equal(t, core.DefaultProjectEnvironmentName, environments[0].Name)
+		assert.True(t, environments[0].Protected)
+	}
+
+	changed := *staging
+	changed.Name = "uat"
+	changed.Branch = nil
+	updated, err := updateEnvironment(ctx, project, &changed, user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, "uat", updated.Name)
+		assert.Nil(t, updated.Branch)
+	}
+
+	_, err = deleteEnvironment(ctx, project, environments[0].ID, user.ID)
+	assert.Equal(t, errProtected, err)
+
+	err = appctx.Database(ctx).WriterExec("insert into resources (name, type, payload, project_id, environment_id) values ('db', 'postgres', '{}', ?, ?)", project.ID, staging.ID)
+	assert.NoError(t, err)
+	_, err = deleteEnvironment(ctx, project, staging.ID, user.ID)
+	assert.Equal(t, errHasResources, err)
+
+	deleted, err := deleteEnvironment(ctx, project, previews.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	found, err := getEnvironment(ctx, project.ID, previews.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	// environment of another project can not be used by resources
+	other := testutil.ProjectFactory(ctx, workspace.ID)
+	err = appctx.Database(ctx).WriterExec("insert into resources (name, type, payload, project_id, environment_id) values ('db', 'postgres', '{}', ?, ?)", other.ID, staging.ID)
+	assert.Error(t, err)
+}
+
+func TestParamValidate(t *testing.T) {
+	main, wildcard, invalid := "main", "feature/*", "feature..x"
+
+	assert.NoError(t, saveEnvironmentParam{Name: "production", Type: core.ProjectEnvironmentTypeProduction}.Validate())
+	assert.NoError(t, saveEnvironmentParam{Name: "staging-2", Type: core.ProjectEnvironmentTypeStaging, Branch: &main}.Validate())
+	assert.NoError(t, saveEnvironmentParam{Name: "preview", Type: core.ProjectEnvironmentTypePreview, Branch: &wildcard}.Validate())
+
+	for _, param := range []saveEnvironmentParam{
+		{Name: "", Type: core.ProjectEnvironmentTypeStaging},
+		{Name: "Staging", Type: core.ProjectEnvironmentTypeStaging},
+		{Name: "staging", Type: "development"},
+		{Name: "staging", Type: core.ProjectEnvironmentTypeStaging, Branch: &wildcard},
+		{Name: "staging", Type: core.ProjectEnvironmentTypeStaging, Branch: &invalid},
+	} {
+		assert.Error(t, param.Validate(), "param %+v", param)
+	}
+}
@@ -0,0 +1,247 @@
+package environment
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// @Id api.v1.workspace.project.environment.listAll
+// @Summary List project environments
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments [get]
+// @Produce json
+// @Success 200 {array} core.ProjectEnvironment
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	environments, err := listEnvironments(r.Context(), currentProject.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, environments)
+}
+
+// @Id api.v1.workspace.project.environment.create
+// @Summary Create project environment, only workspace owners can create protected environment
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param param body saveEnvironmentParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments [post]
+// @Produce json
+// @Success 201 {object} core.ProjectEnvironment
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	var param saveEnvironmentParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	param.Branch = nilIfEmpty(param.Branch)
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+	if param.Protected && !isOwner(r) {
+		protectedForbiddenResp(w)
+		return
+	}
+
+	environment := core.ProjectEnvironment{
+		ProjectID: currentProject.ID,
+		Name:      param.Name,
+		Type:      param.Type,
+		Protected: param.Protected,
+		Branch:    param.Branch,
+	}
+	err := createEnvironment(r.Context(), currentProject, &environment, appctx.Actor(r.Context()).ID)
+	if err == errNameTaken || err == errBranchTaken {
+		conflictResp(w, err)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusCreated, environment)
+}
+
+// @Id api.v1.workspace.project.environment.get
+// @Summary Get project environment
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id} [get]
+// @Produce json
+// @Success 200 {object} core.ProjectEnvironment
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	apihelper.JSON(w, http.StatusOK, appctx.CurrentProjectEnvironment(r.Context()))
+}
+
+// @Id api.v1.workspace.project.environment.update
+// @Summary Update project environment, omitted fields are kept. Only workspace owners can change protected environment.
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Param param body saveEnvironmentParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id} [patch]
+// @Produce json
+// @Success 200 {object} core.ProjectEnvironment
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	current := appctx.CurrentProjectEnvironment(r.Context())
+
+	param := saveEnvironmentParam{
+		Name:      current.Name,
+		Type:      current.Type,
+		Protected: current.Protected,
+		Branch:    current.Branch,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	param.Branch = nilIfEmpty(param.Branch)
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+	if (current.Protected || param.Protected) && !isOwner(r) {
+		protectedForbiddenResp(w)
+		return
+	}
+
+	environment := *current
+	environment.Name = param.Name
+	environment.Type = param.Type
+	environment.Protected = param.Protected
+	environment.Branch = param.Branch
+	updated, err := updateEnvironment(r.Context(), currentProject, &environment, appctx.Actor(r.Context()).ID)
+	if err == errNameTaken || err == errBranchTaken {
+		conflictResp(w, err)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if updated == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, updated)
+}
+
+// @Id api.v1.workspace.project.environment.delete
+// @Summary Delete project environment, it must not be protected nor have resources
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	current := appctx.CurrentProjectEnvironment(r.Context())
+
+	deleted, err := deleteEnvironment(r.Context(), currentProject, current.ID, appctx.Actor(r.Context()).ID)
+	if err == errProtected {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"protected": "protected environment can not be deleted, unprotect it first",
+		})
+		return
+	}
+	if err == errHasResources {
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"resources": "environment still has resources, delete them first",
+		})
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func isOwner(r *http.Request) bool {
+	return core.WorkspaceAccessLevelAtLeast(appctx.ProjectAccessLevel(r.Context()), core.WorkspaceAccessLevelOwner)
+}
+
+func nilIfEmpty(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
+
+func protectedForbiddenResp(w http.ResponseWriter) {
+	apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+		"protected": "only workspace owners can change protected environment",
+	})
+}
+
+func conflictResp(w http.ResponseWriter, err error) {
+	field := "name"
+	if err == errBranchTaken {
+		field = "branch"
+	}
+	apihelper.ConflictErrResp(w, "conflict", map[string]string{
+		field: err.Error(),
+	})
+}
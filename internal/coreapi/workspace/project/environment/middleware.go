@@ -0,0 +1,40 @@
+package environment
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/go-chi/chi"
+)
+
+// CurrentEnvironmentMiddleware loads environment of current project from url,
+// it must be used after CurrentProjectMiddleware
+func CurrentEnvironmentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentProject := appctx.CurrentProject(r.Context())
+
+		environmentID, err := strconv.ParseInt(chi.URLParam(r, "environment_id"), 10, 64)
+		if err != nil || environmentID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"environment_id": "invalid",
+			})
+			return
+		}
+
+		environment, err := getEnvironment(r.Context(), currentProject.ID, environmentID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if environment == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), appctx.KeyCurrentProjectEnv, environment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,72 @@
+package environment
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// namePattern allows lowercase letters, digits and dashes so environment name can be used in hostnames
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// branchPattern rejects characters git does not allow in branch names, except * used as wildcard
+var branchPattern = regexp.MustCompile(`^[^\s~^:?\[\\]+$`)
+
+var environmentTypes = func() []interface{} {
+	types := make([]interface{}, len(core.ProjectEnvironmentTypes))
+	for i, t := range core.ProjectEnvironmentTypes {
+		types[i] = t
+	}
+	return types
+}()
+
+// saveEnvironmentParam is used for both create and update,
+// on update it is prefilled from current environment so omitted fields are kept
+type saveEnvironmentParam struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Protected bool    `json:"protected"`
+	Branch    *string `json:"branch"`
+}
+
+func (p saveEnvironmentParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name,
+			validation.Required,
+			validation.Length(1, 63),
+			validation.Match(namePattern).Error("must start with lowercase letter or digit and only contain lowercase letters, digits and dashes"),
+		),
+		validation.Field(&p.Type, validation.Required, validation.In(environmentTypes...)),
+		validation.Field(&p.Branch,
+			validation.NilOrNotEmpty,
+			validation.Length(1, 255),
+			validation.Match(branchPattern).Error("invalid branch name"),
+			validation.By(validateBranch),
+			validation.When(p.Type != core.ProjectEnvironmentTypePreview, validation.By(rejectWildcard)),
+		),
+	)
+}
+
+func validateBranch(value interface{}) error {
+	branch, _ := value.(*string)
+	if branch == nil {
+		return nil
+	}
+	if strings.Contains(*branch, "..") || strings.Contains(*branch, "//") || strings.Contains(*branch, "@{") ||
+		strings.HasPrefix(*branch, "-") || strings.HasPrefix(*branch, "/") ||
+		strings.HasSuffix(*branch, "/") || strings.HasSuffix(*branch, ".") || strings.HasSuffix(*branch, ".lock") {
+		return errors.New("invalid branch name")
+	}
+	return nil
+}
+
+func rejectWildcard(value interface{}) error {
+	branch, _ := value.(*string)
+	if branch != nil && strings.Contains(*branch, "*") {
+		return errors.New("wildcard is only allowed for preview environments")
+	}
+	return nil
+}
@@ -1,7 +1,391 @@
 package project
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
 
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/internal/coreapi/workspace/repository"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+type projectPage struct {
+	Projects []*core.Project `json:"projects"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// @Id api.v1.workspace.project.listAll
+// @Summary List workspace projects in creation order, users without workspace membership only see projects granted to them
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param cursor query string false "Cursor from next_cursor of previous page"
+// @Param limit query integer false "Page size, 50 by default, at most 100"
+// @Router /v1/workspaces/{workspace_id}/projects [get]
+// @Produce json
+// @Success 200 {object} projectPage
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	param := parseListProjectsParam(r.URL.Query())
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	// guests only see projects granted to them
+	var grantedToUserID int64
+	if appctx.WorkspaceAccessLevel(r.Context()) == core.WorkspaceAccessLevelGuest {
+		grantedToUserID = appctx.AuthenticatedUser(r.Context()).ID
+	}
+
+	projects, nextCursor, err := listProjects(r.Context(), currentWorkspace.ID, grantedToUserID, param)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, projectPage{
+		Projects:   projects,
+		NextCursor: nextCursor,
+	})
+}
+
+// @Id api.v1.workspace.project.create
+// @Summary Create project, its name must be unique in the workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body saveProjectParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects [post]
+// @Produce json
+// @Success 201 {object} core.Project
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	var param saveProjectParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	project := &core.Project{
+		WorkspaceID: currentWorkspace.ID,
+		Name:        param.Name,
+	}
+	err := createProject(r.Context(), project, appctx.Actor(r.Context()).ID)
+	if err == errNameTaken {
+		nameTakenResp(w)
+		return
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusCreated, project)
+}
+
+// @Id api.v1.workspace.project.get
+// @Summary Get project
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id} [get]
+// @Produce json
+// @Success 200 {object} core.Project
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	apihelper.JSON(w, http.StatusOK, appctx.CurrentProject(r.Context()))
+}
+
+// @Id api.v1.workspace.project.update
+// @Summary Rename project
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param param body saveProjectParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id} [patch]
+// @Produce json
+// @Success 200 {object} core.Project
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	var param saveProjectParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	project, err := renameProject(r.Context(), currentProject.WorkspaceID, currentProject.ID, param.Name, appctx.Actor(r.Context()).ID)
+	if err == errNameTaken {
+		nameTakenResp(w)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if project == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, project)
+}
+
+// @Id api.v1.workspace.project.delete
+// @Summary Delete project together with its resources, they stay restorable from trash for 30 days
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	deleted, err := deleteProject(r.Context(), currentProject.WorkspaceID, currentProject.ID, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspace.project.repository.link
+// @Summary Link project to a repository and branch accessible through one of workspace repository connections
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param param body linkRepositoryParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/repository [put]
+// @Produce json
+// @Success 200 {object} core.Project
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleLinkRepository(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	var param linkRepositoryParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	conn, err := repository.GetConnection(r.Context(), currentProject.WorkspaceID, param.RepositoryConnectionID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if conn == nil {
+		connectionNotFoundResp(w)
+		return
+	}
+
+	repo, err := repository.FindRepository(r.Context(), conn, param.Repository)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if repo == nil {
+		apihelper.ValidationErrResp(w, map[string]string{
+			"repository": "not accessible through the repository connection",
+		})
+		return
+	}
+
+	exists, err := repository.BranchExists(r.Context(), conn, repo.Name, param.Branch)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !exists {
+		apihelper.ValidationErrResp(w, map[string]string{
+			"branch": "does not exist in the repository",
+		})
+		return
+	}
+
+	project, err := linkRepository(r.Context(), currentProject, conn.ID, repo.Name, param.Branch, param.CleanRootDir(), appctx.Actor(r.Context()).ID)
+	if err == errConnectionNotFound {
+		connectionNotFoundResp(w)
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if project == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, project)
+}
+
+// @Id api.v1.workspace.project.repository.unlink
+// @Summary Unlink project from its repository
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/repository [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUnlinkRepository(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	unlinked, err := unlinkRepository(r.Context(), currentProject, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !unlinked {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspace.project.transfer
+// @Summary Move project with its environments, resources, secrets and repository link to another workspace, project member grants are revoked and owner access is required on both workspaces
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param param body transferProjectParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/transfer [post]
+// @Produce json
+// @Success 200 {object} core.Project
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleTransfer(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	var param transferProjectParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	project, err := transferProject(r.Context(), currentProject, param.WorkspaceID, appctx.Actor(r.Context()).ID)
+	var exceeded *quota.ExceededError
+	switch {
+	case err == errSameWorkspace || err == errDestinationNotFound:
+		apihelper.ValidationErrResp(w, map[string]string{
+			"workspace_id": err.Error(),
+		})
+	case err == errNotDestinationOwner:
+		apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+			"workspace_id": err.Error(),
+		})
+	case errors.As(err, &exceeded):
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+	case err == errNameTaken:
+		nameTakenResp(w)
+	case err == errConnectionMissing:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"repository_connection_id": "connect the project repository to destination workspace first",
+		})
+	case err != nil:
+		apihelper.InternalServerErrResp(w, err)
+	case project == nil:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		apihelper.JSON(w, http.StatusOK, project)
+	}
+}
+
+func connectionNotFoundResp(w http.ResponseWriter) {
+	apihelper.ValidationErrResp(w, map[string]string{
+		"repository_connection_id": "repository connection does not exist",
+	})
+}
 
+func nameTakenResp(w http.ResponseWriter) {
+	apihelper.ConflictErrResp(w, "conflict", map[string]string{
+		"name": "project with same name already exists",
+	})
 }
@@ -0,0 +1,212 @@
+package member
+
+import (
+	"context"
+	"errors"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errUserNotFound   = errors.New("no user is registered with the email")
+	errAlreadyGranted = errors.New("user already has access to the project")
+)
+
+func getMembers(ctx context.Context, projectID int64) ([]*core.ProjectMember, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            users.id as user_id,
+            users.name,
+            users.email,
+            project_users.access_level,
+            project_users.created_at as granted_at
+        from project_users
+        join users on users.id = project_users.user_id
+        where
+            project_users.project_id = ?
+            and project_users.deleted_at is null
+            and users.deleted_at is null
+        order by project_users.created_at asc, users.id asc
+    `
+	var members []*core.ProjectMember
+	err := db.Query(&members, query, projectID)
+	if err != nil {
+		return []*core.ProjectMember{}, err
+	}
+	if members == nil {
+		members = []*core.ProjectMember{}
+	}
+	return members, nil
+}
+
+func getMember(ctx context.Context, projectID, userID int64) (*core.ProjectMember, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select
+            users.id as user_id,
+            users.name,
+            users.email,
+            project_users.access_level,
+            project_users.created_at as granted_at
+        from project_users
+        join users on users.id = project_users.user_id
+        where
+            project_users.project_id = ?
+            and project_users.user_id = ?
+            and project_users.deleted_at is null
+    `
+	var member core.ProjectMember
+	err := db.Query(&member, query, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.UserID == 0 {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+// grantAccess gives registered user access to the project, the user does not need to be a workspace member.
+// User without workspace role takes a member seat, the seat is checked before telling whether the email is registered
+// so full workspace fails the same way for unknown email.
+func grantAccess(ctx context.Context, project *core.Project, email, accessLevel string, actorID int64) (userID int64, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryUser = `
+        select id
+        from users
+        where lower(email) = lower(?) and deleted_at is null
+    `
+	var user struct{ ID int64 }
+	err = tx.Query(&user, queryUser, email)
+	if err != nil {
+		return 0, err
+	}
+
+	err = quota.ReserveMember(tx, project.WorkspaceID, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	if user.ID == 0 {
+		return 0, errUserNotFound
+	}
+
+	var query = `
+        insert into project_users (project_id, user_id, access_level, created_at)
+        values (?, ?, ?, now())
+        on conflict (project_id, user_id) where deleted_at is null do nothing
+        returning id
+    `
+	var inserted struct{ ID int64 }
+	err = tx.Query(&inserted, query, project.ID, user.ID, accessLevel)
+	if err != nil {
+		return 0, err
+	}
+	if inserted.ID == 0 {
+		return 0, errAlreadyGranted
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, memberEvent(project, user.ID, actorID, activity.VerbCreated, accessLevel))
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// changeAccessLevel returns false when user has no access granted on the project
+func changeAccessLevel(ctx context.Context, project *core.Project, userID int64, accessLevel string, actorID int64) (changed bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update project_users
+        set access_level = ?, updated_at = now()
+        where project_id = ? and user_id = ? and deleted_at is null
+        returning id
+    `
+	var updated struct{ ID int64 }
+	err = tx.Query(&updated, query, accessLevel, project.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	if updated.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, memberEvent(project, userID, actorID, activity.VerbAccessLevelChanged, accessLevel))
+	return err == nil, err
+}
+
+// revokeAccess returns false when user has no access granted on the project
+func revokeAccess(ctx context.Context, project *core.Project, userID, actorID int64) (revoked bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update project_users
+        set deleted_at = now()
+        where project_id = ? and user_id = ? and deleted_at is null
+        returning id, access_level
+    `
+	var revokedAccess struct {
+		ID          int64
+		AccessLevel string
+	}
+	err = tx.Query(&revokedAccess, query, project.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	if revokedAccess.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, memberEvent(project, userID, actorID, activity.VerbRevoked, revokedAccess.AccessLevel))
+	return err == nil, err
+}
+
+func memberEvent(project *core.Project, userID, actorID int64, verb, accessLevel string) *activity.Event {
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetProjectMember,
+		TargetID:   userID,
+		Metadata: map[string]interface{}{
+			"project_id":   project.ID,
+			"access_level": accessLevel,
+		},
+	}
+}
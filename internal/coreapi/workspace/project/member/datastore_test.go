@@ -0,0 +1,107 @@
+package member
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrantAccess(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	owner, contractor := users[0], users[1]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+
+	_, err := grantAccess(ctx, project, "nobody@example.com", core.ProjectAccessLevelViewer, owner.ID)
+	assert.Equal(t, errUserNotFound, err)
+
+	userID, err := grantAccess(ctx, project, contractor.Email, core.ProjectAccessLevelViewer, owner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, contractor.ID, userID)
+
+	_, err = grantAccess(ctx, project, contractor.Email, core.ProjectAccessLevelEditor, owner.ID)
+	assert.Equal(t, errAlreadyGranted, err)
+
+	members, err := getMembers(ctx, project.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, contractor.ID, members[0].UserID)
+		assert.Equal(t, core.ProjectAccessLevelViewer, members[0].AccessLevel)
+	}
+}
+
+func TestChangeAndRevokeAccess(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	owner, contractor := users[0], users[1]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+
+	changed, err := changeAccessLevel(ctx, project, contractor.ID, core.ProjectAccessLevelEditor, owner.ID)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	testutil.ProjectUserFactory(ctx, project.ID, contractor.ID, core.ProjectAccessLevelViewer)
+
+	changed, err = changeAccessLevel(ctx, project, contractor.ID, core.ProjectAccessLevelEditor, owner.ID)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	member, err := getMember(ctx, project.ID, contractor.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, core.ProjectAccessLevelEditor, member.AccessLevel)
+
+	revoked, err := revokeAccess(ctx, project, contractor.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	member, err = getMember(ctx, project.ID, contractor.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+
+	revoked, err = revokeAccess(ctx, project, contractor.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	_, err = grantAccess(ctx, project, contractor.Email, core.ProjectAccessLevelViewer, owner.ID)
+	assert.NoError(t, err)
+}
+
+func TestGrantAccessReservesMemberSeat(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	// free plan allows 3 members
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 4)
+	owner, editor, contractor, other := users[0], users[1], users[2], users[3]
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, owner.ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, editor.ID, core.WorkspaceAccessLevelEditor)
+	projects := []*core.Project{testutil.ProjectFactory(ctx, workspace.ID), testutil.ProjectFactory(ctx, workspace.ID)}
+
+	// workspace member does not take another seat
+	_, err := grantAccess(ctx, projects[0], editor.Email, core.ProjectAccessLevelViewer, owner.ID)
+	assert.NoError(t, err)
+
+	_, err = grantAccess(ctx, projects[0], contractor.Email, core.ProjectAccessLevelViewer, owner.ID)
+	assert.NoError(t, err)
+	// guest takes one seat across all projects
+	_, err = grantAccess(ctx, projects[1], contractor.Email, core.ProjectAccessLevelViewer, owner.ID)
+	assert.NoError(t, err)
+
+	_, err = grantAccess(ctx, projects[1], other.Email, core.ProjectAccessLevelViewer, owner.ID)
+	if assert.IsType(t, &quota.ExceededError{}, err) {
+		assert.Equal(t, quota.LimitMembers, err.(*quota.ExceededError).Limit)
+	}
+	// unknown email fails the same way on full workspace
+	_, err = grantAccess(ctx, projects[1], "nobody@example.com", core.ProjectAccessLevelViewer, owner.ID)
+	assert.IsType(t, &quota.ExceededError{}, err)
+}
@@ -0,0 +1,172 @@
+package member
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.project.member.listAll
+// @Summary List users granted access to a project, workspace members are not included
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/members [get]
+// @Produce json
+// @Success 200 {array} core.ProjectMember
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	members, err := getMembers(r.Context(), currentProject.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, members)
+}
+
+// @Id api.v1.workspace.project.member.create
+// @Summary Grant registered user access to a project, the user does not need to be a workspace member. User without workspace role takes a member seat. Unknown email is accepted the same way so registered emails can not be discovered, granted users are listed in project members.
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param param body grantAccessParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/members [post]
+// @Produce json
+// @Success 202
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	var param grantAccessParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	_, err := grantAccess(r.Context(), currentProject, param.Email, param.AccessLevel, appctx.Actor(r.Context()).ID)
+	var exceeded *quota.ExceededError
+	switch {
+	case err == errAlreadyGranted:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"email": "user already has access to the project",
+		})
+		return
+	case errors.As(err, &exceeded):
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+		return
+	case err != nil && err != errUserNotFound:
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	// unknown email gets the same response as successful grant
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// @Id api.v1.workspace.project.member.update
+// @Summary Change access level granted on a project
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param user_id path integer true "User id"
+// @Param param body changeAccessLevelParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/members/{user_id} [patch]
+// @Produce json
+// @Success 200 {object} core.ProjectMember
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+
+	var param changeAccessLevelParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	changed, err := changeAccessLevel(r.Context(), currentProject, userID, param.AccessLevel, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !changed {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	member, err := getMember(r.Context(), currentProject.ID, userID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, member)
+}
+
+// @Id api.v1.workspace.project.member.delete
+// @Summary Revoke access granted on a project, workspace membership is not affected
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param user_id path integer true "User id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/members/{user_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+
+	revoked, err := revokeAccess(r.Context(), currentProject, userID, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !revoked {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,34 @@
+package member
+
+import (
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+var accessLevelRule = []validation.Rule{
+	validation.Required,
+	validation.In(core.ProjectAccessLevelEditor, core.ProjectAccessLevelViewer),
+}
+
+type grantAccessParam struct {
+	Email       string `json:"email"`
+	AccessLevel string `json:"access_level"`
+}
+
+func (p grantAccessParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Email, validation.Required, validation.Length(1, 500), is.EmailFormat),
+		validation.Field(&p.AccessLevel, accessLevelRule...),
+	)
+}
+
+type changeAccessLevelParam struct {
+	AccessLevel string `json:"access_level"`
+}
+
+func (p changeAccessLevelParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.AccessLevel, accessLevelRule...),
+	)
+}
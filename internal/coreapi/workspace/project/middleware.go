@@ -0,0 +1,72 @@
+package project
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// CurrentProjectMiddleware loads project from url and authenticated user effective access level on it.
+// Project is reported as not found when it does not belong to current workspace or user can not access it.
+// It must be used after CurrentWorkspaceMiddleware.
+func CurrentProjectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentUser := appctx.AuthenticatedUser(r.Context())
+		currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+		projectID, err := strconv.ParseInt(chi.URLParam(r, "project_id"), 10, 64)
+		if err != nil || projectID <= 0 {
+			apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+				"project_id": "invalid",
+			})
+			return
+		}
+
+		project, err := getProject(r.Context(), currentWorkspace.ID, projectID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if project == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		projectLevel, err := getProjectAccessLevel(r.Context(), project.ID, currentUser.ID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		accessLevel := core.EffectiveProjectAccessLevel(appctx.WorkspaceAccessLevel(r.Context()), projectLevel)
+		if accessLevel == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), appctx.KeyCurrentProject, project)
+		ctx = context.WithValue(ctx, appctx.KeyProjectAccess, accessLevel)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAccessLevel only allows users having at least the required effective access level on current project,
+// it must be used after CurrentProjectMiddleware
+func RequireAccessLevel(required string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessLevel := appctx.ProjectAccessLevel(r.Context())
+			if !core.WorkspaceAccessLevelAtLeast(accessLevel, required) {
+				apihelper.ForbiddenErrResp(w, "forbidden", map[string]string{
+					"access_level": required + " access required",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
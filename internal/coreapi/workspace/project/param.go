@@ -0,0 +1,138 @@
+package project
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/awanku/awanku/internal/coreapi/utils/cursor"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+// namePattern allows letters, digits, spaces, dots, dashes and underscores, starting with letter or digit
+var namePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} ._-]*$`)
+
+var nameRule = []validation.Rule{
+	validation.Required,
+	validation.Length(1, 100),
+	validation.Match(namePattern).Error("must start with letter or digit and only contain letters, digits, spaces, dots, dashes and underscores"),
+}
+
+type saveProjectParam struct {
+	Name string `json:"name"`
+}
+
+func (p saveProjectParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, nameRule...),
+	)
+}
+
+// repositoryNamePattern matches full repository name in owner/name form
+var repositoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// branchPattern rejects characters git does not allow in branch names
+var branchPattern = regexp.MustCompile(`^[^\s~^:?*\[\\]+$`)
+
+type linkRepositoryParam struct {
+	RepositoryConnectionID int64  `json:"repository_connection_id"`
+	Repository             string `json:"repository"`
+	Branch                 string `json:"branch"`
+	RootDir                string `json:"root_dir"`
+}
+
+func (p linkRepositoryParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.RepositoryConnectionID, validation.Required, validation.Min(int64(1))),
+		validation.Field(&p.Repository, validation.Required, validation.Length(1, 200), validation.Match(repositoryNamePattern).Error("must be in owner/name format")),
+		validation.Field(&p.Branch, validation.Required, validation.Length(1, 255), validation.Match(branchPattern).Error("invalid branch name"), validation.By(validateBranch)),
+		validation.Field(&p.RootDir, validation.Length(0, 500), validation.By(validateRootDir)),
+	)
+}
+
+// CleanRootDir returns root directory relative to repository root, starting with slash
+func (p linkRepositoryParam) CleanRootDir() string {
+	return path.Clean("/" + p.RootDir)
+}
+
+func validateBranch(value interface{}) error {
+	branch, _ := value.(string)
+	if strings.Contains(branch, "..") || strings.Contains(branch, "//") || strings.Contains(branch, "@{") ||
+		strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") ||
+		strings.HasSuffix(branch, "/") || strings.HasSuffix(branch, ".") || strings.HasSuffix(branch, ".lock") {
+		return errors.New("invalid branch name")
+	}
+	return nil
+}
+
+func validateRootDir(value interface{}) error {
+	rootDir, _ := value.(string)
+	for _, part := range strings.Split(rootDir, "/") {
+		if part == ".." {
+			return errors.New("must not point outside of repository")
+		}
+	}
+	return nil
+}
+
+type transferProjectParam struct {
+	WorkspaceID int64 `json:"workspace_id"`
+}
+
+func (p transferProjectParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.WorkspaceID, validation.Required, validation.Min(int64(1))),
+	)
+}
+
+type listProjectsParam struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+
+	afterID    int64
+	parseError map[string]string
+}
+
+// parseListProjectsParam reads pagination from query string, malformed values are reported by Validate
+func parseListProjectsParam(query url.Values) *listProjectsParam {
+	p := &listProjectsParam{
+		Cursor:     query.Get("cursor"),
+		Limit:      defaultLimit,
+		parseError: map[string]string{},
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			p.parseError["limit"] = "must be a number"
+		}
+		p.Limit = limit
+	}
+	if p.Cursor != "" {
+		p.afterID = cursor.Decode(p.Cursor)
+		if p.afterID <= 0 {
+			p.parseError["cursor"] = "invalid"
+		}
+	}
+	return p
+}
+
+func (p listProjectsParam) Validate() error {
+	if len(p.parseError) > 0 {
+		errs := validation.Errors{}
+		for name, msg := range p.parseError {
+			errs[name] = validation.NewError("validation_invalid_format", msg)
+		}
+		return errs
+	}
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Limit, validation.Required, validation.Min(1), validation.Max(maxLimit)),
+	)
+}
@@ -0,0 +1,304 @@
+package resource
+
+import (
+	"context"
+	"errors"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/internal/coreapi/workspace/webhook"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var errEnvironmentNotFound = errors.New("environment does not exist")
+
+func listResources(ctx context.Context, environmentID int64) ([]*core.Resource, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from resources
+        where environment_id = ? and deleted_at is null
+        order by id
+    `
+	var resources []*core.Resource
+	err := db.Query(&resources, query, environmentID)
+	if err != nil {
+		return []*core.Resource{}, err
+	}
+	if resources == nil {
+		resources = []*core.Resource{}
+	}
+	return resources, nil
+}
+
+func getResource(ctx context.Context, environmentID, id int64) (*core.Resource, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from resources
+        where id = ? and environment_id = ? and deleted_at is null
+    `
+	var resource core.Resource
+	err := db.Query(&resource, query, id, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	if resource.ID == 0 {
+		return nil, nil
+	}
+	return &resource, nil
+}
+
+func createResource(ctx context.Context, project *core.Project, resource *core.Resource, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// environment may have been deleted since it was loaded by middleware
+	var queryEnvironment = `
+        select id
+        from project_environments
+        where id = ? and project_id = ? and deleted_at is null
+        for share
+    `
+	var environment struct{ ID int64 }
+	err = tx.Query(&environment, queryEnvironment, resource.EnvironmentID, project.ID)
+	if err != nil {
+		return err
+	}
+	if environment.ID == 0 {
+		return errEnvironmentNotFound
+	}
+
+	err = quota.Reserve(tx, project.WorkspaceID, quota.Request{
+		ResourceType:  resource.Type,
+		Resources:     1,
+		CPUMillicores: resource.CPUMillicores,
+		MemoryMB:      resource.MemoryMB,
+	})
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        insert into resources (project_id, environment_id, name, type, payload, cpu_millicores, memory_mb, created_at)
+        values (?, ?, ?, ?, ?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(resource, query, project.ID, resource.EnvironmentID, resource.Name, resource.Type, payloadOrEmpty(resource.Payload), resource.CPUMillicores, resource.MemoryMB)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, project.WorkspaceID, resourceEvent(resource, actorID, activity.VerbCreated))
+}
+
+// updateResource returns nil when resource does not exist
+func updateResource(ctx context.Context, project *core.Project, resource *core.Resource, actorID int64) (updated *core.Resource, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryCurrent = `
+        select *
+        from resources
+        where id = ? and environment_id = ? and project_id = ? and deleted_at is null
+        for update
+    `
+	var current core.Resource
+	err = tx.Query(&current, queryCurrent, resource.ID, resource.EnvironmentID, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current.ID == 0 {
+		return nil, nil
+	}
+
+	// only growth has to fit into plan, shrinking resource is always allowed
+	err = quota.Reserve(tx, project.WorkspaceID, quota.Request{
+		CPUMillicores: resource.CPUMillicores - current.CPUMillicores,
+		MemoryMB:      resource.MemoryMB - current.MemoryMB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var query = `
+        update resources
+        set name = ?, payload = ?, cpu_millicores = ?, memory_mb = ?, updated_at = now()
+        where id = ?
+        returning *
+    `
+	var saved core.Resource
+	err = tx.Query(&saved, query, resource.Name, payloadOrEmpty(resource.Payload), resource.CPUMillicores, resource.MemoryMB, current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, resourceEvent(&saved, actorID, activity.VerbUpdated))
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// deleteResource soft deletes resource, it can be restored from workspace trash.
+// It returns false when resource does not exist.
+func deleteResource(ctx context.Context, project *core.Project, environmentID, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update resources
+        set deleted_at = now()
+        where id = ? and environment_id = ? and project_id = ? and deleted_at is null
+        returning *
+    `
+	var resource core.Resource
+	err = tx.Query(&resource, query, id, environmentID, project.ID)
+	if err != nil {
+		return false, err
+	}
+	if resource.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, project.WorkspaceID, resourceEvent(&resource, actorID, activity.VerbDeleted))
+	return err == nil, err
+}
+
+func payloadOrEmpty(payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		return map[string]interface{}{}
+	}
+	return payload
+}
+
+func resourceEvent(resource *core.Resource, actorID int64, verb string) *activity.Event {
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetResource,
+		TargetID:   resource.ID,
+		Metadata: map[string]interface{}{
+			"project_id":     resource.ProjectID,
+			"environment_id": resource.EnvironmentID,
+			"name":           resource.Name,
+			"type":           resource.Type,
+		},
+	}
+}
+
+// stateWebhookEvents maps resource state to webhook event sent when resource enters it
+var stateWebhookEvents = map[string]string{
+	core.ResourceStateProvisioningSuccess: core.WebhookEventResourceProvisioned,
+	core.ResourceStateProvisioningFailed:  core.WebhookEventResourceProvisioningFailed,
+}
+
+// updateState changes resource state as reported by provisioner and meters the change for billing,
+// finishing provisioning is announced to workspace webhooks. It returns nil when resource does not exist.
+func updateState(ctx context.Context, resourceID int64, state string) (updated *core.Resource, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryCurrent = `
+        select resources.*, projects.workspace_id
+        from resources
+        join projects on projects.id = resources.project_id
+        where resources.id = ? and resources.deleted_at is null
+        for update of resources
+    `
+	var current struct {
+		core.Resource
+		WorkspaceID int64
+	}
+	err = tx.Query(&current, queryCurrent, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	if current.ID == 0 {
+		return nil, nil
+	}
+	if current.State == state {
+		return &current.Resource, nil
+	}
+
+	var query = `
+        update resources
+        set state = ?, updated_at = now()
+        where id = ?
+        returning *
+    `
+	var saved core.Resource
+	err = tx.Query(&saved, query, state, current.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = billing.RecordStateChange(tx, current.WorkspaceID, current.ID, current.State, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if eventType, ok := stateWebhookEvents[state]; ok {
+		err = webhook.Enqueue(tx, current.WorkspaceID, eventType, map[string]interface{}{
+			"resource_id":    saved.ID,
+			"project_id":     saved.ProjectID,
+			"environment_id": saved.EnvironmentID,
+			"name":           saved.Name,
+			"type":           saved.Type,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// state is reported by provisioner, not by any user
+	event := resourceEvent(&saved, 0, activity.VerbUpdated)
+	event.Metadata["reported_by"] = "provisioner"
+	event.Metadata["previous_state"] = current.State
+	event.Metadata["state"] = state
+	err = activity.Record(tx, current.WorkspaceID, event)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
@@ -0,0 +1,110 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLifecycle(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeStaging)
+
+	resource := &core.Resource{
+		ProjectID:     project.ID,
+		EnvironmentID: environment.ID,
+		Name:          "db",
+		Type:          core.ResourceTypePostgres,
+		Payload:       map[string]interface{}{"version": "12"},
+		CPUMillicores: 250,
+		MemoryMB:      256,
+	}
+	err := createResource(ctx, project, resource, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, resource.ID > 0)
+	assert.Equal(t, core.ResourceStateUnknown, resource.State)
+
+	resources, err := listResources(ctx, environment.ID)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+
+	changed := *resource
+	changed.Name = "primary-db"
+	updated, err := updateResource(ctx, project, &changed, user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, "primary-db", updated.Name)
+		assert.Equal(t, "12", updated.Payload["version"])
+	}
+
+	// resource of another environment is not found
+	found, err := getResource(ctx, environment.ID+1, resource.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	deleted, err := deleteResource(ctx, project, environment.ID, resource.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	found, err = getResource(ctx, environment.ID, resource.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	deleted, err = deleteResource(ctx, project, environment.ID, resource.ID, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+
+	var activities []struct{ Verb string }
+	err = appctx.Database(ctx).Query(&activities, "select verb from workspace_activity_logs where workspace_id = ? and target_type = 'resource' and target_id = ? order by id", workspace.ID, resource.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []struct{ Verb string }{{"created"}, {"updated"}, {"deleted"}}, activities)
+}
+
+func TestResourceQuota(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeStaging)
+
+	newResource := func(cpu int64) *core.Resource {
+		return &core.Resource{ProjectID: project.ID, EnvironmentID: environment.ID, Name: "cache", Type: core.ResourceTypeRedis, CPUMillicores: cpu}
+	}
+
+	// free plan allows 2 resources per type and 1000 millicores
+	first := newResource(500)
+	assert.NoError(t, createResource(ctx, project, first, user.ID))
+	assert.NoError(t, createResource(ctx, project, newResource(0), user.ID))
+	err := createResource(ctx, project, newResource(0), user.ID)
+	if assert.IsType(t, &quota.ExceededError{}, err) {
+		assert.Equal(t, quota.LimitResources, err.(*quota.ExceededError).Limit)
+	}
+
+	grown := *first
+	grown.CPUMillicores = 1500
+	_, err = updateResource(ctx, project, &grown, user.ID)
+	if assert.IsType(t, &quota.ExceededError{}, err) {
+		assert.Equal(t, quota.LimitCPUMillicores, err.(*quota.ExceededError).Limit)
+	}
+	grown.CPUMillicores = 1000
+	updated, err := updateResource(ctx, project, &grown, user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, updated)
+}
+
+func TestParamValidate(t *testing.T) {
+	assert.NoError(t, saveResourceParam{Name: "db", Type: core.ResourceTypePostgres}.Validate())
+	assert.Error(t, saveResourceParam{Name: "db", Type: "oracle"}.Validate())
+	assert.Error(t, saveResourceParam{Name: "Primary DB", Type: core.ResourceTypePostgres}.Validate())
+	assert.Error(t, saveResourceParam{Name: "db", Type: core.ResourceTypePostgres, MemoryMB: -1}.Validate())
+}
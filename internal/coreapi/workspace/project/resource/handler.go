@@ -1,23 +1,312 @@
 package resource
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
 
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.project.environment.resource.listAll
+// @Summary List resources of project environment
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id}/resources [get]
+// @Produce json
+// @Success 200 {array} core.Resource
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentEnvironment := appctx.CurrentProjectEnvironment(r.Context())
+
+	resources, err := listResources(r.Context(), currentEnvironment.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
 
+	apihelper.JSON(w, http.StatusOK, resources)
 }
 
+// @Id api.v1.workspace.project.environment.resource.get
+// @Summary Get resource
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Param resource_id path integer true "Resource id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id}/resources/{resource_id} [get]
+// @Produce json
+// @Success 200 {object} core.Resource
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleGet(w http.ResponseWriter, r *http.Request) {
-
+	resource, ok := loadResource(w, r)
+	if !ok {
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, resource)
 }
 
+// @Id api.v1.workspace.project.environment.resource.create
+// @Summary Create resource in project environment, it is provisioned asynchronously. Resource must fit into workspace plan.
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Param param body saveResourceParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id}/resources [post]
+// @Produce json
+// @Success 201 {object} core.Resource
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleCreate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	currentEnvironment := appctx.CurrentProjectEnvironment(r.Context())
 
+	var param saveResourceParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	resource := core.Resource{
+		ProjectID:     currentProject.ID,
+		EnvironmentID: currentEnvironment.ID,
+		Name:          param.Name,
+		Type:          param.Type,
+		Payload:       param.Payload,
+		CPUMillicores: param.CPUMillicores,
+		MemoryMB:      param.MemoryMB,
+	}
+	err := createResource(r.Context(), currentProject, &resource, appctx.Actor(r.Context()).ID)
+	var exceeded *quota.ExceededError
+	switch {
+	case err == errEnvironmentNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.As(err, &exceeded):
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+		return
+	case err != nil:
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusCreated, resource)
 }
 
+// @Id api.v1.workspace.project.environment.resource.update
+// @Summary Update resource, omitted fields are kept and type can not be changed. Added CPU and memory must fit into workspace plan.
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Param resource_id path integer true "Resource id"
+// @Param param body saveResourceParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id}/resources/{resource_id} [patch]
+// @Produce json
+// @Success 200 {object} core.Resource
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+
+	current, ok := loadResource(w, r)
+	if !ok {
+		return
+	}
 
+	// payload is left empty so given payload replaces current one instead of being merged into it
+	param := saveResourceParam{
+		Name:          current.Name,
+		Type:          current.Type,
+		CPUMillicores: current.CPUMillicores,
+		MemoryMB:      current.MemoryMB,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if param.Payload == nil {
+		param.Payload = current.Payload
+	}
+	if param.Type != current.Type {
+		apihelper.ValidationErrResp(w, map[string]string{
+			"type": "can not be changed",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	resource := *current
+	resource.Name = param.Name
+	resource.Payload = param.Payload
+	resource.CPUMillicores = param.CPUMillicores
+	resource.MemoryMB = param.MemoryMB
+	updated, err := updateResource(r.Context(), currentProject, &resource, appctx.Actor(r.Context()).ID)
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+		return
+	}
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if updated == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, updated)
 }
 
+// @Id api.v1.workspace.project.environment.resource.delete
+// @Summary Delete resource, it can be restored from workspace trash
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Param environment_id path integer true "Environment id"
+// @Param resource_id path integer true "Resource id"
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/environments/{environment_id}/resources/{resource_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
 func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentProject := appctx.CurrentProject(r.Context())
+	currentEnvironment := appctx.CurrentProjectEnvironment(r.Context())
+
+	resourceID, ok := resourceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := deleteResource(r.Context(), currentProject, currentEnvironment.ID, resourceID, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resourceIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	resourceID, err := strconv.ParseInt(chi.URLParam(r, "resource_id"), 10, 64)
+	if err != nil || resourceID <= 0 {
+		apihelper.BadRequestErrResp(w, "bad_request", map[string]string{
+			"resource_id": "invalid",
+		})
+		return 0, false
+	}
+	return resourceID, true
+}
+
+func loadResource(w http.ResponseWriter, r *http.Request) (*core.Resource, bool) {
+	currentEnvironment := appctx.CurrentProjectEnvironment(r.Context())
+
+	resourceID, ok := resourceIDParam(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	resource, err := getResource(r.Context(), currentEnvironment.ID, resourceID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return nil, false
+	}
+	if resource == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return resource, true
+}
+
+// @Id api.v1.provisioner.resources.updateState
+// @Summary Report resource state, used by provisioner. State changes are metered for billing.
+// @Tags Provisioner
+// @Security provisionerToken
+// @Accept json
+// @Param resource_id path integer true "Resource id"
+// @Param param body updateStateParam true "Request body"
+// @Router /v1/provisioner/resources/{resource_id}/state [post]
+// @Produce json
+// @Success 200 {object} core.Resource
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdateState(w http.ResponseWriter, r *http.Request) {
+	resourceID, ok := resourceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var param updateStateParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	resource, err := updateState(r.Context(), resourceID, param.State)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if resource == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
+	apihelper.JSON(w, http.StatusOK, resource)
 }
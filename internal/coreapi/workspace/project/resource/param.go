@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"regexp"
+
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// namePattern allows lowercase letters, digits and dashes so resource name can be used in hostnames
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var resourceTypes = func() []interface{} {
+	types := make([]interface{}, len(core.ResourceTypes))
+	for i, t := range core.ResourceTypes {
+		types[i] = t
+	}
+	return types
+}()
+
+var resourceStates = func() []interface{} {
+	states := make([]interface{}, len(core.ResourceStates))
+	for i, state := range core.ResourceStates {
+		states[i] = state
+	}
+	return states
+}()
+
+// saveResourceParam is used for both create and update,
+// on update it is prefilled from current resource so omitted fields are kept and type can not be changed
+type saveResourceParam struct {
+	Name          string                 `json:"name"`
+	Type          string                 `json:"type"`
+	Payload       map[string]interface{} `json:"payload"`
+	CPUMillicores int64                  `json:"cpu_millicores"`
+	MemoryMB      int64                  `json:"memory_mb"`
+}
+
+func (p saveResourceParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name,
+			validation.Required,
+			validation.Length(1, 63),
+			validation.Match(namePattern).Error("must start with lowercase letter or digit and only contain lowercase letters, digits and dashes"),
+		),
+		validation.Field(&p.Type, validation.Required, validation.In(resourceTypes...)),
+		validation.Field(&p.CPUMillicores, validation.Min(int64(0))),
+		validation.Field(&p.MemoryMB, validation.Min(int64(0))),
+	)
+}
+
+// updateStateParam is reported by provisioner when resource moves between states
+type updateStateParam struct {
+	State string `json:"state"`
+}
+
+func (p updateStateParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.State, validation.Required, validation.In(resourceStates...)),
+	)
+}
@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"context"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// GetUsage returns current workspace consumption against its plan limits
+func GetUsage(ctx context.Context, workspaceID int64) (*core.WorkspaceUsage, error) {
+	return getUsage(appctx.Database(ctx), workspaceID)
+}
+
+func getUsage(db querier, workspaceID int64) (*core.WorkspaceUsage, error) {
+	var plan core.Plan
+	var planQuery = `
+        select plans.*
+        from plans
+        join workspaces on workspaces.plan = plans.code
+        where workspaces.id = ?
+    `
+	if err := db.Query(&plan, planQuery, workspaceID); err != nil {
+		return nil, err
+	}
+
+	var counts struct {
+		Members               int64
+		PendingInvitations    int64
+		Projects              int64
+		RepositoryConnections int64
+		CPUMillicores         int64
+		MemoryMB              int64
+	}
+	var countsQuery = `
+        select
+            (
+                -- users granted access to a project are guests of the workspace and take a seat too
+                select count(*)
+                from (
+                    select user_id
+                    from workspace_users
+                    where workspace_id = ?0 and deleted_at is null
+                    union
+                    select project_users.user_id
+                    from project_users
+                    join projects on projects.id = project_users.project_id
+                    where projects.workspace_id = ?0 and projects.deleted_at is null and project_users.deleted_at is null
+                ) as seated
+            ) as members,
+            (
+                select count(*)
+                from workspace_invitations
+                where workspace_id = ?0 and status = 'pending' and expires_at > now()
+            ) as pending_invitations,
+            (
+                select count(*)
+                from projects
+                where workspace_id = ?0 and deleted_at is null
+            ) as projects,
+            (
+                select count(*)
+                from workspace_repository_connections
+                where workspace_id = ?0 and deleted_at is null
+            ) as repository_connections,
+            (
+                select coalesce(sum(resources.cpu_millicores), 0)
+                from resources
+                join projects on projects.id = resources.project_id
+                where projects.workspace_id = ?0 and projects.deleted_at is null and resources.deleted_at is null
+            ) as cpu_millicores,
+            (
+                select coalesce(sum(resources.memory_mb), 0)
+                from resources
+                join projects on projects.id = resources.project_id
+                where projects.workspace_id = ?0 and projects.deleted_at is null and resources.deleted_at is null
+            ) as memory_mb
+    `
+	if err := db.Query(&counts, countsQuery, workspaceID); err != nil {
+		return nil, err
+	}
+
+	var resources []struct {
+		Type  string
+		Count int64
+	}
+	var resourcesQuery = `
+        select resources.type, count(*) as count
+        from resources
+        join projects on projects.id = resources.project_id
+        where projects.workspace_id = ? and projects.deleted_at is null and resources.deleted_at is null
+        group by resources.type
+    `
+	if err := db.Query(&resources, resourcesQuery, workspaceID); err != nil {
+		return nil, err
+	}
+
+	usage := &core.WorkspaceUsage{
+		Plan:                  plan.Code,
+		Members:               core.QuotaUsage{Used: counts.Members, Limit: plan.MaxMembers},
+		PendingInvitations:    counts.PendingInvitations,
+		Projects:              core.QuotaUsage{Used: counts.Projects, Limit: plan.MaxProjects},
+		Resources:             map[string]core.QuotaUsage{},
+		RepositoryConnections: core.QuotaUsage{Used: counts.RepositoryConnections, Limit: plan.MaxRepositoryConnections},
+		CPUMillicores:         core.QuotaUsage{Used: counts.CPUMillicores, Limit: plan.MaxCPUMillicores},
+		MemoryMB:              core.QuotaUsage{Used: counts.MemoryMB, Limit: plan.MaxMemoryMB},
+	}
+	// every known type is listed so limit is shown before the type is used
+	for _, resourceType := range core.ResourceTypes {
+		usage.Resources[resourceType] = core.QuotaUsage{Used: 0, Limit: plan.MaxResourcesPerType}
+	}
+	for _, resource := range resources {
+		usage.Resources[resource.Type] = core.QuotaUsage{Used: resource.Count, Limit: plan.MaxResourcesPerType}
+	}
+	return usage, nil
+}
@@ -0,0 +1,76 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/bxcodec/faker/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUsage(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, users[0].ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceInvitationFactory(ctx, workspace.ID, users[0].ID, faker.Email())
+
+	usage, err := GetUsage(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "free", usage.Plan)
+	assert.Equal(t, int64(1), usage.Members.Used)
+	if assert.NotNil(t, usage.Members.Limit) {
+		assert.Equal(t, int64(3), *usage.Members.Limit)
+	}
+	assert.Equal(t, int64(1), usage.PendingInvitations)
+	assert.Equal(t, int64(0), usage.RepositoryConnections.Used)
+	if assert.Contains(t, usage.Resources, core.ResourceTypePostgres) {
+		assert.Equal(t, int64(0), usage.Resources[core.ResourceTypePostgres].Used)
+		assert.NotNil(t, usage.Resources[core.ResourceTypePostgres].Limit)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	users := testutil.UserFactory(ctx, 2)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, users[0].ID, core.WorkspaceAccessLevelOwner)
+	testutil.WorkspaceUserFactory(ctx, workspace.ID, users[1].ID, core.WorkspaceAccessLevelEditor)
+
+	reserve := func(req Request) error {
+		tx, err := appctx.Database(ctx).NewTransaction()
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		return Reserve(tx, workspace.ID, req)
+	}
+
+	assert.NoError(t, reserve(Request{Members: 1}))
+	assert.NoError(t, reserve(Request{Invitations: 1}))
+
+	err := reserve(Request{Members: 2})
+	if assert.IsType(t, &ExceededError{}, err) {
+		assert.Equal(t, LimitMembers, err.(*ExceededError).Limit)
+		assert.Equal(t, int64(3), err.(*ExceededError).Max)
+		assert.Equal(t, int64(2), err.(*ExceededError).Used)
+	}
+
+	// pending invitations take member seats
+	testutil.WorkspaceInvitationFactory(ctx, workspace.ID, users[0].ID, faker.Email())
+	assert.NoError(t, reserve(Request{Members: 1}))
+	assert.IsType(t, &ExceededError{}, reserve(Request{Invitations: 1}))
+
+	// per type limit applies before the type is used
+	assert.NoError(t, reserve(Request{ResourceType: core.ResourceTypeRedis, Resources: 2}))
+	assert.IsType(t, &ExceededError{}, reserve(Request{ResourceType: core.ResourceTypeRedis, Resources: 3}))
+
+	// unlimited plan
+	err = appctx.Database(ctx).WriterExec("update workspaces set plan = 'enterprise' where id = ?", workspace.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, reserve(Request{Members: 100, Invitations: 100, Projects: 100}))
+}
@@ -0,0 +1,32 @@
+package quota
+
+import (
+	"net/http"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+)
+
+// @Id api.v1.workspace.usage.get
+// @Summary Get workspace usage against its plan limits
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/usage [get]
+// @Produce json
+// @Success 200 {object} core.WorkspaceUsage
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGetUsage(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	usage, err := GetUsage(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, usage)
+}
@@ -0,0 +1,136 @@
+package quota
+
+import (
+	"fmt"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+// plan limits
+const (
+	LimitMembers               = "members"
+	LimitProjects              = "projects"
+	LimitResources             = "resources"
+	LimitRepositoryConnections = "repository_connections"
+	LimitCPUMillicores         = "cpu_millicores"
+	LimitMemoryMB              = "memory_mb"
+)
+
+// ExceededError is returned when creation would take workspace over its plan limit
+type ExceededError struct {
+	Limit string
+	Max   int64
+	Used  int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s limit is %d, %d already used", e.Limit, e.Max, e.Used)
+}
+
+// Details returns error details for API response
+func (e *ExceededError) Details() map[string]string {
+	return map[string]string{
+		e.Limit: fmt.Sprintf("plan allows at most %d, %d already used", e.Max, e.Used),
+	}
+}
+
+// Request describes what is about to be created in a workspace
+type Request struct {
+	// Members counts new members joining right away
+	Members int64
+	// Invitations counts new invitations, pending invitations are counted as members
+	Invitations           int64
+	Projects              int64
+	RepositoryConnections int64
+	// ResourceType is required when Resources is set
+	ResourceType  string
+	Resources     int64
+	CPUMillicores int64
+	MemoryMB      int64
+}
+
+// querier is implemented by both database cluster and transaction
+type querier interface {
+	Query(dest interface{}, query string, args ...interface{}) error
+}
+
+// Reserve checks that request fits into workspace plan. It locks the workspace row,
+// so it must be called inside the transaction which creates the items to make concurrent creations wait for each other.
+func Reserve(tx hansip.Transaction, workspaceID int64, req Request) error {
+	var lockQuery = `
+        select id
+        from workspaces
+        where id = ?
+        for update
+    `
+	var locked struct{ ID int64 }
+	if err := tx.Query(&locked, lockQuery, workspaceID); err != nil {
+		return err
+	}
+
+	usage, err := getUsage(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	resources := usage.Resources[req.ResourceType]
+	checks := []struct {
+		limit     string
+		usage     core.QuotaUsage
+		requested int64
+	}{
+		{LimitMembers, usage.Members, req.Members},
+		{LimitMembers, core.QuotaUsage{Used: usage.Members.Used + usage.PendingInvitations, Limit: usage.Members.Limit}, req.Invitations},
+		{LimitProjects, usage.Projects, req.Projects},
+		{LimitRepositoryConnections, usage.RepositoryConnections, req.RepositoryConnections},
+		{LimitResources, resources, req.Resources},
+		{LimitCPUMillicores, usage.CPUMillicores, req.CPUMillicores},
+		{LimitMemoryMB, usage.MemoryMB, req.MemoryMB},
+	}
+	for _, check := range checks {
+		if check.requested <= 0 || check.usage.Limit == nil {
+			continue
+		}
+		if check.usage.Used+check.requested > *check.usage.Limit {
+			return &ExceededError{
+				Limit: check.limit,
+				Max:   *check.usage.Limit,
+				Used:  check.usage.Used,
+			}
+		}
+	}
+	return nil
+}
+
+// ReserveMember checks that user about to join the workspace as member or through project grant fits into plan,
+// users who are already workspace members or have access to any of its projects take a seat already
+func ReserveMember(tx hansip.Transaction, workspaceID, userID int64) error {
+	var query = `
+        select (
+            exists (
+                select 1
+                from workspace_users
+                where workspace_id = ?0 and user_id = ?1 and deleted_at is null
+            )
+            or exists (
+                select 1
+                from project_users
+                join projects on projects.id = project_users.project_id
+                where
+                    projects.workspace_id = ?0
+                    and project_users.user_id = ?1
+                    and project_users.deleted_at is null
+                    and projects.deleted_at is null
+            )
+        ) as seated
+    `
+	var seat struct{ Seated bool }
+	if err := tx.Query(&seat, query, workspaceID, userID); err != nil {
+		return err
+	}
+	if seat.Seated {
+		return nil
+	}
+	return Reserve(tx, workspaceID, Request{Members: 1})
+}
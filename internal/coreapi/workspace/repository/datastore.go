@@ -6,25 +6,64 @@ import (
 	"strings"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/internal/coreapi/workspace/webhook"
 	"github.com/awanku/awanku/pkg/core"
 )
 
 var errConnectionAlreadyExists = errors.New("repository connection already exists")
 
-func saveRepositoryConnection(ctx context.Context, conn *core.RepositoryConnection) error {
-	db := appctx.Database(ctx)
+func saveRepositoryConnection(ctx context.Context, conn *core.RepositoryConnection, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	err = quota.Reserve(tx, conn.WorkspaceID, quota.Request{RepositoryConnections: 1})
+	if err != nil {
+		return err
+	}
 
 	var query = `
         insert into workspace_repository_connections (workspace_id, identifier, provider, payload, created_at)
         values (?, ?, ?, ?, now())
+        returning id, created_at
     `
-	err := db.WriterExec(query, conn.WorkspaceID, conn.Identifier, conn.Provider, conn.Payload)
+	err = tx.Query(conn, query, conn.WorkspaceID, conn.Identifier, conn.Provider, conn.Payload)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 			return errConnectionAlreadyExists
 		}
+		return err
+	}
+
+	err = webhook.Enqueue(tx, conn.WorkspaceID, core.WebhookEventRepositoryConnectionCreated, map[string]interface{}{
+		"repository_connection_id": conn.ID,
+		"provider":                 conn.Provider,
+		"identifier":               conn.Identifier,
+	})
+	if err != nil {
+		return err
 	}
-	return err
+
+	return activity.Record(tx, conn.WorkspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbCreated,
+		TargetType: activity.TargetRepositoryConnection,
+		TargetID:   conn.ID,
+		Metadata: map[string]interface{}{
+			"provider":   conn.Provider,
+			"identifier": conn.Identifier,
+		},
+	})
 }
 
 func getConnections(ctx context.Context, workspaceID int64) ([]*core.RepositoryConnection, error) {
@@ -42,3 +81,88 @@ func getConnections(ctx context.Context, workspaceID int64) ([]*core.RepositoryC
 	}
 	return conns, nil
 }
+
+// GetConnection returns repository connection which is not deleted, nil when it does not exist in the workspace
+func GetConnection(ctx context.Context, workspaceID, id int64) (*core.RepositoryConnection, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from workspace_repository_connections
+        where id = ? and workspace_id = ? and deleted_at is null
+    `
+	var conn core.RepositoryConnection
+	err := db.Query(&conn, query, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.ID == 0 {
+		return nil, nil
+	}
+	return &conn, nil
+}
+
+// deleteConnection moves repository connection to trash, returns false when connection does not exist.
+// Projects linked to repositories of the connection are unlinked, restoring the connection does not link them back.
+func deleteConnection(ctx context.Context, workspaceID, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update workspace_repository_connections
+        set deleted_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	var conn core.RepositoryConnection
+	err = tx.Query(&conn, query, id, workspaceID)
+	if err != nil {
+		return false, err
+	}
+	if conn.ID == 0 {
+		return false, nil
+	}
+
+	var queryUnlink = `
+        update projects
+        set
+            repository_connection_id = null,
+            repository_name = null,
+            repository_branch = null,
+            repository_root_dir = null,
+            updated_at = now()
+        where repository_connection_id = ?
+        returning id
+    `
+	var unlinked []struct{ ID int64 }
+	err = tx.Query(&unlinked, queryUnlink, conn.ID)
+	if err != nil {
+		return false, err
+	}
+	unlinkedProjectIDs := []int64{}
+	for _, project := range unlinked {
+		unlinkedProjectIDs = append(unlinkedProjectIDs, project.ID)
+	}
+
+	err = activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbDeleted,
+		TargetType: activity.TargetRepositoryConnection,
+		TargetID:   conn.ID,
+		Metadata: map[string]interface{}{
+			"provider":             conn.Provider,
+			"identifier":           conn.Identifier,
+			"unlinked_project_ids": unlinkedProjectIDs,
+		},
+	})
+	return err == nil, err
+}
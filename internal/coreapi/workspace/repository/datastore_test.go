@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteConnectionUnlinksProjects(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	conn := testutil.RepositoryConnectionFactory(ctx, workspace.ID)
+	project := testutil.ProjectFactory(ctx, workspace.ID)
+	err := db.WriterExec(`
+        update projects
+        set repository_connection_id = ?, repository_name = 'awanku/awanku', repository_branch = 'master', repository_root_dir = '/'
+        where id = ?
+    `, conn.ID, project.ID)
+	assert.NoError(t, err)
+
+	fetched, err := GetConnection(ctx, workspace.ID, conn.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetched)
+
+	deleted, err := deleteConnection(ctx, workspace.ID, conn.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	fetched, err = GetConnection(ctx, workspace.ID, conn.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, fetched)
+
+	var linked struct {
+		RepositoryConnectionID *int64
+		RepositoryName         *string
+	}
+	err = db.Query(&linked, "select repository_connection_id, repository_name from projects where id = ?", project.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, linked.RepositoryConnectionID)
+	assert.Nil(t, linked.RepositoryName)
+
+	deleted, err = deleteConnection(ctx, workspace.ID, conn.ID, user.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
@@ -2,20 +2,24 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
 	"github.com/awanku/awanku/pkg/core"
+	"github.com/go-chi/chi"
 )
 
 // @Id api.v1.workspace.repository.listAll
 // @Summary List all repositories in a workspace
 // @Tags Workspace
 // @Security oauthAccessToken
-// @Param workspace_id path integer true "Workspace id"
+// @Param workspace_id path string true "Workspace id or slug"
 // @Router /v1/workspaces/{workspace_id}/repositories [get]
 // @Produce json
 // @Success 200 {array} core.Repository
@@ -66,7 +70,7 @@ func HandleListAllRepositories(w http.ResponseWriter, r *http.Request) {
 // @Summary List all repository connections in a workspace
 // @Tags Workspace
 // @Security oauthAccessToken
-// @Param workspace_id path integer true "Workspace id"
+// @Param workspace_id path string true "Workspace id or slug"
 // @Router /v1/workspaces/{workspace_id}/connections [get]
 // @Produce json
 // @Success 200 {array} core.RepositoryConnection
@@ -89,11 +93,41 @@ func HandleListAllConnections(w http.ResponseWriter, r *http.Request) {
 	apihelper.JSON(w, http.StatusOK, conns)
 }
 
+// @Id api.v1.workspace.connections.delete
+// @Summary Delete repository connection, it stays restorable from trash for 30 days
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param connection_id path integer true "Repository connection id"
+// @Router /v1/workspaces/{workspace_id}/repositories/connections/{connection_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDeleteConnection(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	connectionID, _ := strconv.ParseInt(chi.URLParam(r, "connection_id"), 10, 64)
+
+	deleted, err := deleteConnection(r.Context(), currentWorkspace.ID, connectionID, appctx.Actor(r.Context()).ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // @Id api.v1.workspace.repository.provider.github.connect
 // @Summary Start connecting Github repository
 // @Tags Workspace
 // @Security oauthAccessToken
-// @Param workspace_id path integer true "Workspace id"
+// @Param workspace_id path string true "Workspace id or slug"
 // @Router /v1/workspaces/{workspace_id}/providers/github [get]
 // @Produce json
 // @Success 301
@@ -111,12 +145,13 @@ func HandleConnectGithub(w http.ResponseWriter, r *http.Request) {
 // @Summary Save Github repository connection
 // @Tags Workspace
 // @Security oauthAccessToken
-// @Param workspace_id path integer true "Workspace id"
+// @Param workspace_id path string true "Workspace id or slug"
 // @Router /v1/workspaces/{workspace_id}/providers/github [post]
 // @Produce json
 // @Success 201
 // @Failure 400 {object} apihelper.HTTPError
 // @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
 // @Failure 404
 // @Failure 500 {object} apihelper.InternalServerError
 func HandleSaveGithubConnection(w http.ResponseWriter, r *http.Request) {
@@ -152,13 +187,18 @@ func HandleSaveGithubConnection(w http.ResponseWriter, r *http.Request) {
 		Provider:    core.RepositoryProviderGithubV1,
 		Payload:     payload,
 	}
-	err = saveRepositoryConnection(r.Context(), &conn)
+	err = saveRepositoryConnection(r.Context(), &conn, appctx.Actor(r.Context()).ID)
 	if err == errConnectionAlreadyExists {
 		apihelper.ValidationErrResp(w, map[string]string{
 			"installation_id": "github connection with same organization/user already exists",
 		})
 		return
 	}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+		return
+	}
 	if err != nil {
 		apihelper.InternalServerErrResp(w, err)
 		return
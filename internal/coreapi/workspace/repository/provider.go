@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
 	"github.com/awanku/awanku/pkg/core"
@@ -58,31 +59,93 @@ func fetchGithubAppInstallationID(ctx context.Context, provider core.RepositoryP
 }
 
 func fetchGithubRepositories(ctx context.Context, installationID int64) ([]*core.Repository, error) {
+	client, err := githubInstallationClient(ctx, installationID)
+	if err != nil {
+		return []*core.Repository{}, err
+	}
+
+	var results []*core.Repository
+	opts := &githubService.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return []*core.Repository{}, err
+		}
+		for _, repo := range repos {
+			results = append(results, &core.Repository{
+				Name: repo.GetFullName(),
+				URL:  repo.GetHTMLURL(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return results, nil
+}
+
+func fetchGithubBranchExists(ctx context.Context, installationID int64, repositoryName, branch string) (bool, error) {
+	client, err := githubInstallationClient(ctx, installationID)
+	if err != nil {
+		return false, err
+	}
+
+	parts := strings.SplitN(repositoryName, "/", 2)
+	if len(parts) != 2 {
+		return false, nil
+	}
+	_, resp, err := client.Repositories.GetBranch(ctx, parts[0], parts[1], branch)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// githubInstallationClient returns github client authenticated as app installation
+func githubInstallationClient(ctx context.Context, installationID int64) (*githubService.Client, error) {
 	config := appctx.GithubAppConfig(ctx)
 
 	transport, err := ghinstallation.NewAppsTransport(http.DefaultTransport, config.AppID, config.PrivateKey)
 	if err != nil {
-		return []*core.Repository{}, err
+		return nil, err
 	}
 
 	client := githubService.NewClient(&http.Client{Transport: transport})
 	token, _, err := client.Apps.CreateInstallationToken(ctx, installationID, &githubService.InstallationTokenOptions{})
 	if err != nil {
-		return []*core.Repository{}, err
+		return nil, err
 	}
 
-	client = githubService.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token.GetToken()})))
-	repos, _, err := client.Apps.ListRepos(ctx, nil)
+	return githubService.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token.GetToken()}))), nil
+}
+
+// FindRepository returns repository with the given full name when it is accessible through the connection, nil otherwise
+func FindRepository(ctx context.Context, conn *core.RepositoryConnection, name string) (*core.Repository, error) {
+	repos, err := fetchRepositories(ctx, conn)
 	if err != nil {
-		return []*core.Repository{}, err
+		return nil, err
 	}
-
-	var results []*core.Repository
 	for _, repo := range repos {
-		results = append(results, &core.Repository{
-			Name: repo.GetFullName(),
-			URL:  repo.GetHTMLURL(),
-		})
+		if strings.EqualFold(repo.Name, name) {
+			return repo, nil
+		}
 	}
-	return results, nil
+	return nil, nil
+}
+
+// BranchExists returns false when repository accessible through the connection has no such branch
+func BranchExists(ctx context.Context, conn *core.RepositoryConnection, repositoryName, branch string) (bool, error) {
+	switch conn.Provider {
+	case core.RepositoryProviderGithubV1:
+		installationID, err := fetchGithubAppInstallationID(ctx, conn.Provider, conn.Payload)
+		if err != nil {
+			return false, err
+		}
+		return fetchGithubBranchExists(ctx, installationID, repositoryName, branch)
+	}
+	return false, fmt.Errorf("unknown provider: %s", conn.Provider)
 }
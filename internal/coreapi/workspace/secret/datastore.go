@@ -0,0 +1,285 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+)
+
+var errSecretExists = errors.New("secret with same name already exists")
+
+// scope is either a workspace, or a project in the workspace when ProjectID is set
+type scope struct {
+	WorkspaceID int64
+	ProjectID   *int64
+}
+
+func getSecrets(ctx context.Context, s *scope) ([]*core.Secret, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from secrets
+        where workspace_id = ? and project_id is not distinct from ? and deleted_at is null
+        order by name
+    `
+	var secrets []*core.Secret
+	err := db.Query(&secrets, query, s.WorkspaceID, s.ProjectID)
+	if err != nil {
+		return []*core.Secret{}, err
+	}
+	if secrets == nil {
+		secrets = []*core.Secret{}
+	}
+	return secrets, nil
+}
+
+func getSecret(ctx context.Context, s *scope, id int64) (*core.Secret, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from secrets
+        where id = ? and workspace_id = ? and project_id is not distinct from ? and deleted_at is null
+    `
+	var secret core.Secret
+	err := db.Query(&secret, query, id, s.WorkspaceID, s.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if secret.ID == 0 {
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+// createSecret seals value bound to id of the new secret, id is taken from sequence before the row is inserted
+func createSecret(ctx context.Context, keys envelope.KeyProvider, secret *core.Secret, value []byte, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryID = `
+        select nextval(pg_get_serial_sequence('secrets', 'id')) as id
+    `
+	var reserved struct {
+		ID int64
+	}
+	err = tx.Query(&reserved, queryID)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := envelope.Seal(keys, value, secretAAD(reserved.ID))
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        insert into secrets (id, workspace_id, project_id, name, ciphertext, nonce, wrapped_key, master_key_id, created_by_user_id, created_at)
+        values (?, ?, ?, ?, ?, ?, ?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(secret, query, reserved.ID, secret.WorkspaceID, secret.ProjectID, secret.Name, sealed.Ciphertext, sealed.Nonce, sealed.WrappedKey, sealed.MasterKeyID, secret.CreatedByUserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return errSecretExists
+		}
+		return err
+	}
+
+	return activity.Record(tx, secret.WorkspaceID, secretEvent(secret, actorID, activity.VerbCreated))
+}
+
+// updateSecretValue replaces secret value, returns nil when secret does not exist
+func updateSecretValue(ctx context.Context, keys envelope.KeyProvider, s *scope, id int64, value []byte, actorID int64) (secret *core.Secret, err error) {
+	sealed, err := envelope.Seal(keys, value, secretAAD(id))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update secrets
+        set
+            ciphertext = ?,
+            nonce = ?,
+            wrapped_key = ?,
+            master_key_id = ?,
+            version = version + 1,
+            updated_by_user_id = ?,
+            updated_at = now()
+        where id = ? and workspace_id = ? and project_id is not distinct from ? and deleted_at is null
+        returning *
+    `
+	var updated core.Secret
+	err = tx.Query(&updated, query, sealed.Ciphertext, sealed.Nonce, sealed.WrappedKey, sealed.MasterKeyID, actorID, id, s.WorkspaceID, s.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if updated.ID == 0 {
+		return nil, nil
+	}
+
+	err = activity.Record(tx, updated.WorkspaceID, secretEvent(&updated, actorID, activity.VerbUpdated))
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// deleteSecret returns false when secret does not exist
+func deleteSecret(ctx context.Context, s *scope, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update secrets
+        set deleted_at = now()
+        where id = ? and workspace_id = ? and project_id is not distinct from ? and deleted_at is null
+        returning *
+    `
+	var secret core.Secret
+	err = tx.Query(&secret, query, id, s.WorkspaceID, s.ProjectID)
+	if err != nil {
+		return false, err
+	}
+	if secret.ID == 0 {
+		return false, nil
+	}
+
+	err = activity.Record(tx, secret.WorkspaceID, secretEvent(&secret, actorID, activity.VerbDeleted))
+	return err == nil, err
+}
+
+// recordReveal must succeed before revealed value is returned, so no value leaves without audit trail
+func recordReveal(ctx context.Context, secret *core.Secret, actorID int64) error {
+	return activity.RecordNow(ctx, secret.WorkspaceID, secretEvent(secret, actorID, activity.VerbRevealed))
+}
+
+// RotateMasterKey rewraps data keys of all secrets, including deleted ones, with current master key.
+// Values are not re-encrypted. It returns number of rewrapped secrets.
+func RotateMasterKey(db *hansip.Cluster, keys envelope.KeyProvider, batchSize int) (int, error) {
+	var rotated int
+	for {
+		count, err := rotateBatch(db, keys, batchSize)
+		rotated += count
+		if err != nil || count == 0 {
+			return rotated, err
+		}
+	}
+}
+
+func rotateBatch(db *hansip.Cluster, keys envelope.KeyProvider, batchSize int) (count int, err error) {
+	tx, err := db.NewTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        select *
+        from secrets
+        where master_key_id <> ?
+        order by id
+        limit ?
+        for update skip locked
+    `
+	var secrets []*core.Secret
+	err = tx.Query(&secrets, query, keys.CurrentKeyID(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var queryUpdate = `
+        update secrets
+        set wrapped_key = ?, master_key_id = ?
+        where id = ?
+    `
+	for _, secret := range secrets {
+		sealed := sealedOf(secret)
+		if _, err = envelope.Rewrap(keys, sealed); err != nil {
+			return 0, err
+		}
+		if err = tx.Exec(queryUpdate, sealed.WrappedKey, sealed.MasterKeyID, secret.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(secrets), nil
+}
+
+// openSecret decrypts secret value
+func openSecret(keys envelope.KeyProvider, secret *core.Secret) ([]byte, error) {
+	return envelope.Open(keys, sealedOf(secret), secretAAD(secret.ID))
+}
+
+// secretAAD binds sealed value to its secret so it can not be opened when copied to another row
+func secretAAD(id int64) []byte {
+	return []byte("secrets:" + strconv.FormatInt(id, 10))
+}
+
+func sealedOf(secret *core.Secret) *envelope.Sealed {
+	return &envelope.Sealed{
+		Ciphertext:  secret.Ciphertext,
+		Nonce:       secret.Nonce,
+		WrappedKey:  secret.WrappedKey,
+		MasterKeyID: secret.MasterKeyID,
+	}
+}
+
+func secretEvent(secret *core.Secret, actorID int64, verb string) *activity.Event {
+	metadata := map[string]interface{}{
+		"name": secret.Name,
+	}
+	if secret.ProjectID != nil {
+		metadata["project_id"] = *secret.ProjectID
+	}
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetSecret,
+		TargetID:   secret.ID,
+		Metadata:   metadata,
+	}
+}
@@ -0,0 +1,149 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeys(t *testing.T, ids ...string) envelope.KeyProvider {
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		key := make([]byte, 32)
+		key[0] = byte(i)
+		keys[id] = key
+	}
+	provider, err := envelope.NewLocalKeyProvider(keys, ids[len(ids)-1])
+	assert.NoError(t, err)
+	return provider
+}
+
+func newSecret(s *scope, name string) *core.Secret {
+	return &core.Secret{
+		WorkspaceID: s.WorkspaceID,
+		ProjectID:   s.ProjectID,
+		Name:        name,
+	}
+}
+
+func TestSecretLifecycle(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	keys := testKeys(t, "first")
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	s := &scope{WorkspaceID: workspace.ID}
+
+	secret := newSecret(s, "DATABASE_PASSWORD")
+	err := createSecret(ctx, keys, secret, []byte("rahasia"), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, secret.ID > 0)
+	assert.Equal(t, int64(1), secret.Version)
+
+	value, err := openSecret(keys, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "rahasia", string(value))
+
+	err = createSecret(ctx, keys, newSecret(s, "DATABASE_PASSWORD"), []byte("other"), user.ID)
+	assert.Equal(t, errSecretExists, err)
+
+	updated, err := updateSecretValue(ctx, keys, s, secret.ID, []byte("updated"), user.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, updated) {
+		assert.Equal(t, int64(2), updated.Version)
+		value, err := openSecret(keys, updated)
+		assert.NoError(t, err)
+		assert.Equal(t, "updated", string(value))
+	}
+
+	// secret is not reachable through another scope
+	other := testutil.WorkspaceFactory(ctx, 1)[0]
+	found, err := getSecret(ctx, &scope{WorkspaceID: other.ID}, secret.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	deleted, err := deleteSecret(ctx, s, secret.ID, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	secrets, err := getSecrets(ctx, s)
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 0)
+
+	var activities []struct{ Verb string }
+	err = appctx.Database(ctx).Query(&activities, "select verb from workspace_activity_logs where workspace_id = ? and target_type = 'secret' order by id", workspace.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []struct{ Verb string }{{"created"}, {"updated"}, {"deleted"}}, activities)
+}
+
+func TestSealedValueIsBoundToSecret(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	keys := testKeys(t, "first")
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+	s := &scope{WorkspaceID: workspace.ID}
+
+	source := newSecret(s, "SOURCE")
+	assert.NoError(t, createSecret(ctx, keys, source, []byte("rahasia"), user.ID))
+	target := newSecret(s, "TARGET")
+	assert.NoError(t, createSecret(ctx, keys, target, []byte("other"), user.ID))
+
+	err := db.WriterExec(`
+        update secrets
+        set ciphertext = source.ciphertext, nonce = source.nonce, wrapped_key = source.wrapped_key, master_key_id = source.master_key_id
+        from secrets source
+        where secrets.id = ? and source.id = ?
+    `, target.ID, source.ID)
+	assert.NoError(t, err)
+
+	copied, err := getSecret(ctx, s, target.ID)
+	assert.NoError(t, err)
+	_, err = openSecret(keys, copied)
+	assert.Error(t, err)
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	keys := testKeys(t, "first")
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	var project struct{ ID int64 }
+	err := db.WriterQuery(&project, "insert into projects (name, workspace_id) values ('project', ?) returning id", workspace.ID)
+	assert.NoError(t, err)
+
+	workspaceScope := &scope{WorkspaceID: workspace.ID}
+	projectScope := &scope{WorkspaceID: workspace.ID, ProjectID: &project.ID}
+	projectSecret := newSecret(projectScope, "API_KEY")
+	assert.NoError(t, createSecret(ctx, keys, newSecret(workspaceScope, "API_KEY"), []byte("workspace key"), user.ID))
+	assert.NoError(t, createSecret(ctx, keys, newSecret(workspaceScope, "REGION"), []byte("jakarta"), user.ID))
+	assert.NoError(t, createSecret(ctx, keys, projectSecret, []byte("project key"), user.ID))
+
+	rotatedKeys := testKeys(t, "first", "second")
+	rotated, err := RotateMasterKey(db, rotatedKeys, 2)
+	assert.NoError(t, err)
+	assert.True(t, rotated >= 3)
+
+	var remaining struct{ Count int }
+	err = db.Query(&remaining, "select count(*) as count from secrets where master_key_id <> 'second'")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, remaining.Count)
+
+	// old master key is no longer needed
+	rewrapped, err := getSecret(ctx, projectScope, projectSecret.ID)
+	assert.NoError(t, err)
+	value, err := openSecret(testKeys(t, "unused", "second"), rewrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, "project key", string(value))
+}
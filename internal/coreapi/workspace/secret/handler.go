@@ -0,0 +1,238 @@
+package secret
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/go-chi/chi"
+)
+
+type revealedSecret struct {
+	*core.Secret
+	Value string `json:"value"`
+}
+
+// @Id api.v1.workspace.secret.listAll
+// @Summary List secrets of a workspace, or of a project when project_id is given, without their values
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/secrets [get]
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/secrets [get]
+// @Produce json
+// @Success 200 {array} core.Secret
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	s := currentScope(r)
+
+	secrets, err := getSecrets(r.Context(), s)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, secrets)
+}
+
+// @Id api.v1.workspace.secret.create
+// @Summary Create secret, its value can not be read back except by reveal
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body createSecretParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/secrets [post]
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/secrets [post]
+// @Produce json
+// @Success 201 {object} core.Secret
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(keys envelope.KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := appctx.Actor(r.Context())
+		s := currentScope(r)
+
+		var param createSecretParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		secret := &core.Secret{
+			WorkspaceID:     s.WorkspaceID,
+			ProjectID:       s.ProjectID,
+			Name:            param.Name,
+			CreatedByUserID: &actor.ID,
+		}
+		err := createSecret(r.Context(), keys, secret, []byte(param.Value), actor.ID)
+		if err == errSecretExists {
+			apihelper.ConflictErrResp(w, "conflict", map[string]string{
+				"name": "secret with same name already exists",
+			})
+			return
+		}
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusCreated, secret)
+	}
+}
+
+// @Id api.v1.workspace.secret.update
+// @Summary Replace secret value
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param secret_id path integer true "Secret id"
+// @Param param body updateSecretParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/secrets/{secret_id} [put]
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/secrets/{secret_id} [put]
+// @Produce json
+// @Success 200 {object} core.Secret
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(keys envelope.KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := appctx.Actor(r.Context())
+		secretID, _ := strconv.ParseInt(chi.URLParam(r, "secret_id"), 10, 64)
+		s := currentScope(r)
+
+		var param updateSecretParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		secret, err := updateSecretValue(r.Context(), keys, s, secretID, []byte(param.Value), actor.ID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if secret == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		apihelper.JSON(w, http.StatusOK, secret)
+	}
+}
+
+// @Id api.v1.workspace.secret.delete
+// @Summary Delete secret
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param secret_id path integer true "Secret id"
+// @Router /v1/workspaces/{workspace_id}/secrets/{secret_id} [delete]
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/secrets/{secret_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	actor := appctx.Actor(r.Context())
+	secretID, _ := strconv.ParseInt(chi.URLParam(r, "secret_id"), 10, 64)
+	s := currentScope(r)
+
+	deleted, err := deleteSecret(r.Context(), s, secretID, actor.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspace.secret.reveal
+// @Summary Reveal secret value, every reveal is recorded in workspace activities
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param secret_id path integer true "Secret id"
+// @Router /v1/workspaces/{workspace_id}/secrets/{secret_id}/reveal [post]
+// @Router /v1/workspaces/{workspace_id}/projects/{project_id}/secrets/{secret_id}/reveal [post]
+// @Produce json
+// @Success 200 {object} revealedSecret
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleReveal(keys envelope.KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := appctx.Actor(r.Context())
+		secretID, _ := strconv.ParseInt(chi.URLParam(r, "secret_id"), 10, 64)
+		s := currentScope(r)
+
+		secret, err := getSecret(r.Context(), s, secretID)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+		if secret == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		value, err := openSecret(keys, secret)
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		if err := recordReveal(r.Context(), secret, actor.ID); err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		apihelper.JSON(w, http.StatusOK, revealedSecret{Secret: secret, Value: string(value)})
+	}
+}
+
+// currentScope returns project scope when route is under a project, otherwise workspace scope
+func currentScope(r *http.Request) *scope {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	s := scope{WorkspaceID: currentWorkspace.ID}
+
+	if currentProject := appctx.CurrentProject(r.Context()); currentProject != nil {
+		s.ProjectID = &currentProject.ID
+	}
+	return &s
+}
@@ -0,0 +1,34 @@
+package secret
+
+import (
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// secret names are used as environment variable names by deployments
+var namePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+const maxValueLength = 65536
+
+type createSecretParam struct {
+	Name  string `json:"name" validate:"required"`
+	Value string `json:"value" validate:"required"`
+}
+
+func (p createSecretParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 255), validation.Match(namePattern).Error("must only contain uppercase letters, digits and underscores, and not start with a digit")),
+		validation.Field(&p.Value, validation.Required, validation.Length(1, maxValueLength)),
+	)
+}
+
+type updateSecretParam struct {
+	Value string `json:"value" validate:"required"`
+}
+
+func (p updateSecretParam) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Value, validation.Required, validation.Length(1, maxValueLength)),
+	)
+}
@@ -0,0 +1,120 @@
+package workspace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	slugMinLength = 3
+	slugMaxLength = 63
+	// generated slug base is shorter to leave room for suffix
+	slugBaseMaxLength = 50
+)
+
+var (
+	slugPattern        = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugDigitsPattern  = regexp.MustCompile(`^[0-9]+$`)
+	slugInvalidPattern = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// reservedSlugs can not be used because they are, or may become, our own paths and names
+var reservedSlugs = map[string]bool{
+	"admin":       true,
+	"api":         true,
+	"app":         true,
+	"auth":        true,
+	"awanku":      true,
+	"billing":     true,
+	"console":     true,
+	"dashboard":   true,
+	"docs":        true,
+	"help":        true,
+	"invitations": true,
+	"login":       true,
+	"logout":      true,
+	"new":         true,
+	"oauth":       true,
+	"settings":    true,
+	"signup":      true,
+	"status":      true,
+	"support":     true,
+	"system":      true,
+	"users":       true,
+	"www":         true,
+}
+
+var (
+	errSlugTaken          = errors.New("workspace slug is already taken")
+	errInvalidWorkspaceID = errors.New("workspace id is neither positive number nor slug")
+)
+
+// validateSlug is ozzo validation rule for workspace slug
+func validateSlug(value interface{}) error {
+	slug, _ := value.(string)
+	if slug == "" {
+		return nil
+	}
+	if len(slug) < slugMinLength || len(slug) > slugMaxLength {
+		return fmt.Errorf("the length must be between %d and %d", slugMinLength, slugMaxLength)
+	}
+	if !slugPattern.MatchString(slug) {
+		return errors.New("must only contain lowercase letters, digits and single dashes between them")
+	}
+	if slugDigitsPattern.MatchString(slug) {
+		return errors.New("must not only contain digits")
+	}
+	if reservedSlugs[slug] {
+		return errors.New("is reserved")
+	}
+	return nil
+}
+
+var slugRule = validation.By(validateSlug)
+
+// slugify turns workspace name into slug base, result may still be too short or reserved
+func slugify(name string) string {
+	slug := slugInvalidPattern.ReplaceAllString(strings.ToLower(name), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > slugBaseMaxLength {
+		slug = strings.TrimRight(slug[:slugBaseMaxLength], "-")
+	}
+	return slug
+}
+
+// GenerateSlug returns available slug derived from workspace name
+func GenerateSlug(db querier, name string) (string, error) {
+	base := slugify(name)
+	if base == "" {
+		base = "workspace"
+	}
+
+	for i := 1; i <= 10; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		if validateSlug(candidate) != nil {
+			continue
+		}
+		available, err := slugAvailable(db, candidate, 0)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return candidate, nil
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,28 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "john-doe-s-workspace", slugify("John Doe's workspace"))
+	assert.Equal(t, "acme", slugify("  --Acme--  "))
+	assert.Equal(t, "", slugify("日本"))
+	assert.Len(t, slugify(strings.Repeat("a", 100)), slugBaseMaxLength)
+	assert.Equal(t, strings.Repeat("a", slugBaseMaxLength-1), slugify(strings.Repeat("a", slugBaseMaxLength-1)+" bbb"))
+}
+
+func TestValidateSlug(t *testing.T) {
+	valid := []string{"", "acme", "acme-corp", "team-42", "42-team"}
+	for _, slug := range valid {
+		assert.NoError(t, validateSlug(slug), slug)
+	}
+
+	invalid := []string{"ab", "Acme", "acme_corp", "-acme", "acme-", "acme--corp", "12345", "admin", "new", strings.Repeat("a", 64)}
+	for _, slug := range invalid {
+		assert.Error(t, validateSlug(slug), slug)
+	}
+}
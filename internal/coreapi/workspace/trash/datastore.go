@@ -0,0 +1,418 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/internal/coreapi/workspace/billing"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/awanku/awanku/pkg/core"
+)
+
+var (
+	errProjectDeleted     = errors.New("project of the resource is deleted")
+	errEnvironmentDeleted = errors.New("environment of the resource is deleted")
+	errProjectNameTaken   = errors.New("project with same name already exists")
+	errConnectionExists   = errors.New("repository connection with same identifier already exists")
+	errNotFoundInTrash    = errors.New("item is not in trash")
+)
+
+// restorableSince returns the oldest deletion time which can still be restored
+func restorableSince() time.Time {
+	return time.Now().Add(-core.TrashRetentionDuration)
+}
+
+func getItems(ctx context.Context, workspaceID int64) ([]*core.TrashItem, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select 'project' as type, id, name, null as project_id, deleted_at
+        from projects
+        where workspace_id = ?0 and deleted_at > ?1
+        union all
+        select 'resource' as type, resources.id, resources.name, resources.project_id, resources.deleted_at
+        from resources
+        join projects on projects.id = resources.project_id
+        where projects.workspace_id = ?0 and resources.deleted_at > ?1
+        union all
+        select 'repository_connection' as type, id, identifier as name, null as project_id, deleted_at
+        from workspace_repository_connections
+        where workspace_id = ?0 and deleted_at > ?1
+        order by deleted_at desc, type, id
+    `
+	var items []*core.TrashItem
+	err := db.Query(&items, query, workspaceID, restorableSince())
+	if err != nil {
+		return []*core.TrashItem{}, err
+	}
+	for _, item := range items {
+		item.RestorableUntil = item.DeletedAt.Add(core.TrashRetentionDuration)
+	}
+	if items == nil {
+		items = []*core.TrashItem{}
+	}
+	return items, nil
+}
+
+// restoreProject restores project together with resources deleted with it
+func restoreProject(ctx context.Context, workspaceID, projectID, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// quota reservation locks the workspace, so name check below can not race with project creation
+	err = quota.Reserve(tx, workspaceID, quota.Request{Projects: 1})
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        select id, name
+        from projects
+        where id = ? and workspace_id = ? and deleted_at > ?
+        for update
+    `
+	var project struct {
+		ID   int64
+		Name string
+	}
+	err = tx.Query(&project, query, projectID, workspaceID, restorableSince())
+	if err != nil {
+		return err
+	}
+	if project.ID == 0 {
+		return errNotFoundInTrash
+	}
+
+	var queryNameTaken = `
+        select count(*) as count
+        from projects
+        where workspace_id = ? and lower(name) = lower(?) and deleted_at is null
+    `
+	var taken struct{ Count int }
+	err = tx.Query(&taken, queryNameTaken, workspaceID, project.Name)
+	if err != nil {
+		return err
+	}
+	if taken.Count > 0 {
+		return errProjectNameTaken
+	}
+
+	// resources deleted together with the project share its deletion time
+	var queryResources = `
+        select id
+        from resources
+        where project_id = ?0 and deleted_at = (select deleted_at from projects where id = ?0)
+        order by id
+    `
+	var resources []struct{ ID int64 }
+	err = tx.Query(&resources, queryResources, project.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec("update projects set deleted_at = null, updated_at = now() where id = ?", project.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, resource := range resources {
+		if err = restoreResourceTx(tx, workspaceID, resource.ID, actorID); err != nil {
+			return err
+		}
+	}
+
+	return activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbRestored,
+		TargetType: activity.TargetProject,
+		TargetID:   project.ID,
+		Metadata: map[string]interface{}{
+			"name": project.Name,
+		},
+	})
+}
+
+func restoreResource(ctx context.Context, workspaceID, resourceID, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return restoreResourceTx(tx, workspaceID, resourceID, actorID)
+}
+
+func restoreResourceTx(tx hansip.Transaction, workspaceID, resourceID, actorID int64) error {
+	var query = `
+        select
+            resources.id,
+            resources.name,
+            resources.type,
+            resources.state,
+            resources.project_id,
+            resources.cpu_millicores,
+            resources.memory_mb,
+            resources.deleted_at,
+            projects.deleted_at as project_deleted_at,
+            project_environments.deleted_at as environment_deleted_at
+        from resources
+        join projects on projects.id = resources.project_id
+        left join project_environments on project_environments.id = resources.environment_id
+        where resources.id = ? and projects.workspace_id = ? and resources.deleted_at > ?
+        for update of resources
+    `
+	var resource struct {
+		ID                   int64
+		Name                 string
+		Type                 string
+		State                string
+		ProjectID            int64
+		CPUMillicores        int64 `pg:"cpu_millicores"`
+		MemoryMB             int64 `pg:"memory_mb"`
+		DeletedAt            time.Time
+		ProjectDeletedAt     *time.Time
+		EnvironmentDeletedAt *time.Time
+	}
+	err := tx.Query(&resource, query, resourceID, workspaceID, restorableSince())
+	if err != nil {
+		return err
+	}
+	if resource.ID == 0 {
+		return errNotFoundInTrash
+	}
+	if resource.ProjectDeletedAt != nil {
+		return errProjectDeleted
+	}
+	if resource.EnvironmentDeletedAt != nil {
+		return errEnvironmentDeleted
+	}
+
+	err = quota.Reserve(tx, workspaceID, quota.Request{
+		ResourceType:  resource.Type,
+		Resources:     1,
+		CPUMillicores: resource.CPUMillicores,
+		MemoryMB:      resource.MemoryMB,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = billing.RecordRestore(tx, workspaceID, resource.ID, resource.State, resource.DeletedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec("update resources set deleted_at = null, updated_at = now() where id = ?", resource.ID)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbRestored,
+		TargetType: activity.TargetResource,
+		TargetID:   resource.ID,
+		Metadata: map[string]interface{}{
+			"name":       resource.Name,
+			"type":       resource.Type,
+			"project_id": resource.ProjectID,
+		},
+	})
+}
+
+func restoreRepositoryConnection(ctx context.Context, workspaceID, connectionID, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	err = quota.Reserve(tx, workspaceID, quota.Request{RepositoryConnections: 1})
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        select *
+        from workspace_repository_connections
+        where id = ? and workspace_id = ? and deleted_at > ?
+        for update
+    `
+	var conn core.RepositoryConnection
+	err = tx.Query(&conn, query, connectionID, workspaceID, restorableSince())
+	if err != nil {
+		return err
+	}
+	if conn.ID == 0 {
+		return errNotFoundInTrash
+	}
+
+	var queryExists = `
+        select count(*) as count
+        from workspace_repository_connections
+        where workspace_id = ? and provider = ? and identifier = ? and deleted_at is null
+    `
+	var exists struct{ Count int }
+	err = tx.Query(&exists, queryExists, workspaceID, conn.Provider, conn.Identifier)
+	if err != nil {
+		return err
+	}
+	if exists.Count > 0 {
+		return errConnectionExists
+	}
+
+	err = tx.Exec("update workspace_repository_connections set deleted_at = null, updated_at = now() where id = ?", conn.ID)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, workspaceID, &activity.Event{
+		ActorID:    actorID,
+		Verb:       activity.VerbRestored,
+		TargetType: activity.TargetRepositoryConnection,
+		TargetID:   conn.ID,
+		Metadata: map[string]interface{}{
+			"provider":   conn.Provider,
+			"identifier": conn.Identifier,
+		},
+	})
+}
+
+// PurgeExpiredResources hard deletes resources which stayed in trash longer than restore window.
+// Resource is kept until every workspace it was metered in has been invoiced for the time it was billed,
+// the workspace owning it is billed until deletion while workspaces it was transferred from are billed until their last event.
+func PurgeExpiredResources(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with expired as (
+            select resources.id
+            from resources
+            join projects on projects.id = resources.project_id
+            where
+                resources.deleted_at < ?
+                and not exists (
+                    select 1
+                    from resource_metering_events
+                    where
+                        resource_metering_events.resource_id = resources.id
+                        and not exists (
+                            select 1
+                            from invoices
+                            where
+                                invoices.workspace_id = resource_metering_events.workspace_id
+                                and invoices.period_end > (
+                                    case
+                                        when resource_metering_events.workspace_id = projects.workspace_id then resources.deleted_at
+                                        else resource_metering_events.occurred_at
+                                    end at time zone 'UTC'
+                                )::date
+                        )
+                )
+            limit ?
+        ),
+        logs as (
+            delete from resource_logs
+            where resource_id in (select id from expired)
+        ),
+        -- only metering events of invoiced periods are left here
+        metering as (
+            delete from resource_metering_events
+            where resource_id in (select id from expired)
+        ),
+        purged as (
+            delete from resources
+            where id in (select id from expired)
+            returning 1
+        )
+        select count(*) as count from purged
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, restorableSince(), batchSize)
+	return returned.Count, err
+}
+
+// PurgeExpiredProjects hard deletes projects which stayed in trash longer than restore window
+// and have no resources left, resources of the project are purged first
+func PurgeExpiredProjects(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with expired as (
+            select id
+            from projects
+            where
+                deleted_at < ?
+                and not exists (select 1 from resources where resources.project_id = projects.id)
+            limit ?
+        ),
+        secrets as (
+            delete from secrets
+            where project_id in (select id from expired)
+        ),
+        users as (
+            delete from project_users
+            where project_id in (select id from expired)
+        ),
+        environments as (
+            delete from project_environments
+            where project_id in (select id from expired)
+        ),
+        purged as (
+            delete from projects
+            where id in (select id from expired)
+            returning 1
+        )
+        select count(*) as count from purged
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, restorableSince(), batchSize)
+	return returned.Count, err
+}
+
+// PurgeExpiredRepositoryConnections hard deletes repository connections which stayed in trash longer than restore window
+func PurgeExpiredRepositoryConnections(ctx context.Context, batchSize int) (int64, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        with purged as (
+            delete from workspace_repository_connections
+            where id in (
+                select id
+                from workspace_repository_connections
+                where deleted_at < ?
+                limit ?
+            )
+            returning 1
+        )
+        select count(*) as count from purged
+    `
+	var returned struct{ Count int64 }
+	err := db.WriterQuery(&returned, query, restorableSince(), batchSize)
+	return returned.Count, err
+}
@@ -0,0 +1,153 @@
+package trash
+
+import (
+	"testing"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreProjectWithItsResources(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	var project struct{ ID int64 }
+	err := db.WriterQuery(&project, "insert into projects (name, workspace_id, deleted_at) values ('web', ?, now()) returning id", workspace.ID)
+	assert.NoError(t, err)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeProduction)
+	var resource struct{ ID int64 }
+	err = db.WriterQuery(&resource, `
+        insert into resources (name, type, payload, project_id, environment_id, deleted_at)
+        values ('db', 'postgres', '{}', ?0, ?1, (select deleted_at from projects where id = ?0))
+        returning id
+    `, project.ID, environment.ID)
+	assert.NoError(t, err)
+
+	items, err := getItems(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	// resource can not be restored into deleted project
+	err = restoreResource(ctx, workspace.ID, resource.ID, user.ID)
+	assert.Equal(t, errProjectDeleted, err)
+
+	err = restoreProject(ctx, workspace.ID, project.ID, user.ID)
+	assert.NoError(t, err)
+
+	items, err = getItems(ctx, workspace.ID)
+	assert.NoError(t, err)
+	assert.Len(t, items, 0)
+
+	err = restoreProject(ctx, workspace.ID, project.ID, user.ID)
+	assert.Equal(t, errNotFoundInTrash, err)
+}
+
+func TestRestoreRespectsWindowAndConflicts(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	user := testutil.UserFactory(ctx, 1)[0]
+
+	var expired struct{ ID int64 }
+	err := db.WriterQuery(&expired, "insert into projects (name, workspace_id, deleted_at) values ('old', ?, now() - interval '31 days') returning id", workspace.ID)
+	assert.NoError(t, err)
+	err = restoreProject(ctx, workspace.ID, expired.ID, user.ID)
+	assert.Equal(t, errNotFoundInTrash, err)
+
+	var deleted struct{ ID int64 }
+	err = db.WriterQuery(&deleted, "insert into projects (name, workspace_id, deleted_at) values ('api', ?, now()) returning id", workspace.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec("insert into projects (name, workspace_id) values ('api', ?)", workspace.ID)
+	assert.NoError(t, err)
+	err = restoreProject(ctx, workspace.ID, deleted.ID, user.ID)
+	assert.Equal(t, errProjectNameTaken, err)
+
+	var conn core.RepositoryConnection
+	err = db.WriterQuery(&conn, `
+        insert into workspace_repository_connections (workspace_id, identifier, provider, payload, deleted_at)
+        values (?, 'awanku', 'github-v1', '{}', now())
+        returning *
+    `, workspace.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into workspace_repository_connections (workspace_id, identifier, provider, payload)
+        values (?, 'awanku', 'github-v1', '{}')
+    `, workspace.ID)
+	assert.NoError(t, err)
+	err = restoreRepositoryConnection(ctx, workspace.ID, conn.ID, user.ID)
+	assert.Equal(t, errConnectionExists, err)
+
+	// trash of another workspace is not reachable
+	other := testutil.WorkspaceFactory(ctx, 1)[0]
+	err = restoreRepositoryConnection(ctx, other.ID, conn.ID, user.ID)
+	assert.Equal(t, errNotFoundInTrash, err)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	db := appctx.Database(ctx)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+
+	var project struct{ ID int64 }
+	err := db.WriterQuery(&project, "insert into projects (name, workspace_id, deleted_at) values ('old', ?, now() - interval '31 days') returning id", workspace.ID)
+	assert.NoError(t, err)
+	environment := testutil.ProjectEnvironmentFactory(ctx, project.ID, core.ProjectEnvironmentTypeProduction)
+	err = db.WriterExec("insert into resources (name, type, payload, project_id, environment_id, deleted_at) values ('db', 'postgres', '{}', ?, ?, now() - interval '31 days')", project.ID, environment.ID)
+	assert.NoError(t, err)
+
+	var resource struct{ ID int64 }
+	err = db.WriterQuery(&resource, "insert into resources (name, type, payload, project_id, environment_id, deleted_at) values ('web', 'application', '{}', ?, ?, now() - interval '31 days') returning id", project.ID, environment.ID)
+	assert.NoError(t, err)
+	err = db.WriterExec(`
+        insert into resource_metering_events (resource_id, workspace_id, previous_state, state, occurred_at)
+        values (?, ?, 'provisioning', 'provisioning_success', now() - interval '40 days')
+    `, resource.ID, workspace.ID)
+	assert.NoError(t, err)
+
+	// project waits until its resources are purged
+	_, err = PurgeExpiredProjects(ctx, 1000)
+	assert.NoError(t, err)
+	var count struct{ Count int }
+	err = db.Query(&count, "select count(*) as count from projects where id = ?", project.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count.Count)
+
+	// metered resource waits until the month it was deleted in is invoiced
+	purged, err := PurgeExpiredResources(ctx, 1000)
+	assert.NoError(t, err)
+	assert.True(t, purged >= 1)
+	err = db.Query(&count, "select count(*) as count from resource_metering_events where resource_id = ?", resource.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count.Count)
+
+	err = db.WriterExec(`
+        insert into invoices (workspace_id, number, period_start, period_end, plan, currency, line_items, total)
+        select ?0, 'TEST-' || ?0, date_trunc('month', deleted_at at time zone 'UTC'), date_trunc('month', deleted_at at time zone 'UTC') + interval '1 month', 'free', 'IDR', '[]', 0
+        from resources
+        where id = ?1
+    `, workspace.ID, resource.ID)
+	assert.NoError(t, err)
+	purged, err = PurgeExpiredResources(ctx, 1000)
+	assert.NoError(t, err)
+	assert.True(t, purged >= 1)
+	err = db.Query(&count, "select count(*) as count from resources where id = ?", resource.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count.Count)
+	purged, err = PurgeExpiredProjects(ctx, 1000)
+	assert.NoError(t, err)
+	assert.True(t, purged >= 1)
+
+	err = db.Query(&count, "select count(*) as count from projects where id = ?", project.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count.Count)
+}
@@ -0,0 +1,132 @@
+package trash
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/internal/coreapi/workspace/quota"
+	"github.com/go-chi/chi"
+)
+
+// @Id api.v1.workspace.trash.listAll
+// @Summary List deleted projects, resources and repository connections which can still be restored
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/trash [get]
+// @Produce json
+// @Success 200 {array} core.TrashItem
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	items, err := getItems(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, items)
+}
+
+// @Id api.v1.workspace.trash.restoreProject
+// @Summary Restore deleted project together with resources deleted with it
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param project_id path integer true "Project id"
+// @Router /v1/workspaces/{workspace_id}/trash/projects/{project_id}/restore [post]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleRestoreProject(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	projectID, _ := strconv.ParseInt(chi.URLParam(r, "project_id"), 10, 64)
+
+	err := restoreProject(r.Context(), currentWorkspace.ID, projectID, appctx.Actor(r.Context()).ID)
+	restoreResp(w, err)
+}
+
+// @Id api.v1.workspace.trash.restoreResource
+// @Summary Restore deleted resource, its project must not be deleted
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param resource_id path integer true "Resource id"
+// @Router /v1/workspaces/{workspace_id}/trash/resources/{resource_id}/restore [post]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleRestoreResource(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	resourceID, _ := strconv.ParseInt(chi.URLParam(r, "resource_id"), 10, 64)
+
+	err := restoreResource(r.Context(), currentWorkspace.ID, resourceID, appctx.Actor(r.Context()).ID)
+	restoreResp(w, err)
+}
+
+// @Id api.v1.workspace.trash.restoreRepositoryConnection
+// @Summary Restore deleted repository connection
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param connection_id path integer true "Repository connection id"
+// @Router /v1/workspaces/{workspace_id}/trash/repository-connections/{connection_id}/restore [post]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 409 {object} apihelper.HTTPError
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleRestoreRepositoryConnection(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	connectionID, _ := strconv.ParseInt(chi.URLParam(r, "connection_id"), 10, 64)
+
+	err := restoreRepositoryConnection(r.Context(), currentWorkspace.ID, connectionID, appctx.Actor(r.Context()).ID)
+	restoreResp(w, err)
+}
+
+func restoreResp(w http.ResponseWriter, err error) {
+	var exceeded *quota.ExceededError
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case err == errNotFoundInTrash:
+		w.WriteHeader(http.StatusNotFound)
+	case err == errProjectDeleted:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"project": "project is deleted, restore the project first",
+		})
+	case err == errEnvironmentDeleted:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"environment": "environment of the resource is deleted",
+		})
+	case err == errProjectNameTaken:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"name": "project with same name already exists",
+		})
+	case err == errConnectionExists:
+		apihelper.ConflictErrResp(w, "conflict", map[string]string{
+			"identifier": "repository connection with same identifier already exists",
+		})
+	case errors.As(err, &exceeded):
+		apihelper.ForbiddenErrResp(w, "quota_exceeded", exceeded.Details())
+	default:
+		apihelper.InternalServerErrResp(w, err)
+	}
+}
@@ -0,0 +1,464 @@
+package webhook
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/workspace/activity"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/go-pg/pg/v9"
+)
+
+// deliveryLease is how long claimed delivery is hidden from other workers while it is being sent
+const deliveryLease = 5 * time.Minute
+
+func getWebhooks(ctx context.Context, workspaceID int64) ([]*core.Webhook, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhooks
+        where workspace_id = ? and deleted_at is null
+        order by id
+    `
+	var webhooks []*core.Webhook
+	err := db.Query(&webhooks, query, workspaceID)
+	if err != nil {
+		return []*core.Webhook{}, err
+	}
+	if webhooks == nil {
+		webhooks = []*core.Webhook{}
+	}
+	return webhooks, nil
+}
+
+func getWebhook(ctx context.Context, workspaceID, id int64) (*core.Webhook, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhooks
+        where id = ? and workspace_id = ? and deleted_at is null
+    `
+	var webhook core.Webhook
+	err := db.Query(&webhook, query, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.ID == 0 {
+		return nil, nil
+	}
+	return &webhook, nil
+}
+
+// createWebhook seals signing secret bound to id of the new webhook, id is taken from sequence before the row is inserted
+func createWebhook(ctx context.Context, keys envelope.KeyProvider, webhook *core.Webhook, secret []byte, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryID = `
+        select nextval(pg_get_serial_sequence('webhooks', 'id')) as id
+    `
+	var reserved struct {
+		ID int64
+	}
+	err = tx.Query(&reserved, queryID)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := envelope.Seal(keys, secret, webhookAAD(reserved.ID))
+	if err != nil {
+		return err
+	}
+
+	var query = `
+        insert into webhooks (id, workspace_id, url, event_types, active, secret_ciphertext, secret_nonce, secret_wrapped_key, secret_master_key_id, created_by_user_id, created_at)
+        values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(webhook, query, reserved.ID, webhook.WorkspaceID, webhook.URL, pg.Array(webhook.EventTypes), webhook.Active, sealed.Ciphertext, sealed.Nonce, sealed.WrappedKey, sealed.MasterKeyID, webhook.CreatedByUserID)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, webhook.WorkspaceID, webhookEvent(webhook, actorID, activity.VerbCreated))
+}
+
+func updateWebhook(ctx context.Context, webhook *core.Webhook, actorID int64) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update webhooks
+        set url = ?, event_types = ?, active = ?, updated_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	err = tx.Query(webhook, query, webhook.URL, pg.Array(webhook.EventTypes), webhook.Active, webhook.ID, webhook.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	return activity.Record(tx, webhook.WorkspaceID, webhookEvent(webhook, actorID, activity.VerbUpdated))
+}
+
+// deleteWebhook returns false when webhook does not exist, its pending deliveries are no longer sent
+func deleteWebhook(ctx context.Context, workspaceID, id, actorID int64) (deleted bool, err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        update webhooks
+        set deleted_at = now()
+        where id = ? and workspace_id = ? and deleted_at is null
+        returning *
+    `
+	var webhook core.Webhook
+	err = tx.Query(&webhook, query, id, workspaceID)
+	if err != nil {
+		return false, err
+	}
+	if webhook.ID == 0 {
+		return false, nil
+	}
+
+	var queryDeliveries = `
+        update webhook_deliveries
+        set status = 'failed', next_attempt_at = null, updated_at = now()
+        where webhook_id = ? and status = 'pending'
+    `
+	err = tx.Exec(queryDeliveries, webhook.ID)
+	if err != nil {
+		return false, err
+	}
+
+	err = activity.Record(tx, workspaceID, webhookEvent(&webhook, actorID, activity.VerbDeleted))
+	return err == nil, err
+}
+
+func getDeliveries(ctx context.Context, webhookID int64, limit int) ([]*core.WebhookDelivery, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhook_deliveries
+        where webhook_id = ?
+        order by id desc
+        limit ?
+    `
+	var deliveries []*core.WebhookDelivery
+	err := db.Query(&deliveries, query, webhookID, limit)
+	if err != nil {
+		return []*core.WebhookDelivery{}, err
+	}
+	if deliveries == nil {
+		deliveries = []*core.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+func getDelivery(ctx context.Context, webhookID, id int64) (*core.WebhookDelivery, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhook_deliveries
+        where id = ? and webhook_id = ?
+    `
+	var delivery core.WebhookDelivery
+	err := db.Query(&delivery, query, id, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.ID == 0 {
+		return nil, nil
+	}
+	return &delivery, nil
+}
+
+func getDeliveryAttempts(ctx context.Context, deliveryID int64) ([]*core.WebhookDeliveryAttempt, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhook_delivery_attempts
+        where delivery_id = ?
+        order by id
+    `
+	var attempts []*core.WebhookDeliveryAttempt
+	err := db.Query(&attempts, query, deliveryID)
+	if err != nil {
+		return []*core.WebhookDeliveryAttempt{}, err
+	}
+	if attempts == nil {
+		attempts = []*core.WebhookDeliveryAttempt{}
+	}
+	return attempts, nil
+}
+
+// redeliver queues a new delivery with the same event, returns nil when delivery does not exist
+func redeliver(ctx context.Context, webhookID, deliveryID int64) (*core.WebhookDelivery, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        insert into webhook_deliveries (webhook_id, event_type, payload, next_attempt_at, redelivery_of, created_at)
+        select webhook_id, event_type, payload, now(), id, now()
+        from webhook_deliveries
+        where id = ? and webhook_id = ?
+        returning *
+    `
+	var delivery core.WebhookDelivery
+	err := db.WriterQuery(&delivery, query, deliveryID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.ID == 0 {
+		return nil, nil
+	}
+	return &delivery, nil
+}
+
+// claimDueDeliveries leases pending deliveries whose next attempt is due,
+// so concurrent workers do not send the same delivery twice
+func claimDueDeliveries(ctx context.Context, limit int) ([]*core.WebhookDelivery, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        update webhook_deliveries
+        set next_attempt_at = now() + make_interval(secs => ?)
+        where id in (
+            select webhook_deliveries.id
+            from webhook_deliveries
+            join webhooks on webhooks.id = webhook_deliveries.webhook_id
+            where
+                webhook_deliveries.status = 'pending'
+                and webhook_deliveries.next_attempt_at <= now()
+                and webhooks.active
+                and webhooks.deleted_at is null
+            order by webhook_deliveries.next_attempt_at
+            limit ?
+            for update of webhook_deliveries skip locked
+        )
+        returning *
+    `
+	var deliveries []*core.WebhookDelivery
+	err := db.WriterQuery(&deliveries, query, deliveryLease.Seconds(), limit)
+	return deliveries, err
+}
+
+// getDeliveryWebhook returns webhook of delivery including its encrypted secret, deleted webhook is returned as nil
+func getDeliveryWebhook(ctx context.Context, webhookID int64) (*core.Webhook, error) {
+	db := appctx.Database(ctx)
+
+	var query = `
+        select *
+        from webhooks
+        where id = ? and deleted_at is null
+    `
+	var webhook core.Webhook
+	err := db.Query(&webhook, query, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.ID == 0 {
+		return nil, nil
+	}
+	return &webhook, nil
+}
+
+// recordAttempt stores attempt result, delivery is retried with backoff until it succeeds or runs out of attempts
+func recordAttempt(ctx context.Context, delivery *core.WebhookDelivery, attempt *core.WebhookDeliveryAttempt) (err error) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var queryAttempt = `
+        insert into webhook_delivery_attempts (delivery_id, response_status, response_body, error, duration_ms, created_at)
+        values (?, ?, ?, ?, ?, now())
+        returning *
+    `
+	err = tx.Query(attempt, queryAttempt, delivery.ID, attempt.ResponseStatus, attempt.ResponseBody, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	attempts := delivery.Attempts + 1
+	status := core.WebhookDeliveryStatusPending
+	nextAttemptAt := time.Now().Add(retryWait(attempts))
+	next := &nextAttemptAt
+	switch {
+	case attempt.ResponseStatus != nil && *attempt.ResponseStatus >= 200 && *attempt.ResponseStatus < 300:
+		status = core.WebhookDeliveryStatusSucceeded
+		next = nil
+	case attempts >= maxAttempts:
+		status = core.WebhookDeliveryStatusFailed
+		next = nil
+	}
+
+	var queryDelivery = `
+        update webhook_deliveries
+        set
+            status = ?,
+            attempts = ?,
+            next_attempt_at = ?,
+            last_attempt_at = now(),
+            last_response_status = ?,
+            updated_at = now()
+        where id = ?
+        returning *
+    `
+	return tx.Query(delivery, queryDelivery, status, attempts, next, attempt.ResponseStatus, delivery.ID)
+}
+
+// PurgeOldDeliveries removes finished deliveries older than retention together with their attempts
+func PurgeOldDeliveries(retention time.Duration) func(ctx context.Context, batchSize int) (int64, error) {
+	return func(ctx context.Context, batchSize int) (int64, error) {
+		db := appctx.Database(ctx)
+
+		var query = `
+            with deleted as (
+                delete from webhook_deliveries
+                where id in (
+                    select id
+                    from webhook_deliveries
+                    where status <> 'pending' and created_at < now() - make_interval(secs => ?)
+                    limit ?
+                )
+                returning 1
+            )
+            select count(*) as count from deleted
+        `
+		var returned struct{ Count int64 }
+		err := db.WriterQuery(&returned, query, retention.Seconds(), batchSize)
+		return returned.Count, err
+	}
+}
+
+// RotateMasterKey rewraps data keys of webhook signing secrets with current master key.
+// It returns number of rewrapped webhooks.
+func RotateMasterKey(db *hansip.Cluster, keys envelope.KeyProvider, batchSize int) (int, error) {
+	var rotated int
+	for {
+		count, err := rotateBatch(db, keys, batchSize)
+		rotated += count
+		if err != nil || count == 0 {
+			return rotated, err
+		}
+	}
+}
+
+func rotateBatch(db *hansip.Cluster, keys envelope.KeyProvider, batchSize int) (count int, err error) {
+	tx, err := db.NewTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var query = `
+        select *
+        from webhooks
+        where secret_master_key_id <> ?
+        order by id
+        limit ?
+        for update skip locked
+    `
+	var webhooks []*core.Webhook
+	err = tx.Query(&webhooks, query, keys.CurrentKeyID(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var queryUpdate = `
+        update webhooks
+        set secret_wrapped_key = ?, secret_master_key_id = ?
+        where id = ?
+    `
+	for _, webhook := range webhooks {
+		sealed := sealedSecretOf(webhook)
+		if _, err = envelope.Rewrap(keys, sealed); err != nil {
+			return 0, err
+		}
+		if err = tx.Exec(queryUpdate, sealed.WrappedKey, sealed.MasterKeyID, webhook.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(webhooks), nil
+}
+
+// openSecret decrypts signing secret of webhook
+func openSecret(keys envelope.KeyProvider, webhook *core.Webhook) ([]byte, error) {
+	return envelope.Open(keys, sealedSecretOf(webhook), webhookAAD(webhook.ID))
+}
+
+// webhookAAD binds sealed signing secret to its webhook so it can not be opened when copied to another row
+func webhookAAD(id int64) []byte {
+	return []byte("webhooks:" + strconv.FormatInt(id, 10))
+}
+
+func sealedSecretOf(webhook *core.Webhook) *envelope.Sealed {
+	return &envelope.Sealed{
+		Ciphertext:  webhook.SecretCiphertext,
+		Nonce:       webhook.SecretNonce,
+		WrappedKey:  webhook.SecretWrappedKey,
+		MasterKeyID: webhook.SecretMasterKeyID,
+	}
+}
+
+func webhookEvent(webhook *core.Webhook, actorID int64, verb string) *activity.Event {
+	return &activity.Event{
+		ActorID:    actorID,
+		Verb:       verb,
+		TargetType: activity.TargetWebhook,
+		TargetID:   webhook.ID,
+		Metadata: map[string]interface{}{
+			"url":         webhook.URL,
+			"event_types": webhook.EventTypes,
+		},
+	}
+}
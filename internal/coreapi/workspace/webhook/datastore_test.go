@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/awanku/awanku/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeys(t *testing.T) envelope.KeyProvider {
+	provider, err := envelope.NewLocalKeyProvider(map[string][]byte{"first": make([]byte, 32)}, "first")
+	assert.NoError(t, err)
+	return provider
+}
+
+func newWebhook(ctx context.Context, t *testing.T, keys envelope.KeyProvider, workspaceID int64, url string, eventTypes ...string) (*core.Webhook, string) {
+	secret, err := generateSecret()
+	assert.NoError(t, err)
+
+	webhook := &core.Webhook{
+		WorkspaceID: workspaceID,
+		URL:         url,
+		EventTypes:  eventTypes,
+		Active:      true,
+	}
+	assert.NoError(t, createWebhook(ctx, keys, webhook, []byte(secret), 0))
+	return webhook, secret
+}
+
+func enqueue(ctx context.Context, t *testing.T, workspaceID int64, eventType string) {
+	tx, err := appctx.Database(ctx).NewTransaction()
+	assert.NoError(t, err)
+	assert.NoError(t, Enqueue(tx, workspaceID, eventType, map[string]interface{}{"user_id": 1}))
+	assert.NoError(t, tx.Commit())
+}
+
+func TestEnqueueOnlySubscribedWebhooks(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	keys := testKeys(t)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	subscribed, _ := newWebhook(ctx, t, keys, workspace.ID, "https://example.com/a", core.WebhookEventMemberJoined)
+	other, _ := newWebhook(ctx, t, keys, workspace.ID, "https://example.com/b", core.WebhookEventMemberRemoved)
+
+	enqueue(ctx, t, workspace.ID, core.WebhookEventMemberJoined)
+
+	deliveries, err := getDeliveries(ctx, subscribed.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, core.WebhookDeliveryStatusPending, deliveries[0].Status)
+		assert.Equal(t, core.WebhookEventMemberJoined, deliveries[0].Payload["event"])
+	}
+
+	deliveries, err = getDeliveries(ctx, other.ID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 0)
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	var received *http.Request
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	keys := testKeys(t)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	webhook, secret := newWebhook(ctx, t, keys, workspace.ID, receiver.URL, core.WebhookEventMemberJoined)
+	enqueue(ctx, t, workspace.ID, core.WebhookEventMemberJoined)
+
+	deliveries, err := getDeliveries(ctx, webhook.ID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	worker := NewWorker(nil, keys, time.Second)
+	// receiver listens on loopback which default client refuses
+	worker.client = receiver.Client()
+	assert.NoError(t, worker.deliver(ctx, deliveries[0]))
+
+	if assert.NotNil(t, received) {
+		timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign([]byte(secret), timestamp, receivedBody), received.Header.Get(HeaderSignature))
+		assert.Equal(t, core.WebhookEventMemberJoined, received.Header.Get(HeaderEvent))
+	}
+
+	deliveries, err = getDeliveries(ctx, webhook.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, core.WebhookDeliveryStatusSucceeded, deliveries[0].Status)
+		assert.Equal(t, int64(1), deliveries[0].Attempts)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+
+		attempts, err := getDeliveryAttempts(ctx, deliveries[0].ID)
+		assert.NoError(t, err)
+		if assert.Len(t, attempts, 1) {
+			assert.Equal(t, "ok", *attempts[0].ResponseBody)
+		}
+	}
+}
+
+func TestDeliverRetriesFailures(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	keys := testKeys(t)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	webhook, _ := newWebhook(ctx, t, keys, workspace.ID, receiver.URL, core.WebhookEventMemberRemoved)
+	enqueue(ctx, t, workspace.ID, core.WebhookEventMemberRemoved)
+
+	deliveries, err := getDeliveries(ctx, webhook.ID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+
+	worker := NewWorker(nil, keys, time.Second)
+	worker.client = receiver.Client()
+	assert.NoError(t, worker.deliver(ctx, delivery))
+	assert.Equal(t, core.WebhookDeliveryStatusPending, delivery.Status)
+	assert.Equal(t, int64(1), delivery.Attempts)
+	if assert.NotNil(t, delivery.NextAttemptAt) {
+		assert.True(t, delivery.NextAttemptAt.After(time.Now()))
+	}
+
+	for delivery.Attempts < maxAttempts {
+		assert.NoError(t, worker.deliver(ctx, delivery))
+	}
+	assert.Equal(t, core.WebhookDeliveryStatusFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	redelivered, err := redeliver(ctx, webhook.ID, delivery.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, redelivered) {
+		assert.Equal(t, core.WebhookDeliveryStatusPending, redelivered.Status)
+		assert.Equal(t, int64(0), redelivered.Attempts)
+		assert.Equal(t, delivery.ID, *redelivered.RedeliveryOf)
+	}
+}
+
+func TestDeliverRefusesInternalAddress(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	keys := testKeys(t)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	webhook, _ := newWebhook(ctx, t, keys, workspace.ID, receiver.URL, core.WebhookEventMemberJoined)
+	enqueue(ctx, t, workspace.ID, core.WebhookEventMemberJoined)
+
+	deliveries, err := getDeliveries(ctx, webhook.ID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	worker := NewWorker(nil, keys, time.Second)
+	assert.NoError(t, worker.deliver(ctx, deliveries[0]))
+	assert.False(t, called)
+
+	attempts, err := getDeliveryAttempts(ctx, deliveries[0].ID)
+	assert.NoError(t, err)
+	if assert.Len(t, attempts, 1) {
+		assert.Contains(t, *attempts[0].Error, errInternalAddress.Error())
+	}
+}
+
+func TestDeleteWebhookFailsPendingDeliveries(t *testing.T) {
+	ctx, close := testutil.Context()
+	defer close()
+
+	keys := testKeys(t)
+	workspace := testutil.WorkspaceFactory(ctx, 1)[0]
+	webhook, _ := newWebhook(ctx, t, keys, workspace.ID, "https://example.com/a", core.WebhookEventMemberJoined)
+	enqueue(ctx, t, workspace.ID, core.WebhookEventMemberJoined)
+
+	deleted, err := deleteWebhook(ctx, workspace.ID, webhook.ID, 0)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	found, err := getWebhook(ctx, workspace.ID, webhook.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	deliveries, err := getDeliveries(ctx, webhook.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, core.WebhookDeliveryStatusFailed, deliveries[0].Status)
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const resolveTimeout = 5 * time.Second
+
+var errInternalAddress = errors.New("webhook endpoint resolves to internal address")
+
+// lookupIPAddr resolves endpoint host, tests replace it to avoid depending on DNS
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// internalNetworks are not covered by net.IP helpers available in our Go version
+var internalNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"fc00::/7",
+	}
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}()
+
+// isPublicIP reports whether webhook deliveries may connect to ip,
+// loopback, link-local, private and unspecified addresses point into our own network
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvePublicIPs resolves host and fails when any of its addresses is not public
+func resolvePublicIPs(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return nil, errInternalAddress
+		}
+	}
+	return addrs, nil
+}
+
+// newDeliveryClient returns client which only connects to public addresses and does not follow redirects.
+// Host is resolved again when connecting and the checked address is dialed,
+// so DNS changed after the webhook was saved can not point deliveries into our network.
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{Timeout: deliveryTimeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			addrs, err := resolvePublicIPs(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			if len(addrs) == 0 {
+				return nil, errors.New("webhook endpoint host has no address")
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].IP.String(), port))
+		},
+		TLSHandshakeTimeout:   deliveryTimeout,
+		ResponseHeaderTimeout: deliveryTimeout,
+	}
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		// redirect is recorded as response of the attempt instead of being followed to another host
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,344 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/internal/coreapi/utils/apihelper"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 100
+)
+
+type createdWebhook struct {
+	*core.Webhook
+	// Secret is used to verify delivery signature, it is only returned once
+	Secret string `json:"secret"`
+}
+
+type deliveryDetail struct {
+	*core.WebhookDelivery
+	Attempts []*core.WebhookDeliveryAttempt `json:"attempts"`
+}
+
+// @Id api.v1.workspace.webhook.listAll
+// @Summary List webhooks of a workspace
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Router /v1/workspaces/{workspace_id}/webhooks [get]
+// @Produce json
+// @Success 200 {array} core.Webhook
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListAll(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+
+	webhooks, err := getWebhooks(r.Context(), currentWorkspace.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, webhooks)
+}
+
+// @Id api.v1.workspace.webhook.create
+// @Summary Register webhook endpoint, response contains signing secret which is not shown again
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param param body saveWebhookParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/webhooks [post]
+// @Produce json
+// @Success 201 {object} createdWebhook
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleCreate(keys envelope.KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		currentWorkspace := appctx.CurrentWorkspace(r.Context())
+		actor := appctx.Actor(r.Context())
+
+		var param saveWebhookParam
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+				"request_body": "malformed format",
+			})
+			return
+		}
+		if err := param.Validate(); err != nil {
+			apihelper.ValidationErrResp(w, err)
+			return
+		}
+
+		secret, err := generateSecret()
+		if err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		webhook := &core.Webhook{
+			WorkspaceID:     currentWorkspace.ID,
+			URL:             param.URL,
+			EventTypes:      param.EventTypes,
+			Active:          param.active(),
+			CreatedByUserID: &actor.ID,
+		}
+		if err := createWebhook(r.Context(), keys, webhook, []byte(secret), actor.ID); err != nil {
+			apihelper.InternalServerErrResp(w, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		apihelper.JSON(w, http.StatusCreated, createdWebhook{Webhook: webhook, Secret: secret})
+	}
+}
+
+// @Id api.v1.workspace.webhook.get
+// @Summary Get webhook
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id} [get]
+// @Produce json
+// @Success 200 {object} core.Webhook
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGet(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := currentWebhook(w, r)
+	if !ok {
+		return
+	}
+	apihelper.JSON(w, http.StatusOK, webhook)
+}
+
+// @Id api.v1.workspace.webhook.update
+// @Summary Change webhook url, subscribed event types or active state
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Accept json
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Param param body saveWebhookParam true "Request body"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id} [patch]
+// @Produce json
+// @Success 200 {object} core.Webhook
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	actor := appctx.Actor(r.Context())
+
+	webhook, ok := currentWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var param saveWebhookParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		apihelper.BadRequestErrResp(w, "invalid_request", map[string]string{
+			"request_body": "malformed format",
+		})
+		return
+	}
+	if err := param.Validate(); err != nil {
+		apihelper.ValidationErrResp(w, err)
+		return
+	}
+
+	webhook.URL = param.URL
+	webhook.EventTypes = param.EventTypes
+	webhook.Active = param.active()
+	if err := updateWebhook(r.Context(), webhook, actor.ID); err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, webhook)
+}
+
+// @Id api.v1.workspace.webhook.delete
+// @Summary Delete webhook, its pending deliveries are cancelled
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id} [delete]
+// @Success 204
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleDelete(w http.ResponseWriter, r *http.Request) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	actor := appctx.Actor(r.Context())
+	webhookID, _ := strconv.ParseInt(chi.URLParam(r, "webhook_id"), 10, 64)
+
+	deleted, err := deleteWebhook(r.Context(), currentWorkspace.ID, webhookID, actor.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if !deleted {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Id api.v1.workspace.webhook.delivery.listAll
+// @Summary List recent deliveries of a webhook, newest first
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Param limit query integer false "Number of deliveries, 50 by default, at most 100"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries [get]
+// @Produce json
+// @Success 200 {array} core.WebhookDelivery
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := currentWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDeliveriesLimit {
+			apihelper.ValidationErrResp(w, map[string]string{
+				"limit": "must be a number between 1 and 100",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := getDeliveries(r.Context(), webhook.ID, limit)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, deliveries)
+}
+
+// @Id api.v1.workspace.webhook.delivery.get
+// @Summary Get webhook delivery together with its attempts and response codes
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Param delivery_id path integer true "Delivery id"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries/{delivery_id} [get]
+// @Produce json
+// @Success 200 {object} deliveryDetail
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := currentWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, _ := strconv.ParseInt(chi.URLParam(r, "delivery_id"), 10, 64)
+
+	delivery, err := getDelivery(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if delivery == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	attempts, err := getDeliveryAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusOK, deliveryDetail{WebhookDelivery: delivery, Attempts: attempts})
+}
+
+// @Id api.v1.workspace.webhook.delivery.redeliver
+// @Summary Queue the same event again as a new delivery
+// @Tags Workspace
+// @Security oauthAccessToken
+// @Param workspace_id path string true "Workspace id or slug"
+// @Param webhook_id path integer true "Webhook id"
+// @Param delivery_id path integer true "Delivery id"
+// @Router /v1/workspaces/{workspace_id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver [post]
+// @Produce json
+// @Success 202 {object} core.WebhookDelivery
+// @Failure 400 {object} apihelper.HTTPError
+// @Failure 401 {object} apihelper.HTTPError
+// @Failure 403 {object} apihelper.HTTPError
+// @Failure 404
+// @Failure 500 {object} apihelper.InternalServerError
+func HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := currentWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, _ := strconv.ParseInt(chi.URLParam(r, "delivery_id"), 10, 64)
+
+	delivery, err := redeliver(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return
+	}
+	if delivery == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	apihelper.JSON(w, http.StatusAccepted, delivery)
+}
+
+// currentWebhook loads webhook from url, it writes not found response when webhook is not in current workspace
+func currentWebhook(w http.ResponseWriter, r *http.Request) (*core.Webhook, bool) {
+	currentWorkspace := appctx.CurrentWorkspace(r.Context())
+	webhookID, _ := strconv.ParseInt(chi.URLParam(r, "webhook_id"), 10, 64)
+
+	webhook, err := getWebhook(r.Context(), currentWorkspace.ID, webhookID)
+	if err != nil {
+		apihelper.InternalServerErrResp(w, err)
+		return nil, false
+	}
+	if webhook == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return webhook, true
+}
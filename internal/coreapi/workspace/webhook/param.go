@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/awanku/awanku/pkg/core"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type saveWebhookParam struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"event_types" validate:"required"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+func (p saveWebhookParam) Validate() error {
+	eventTypes := make([]interface{}, len(core.WebhookEventTypes))
+	for i, eventType := range core.WebhookEventTypes {
+		eventTypes[i] = eventType
+	}
+
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.URL, validation.Required, validation.Length(1, 2000), validation.By(validateEndpointURL)),
+		validation.Field(&p.EventTypes, validation.Required, validation.Each(validation.In(eventTypes...))),
+	)
+}
+
+func (p saveWebhookParam) active() bool {
+	return p.Active == nil || *p.Active
+}
+
+func validateEndpointURL(value interface{}) error {
+	raw, _ := value.(string)
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("must be an absolute http or https url")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	_, err = resolvePublicIPs(ctx, parsed.Hostname())
+	if err == errInternalAddress {
+		return errors.New("must not point to internal network address")
+	}
+	if err != nil {
+		return errors.New("host can not be resolved")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+)
+
+// delivery request headers
+const (
+	HeaderEvent     = "X-Awanku-Event"
+	HeaderDelivery  = "X-Awanku-Delivery"
+	HeaderTimestamp = "X-Awanku-Timestamp"
+	HeaderSignature = "X-Awanku-Signature"
+)
+
+const (
+	// maxAttempts is number of requests made before delivery is marked as failed
+	maxAttempts   = 8
+	retryBaseWait = 30 * time.Second
+	retryMaxWait  = 6 * time.Hour
+	secretLength  = 32
+)
+
+// Enqueue creates pending deliveries of the event for every active webhook in workspace subscribing to it.
+// It is called inside the transaction making the change, so event is only sent when the change is committed.
+func Enqueue(tx hansip.Transaction, workspaceID int64, eventType string, data map[string]interface{}) error {
+	var query = `
+        insert into webhook_deliveries (webhook_id, event_type, payload, next_attempt_at, created_at)
+        select id, ?, ?, now(), now()
+        from webhooks
+        where
+            workspace_id = ?
+            and active
+            and deleted_at is null
+            and ? = any(event_types)
+    `
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	payload := map[string]interface{}{
+		"event":        eventType,
+		"workspace_id": workspaceID,
+		"occurred_at":  time.Now().UTC().Format(time.RFC3339),
+		"data":         data,
+	}
+	return tx.Exec(query, eventType, payload, workspaceID, eventType)
+}
+
+// Sign returns signature of delivery body sent at timestamp, receivers compute the same value
+// to verify the request and should reject old timestamps to prevent replay
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryWait doubles with every failed attempt, capped at retryMaxWait
+func retryWait(attempts int64) time.Duration {
+	wait := retryBaseWait
+	for i := int64(1); i < attempts; i++ {
+		wait *= 2
+		if wait >= retryMaxWait {
+			return retryMaxWait
+		}
+	}
+	return wait
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("whsec_%s", hex.EncodeToString(secret)), nil
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"event":"member.joined"}`)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("1598000000." + string(body)))
+	expected := "v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, expected, Sign(secret, 1598000000, body))
+	assert.NotEqual(t, expected, Sign(secret, 1598000001, body))
+	assert.NotEqual(t, expected, Sign([]byte("other"), 1598000000, body))
+}
+
+func TestRetryWait(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryWait(1))
+	assert.Equal(t, 60*time.Second, retryWait(2))
+	assert.Equal(t, 4*time.Minute, retryWait(4))
+	assert.Equal(t, retryMaxWait, retryWait(20))
+}
+
+func stubLookupIPAddr(t *testing.T, hosts map[string]string) {
+	original := lookupIPAddr
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ip, ok := hosts[host]
+		if !ok {
+			return original(ctx, host)
+		}
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	t.Cleanup(func() { lookupIPAddr = original })
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.True(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"127.0.0.1", "::1", "0.0.0.0", "::", "10.1.2.3", "172.16.0.1", "192.168.1.1", "100.64.0.1", "169.254.169.254", "fe80::1", "fd00::1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestDeliveryClientDoesNotFollowRedirect(t *testing.T) {
+	client := newDeliveryClient()
+	req, _ := http.NewRequest(http.MethodPost, "http://10.0.0.5/hooks", nil)
+	assert.Equal(t, http.ErrUseLastResponse, client.CheckRedirect(req, nil))
+}
+
+func TestSaveWebhookParamValidate(t *testing.T) {
+	stubLookupIPAddr(t, map[string]string{
+		"example.com":          "93.184.216.34",
+		"internal.example.com": "10.0.0.5",
+	})
+
+	valid := saveWebhookParam{
+		URL:        "https://example.com/hooks",
+		EventTypes: []string{"member.joined"},
+	}
+	assert.NoError(t, valid.Validate())
+	assert.True(t, valid.active())
+
+	invalidURL := valid
+	invalidURL.URL = "ftp://example.com"
+	assert.Error(t, invalidURL.Validate())
+
+	for _, url := range []string{"http://127.0.0.1:8080/hooks", "http://[::1]/hooks", "http://169.254.169.254/latest", "https://internal.example.com/hooks"} {
+		internalURL := valid
+		internalURL.URL = url
+		assert.Error(t, internalURL.Validate(), url)
+	}
+
+	invalidEvent := valid
+	invalidEvent.EventTypes = []string{"member.exploded"}
+	assert.Error(t, invalidEvent.Validate())
+}
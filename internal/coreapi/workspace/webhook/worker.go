@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	hansip "github.com/asasmoyo/pq-hansip"
+	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/core"
+	"github.com/awanku/awanku/pkg/envelope"
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+	// only the beginning of response body is kept for inspection
+	maxResponseBodyLength = 4096
+	claimBatchSize        = 50
+)
+
+// Worker sends pending webhook deliveries in background
+type Worker struct {
+	db       *hansip.Cluster
+	keys     envelope.KeyProvider
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewWorker creates new webhook delivery worker
+func NewWorker(db *hansip.Cluster, keys envelope.KeyProvider, interval time.Duration) *Worker {
+	return &Worker{
+		db:       db,
+		keys:     keys,
+		client:   newDeliveryClient(),
+		interval: interval,
+	}
+}
+
+// Start sends due deliveries until ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	ctx = context.WithValue(ctx, appctx.KeyDatabase, w.db)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// drain all due deliveries before waiting for next tick
+		for {
+			processed, err := w.processDue(ctx)
+			if err != nil {
+				log.Println("webhook worker failed:", err)
+			}
+			if processed == 0 || err != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) processDue(ctx context.Context) (int, error) {
+	deliveries, err := claimDueDeliveries(ctx, claimBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		if err := w.deliver(ctx, delivery); err != nil {
+			log.Println("failed to deliver webhook", delivery.ID, ":", err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends delivery once and records the attempt, errors from the receiver are recorded instead of returned
+func (w *Worker) deliver(ctx context.Context, delivery *core.WebhookDelivery) error {
+	webhook, err := getDeliveryWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return nil
+	}
+
+	secret, err := openSecret(w.keys, webhook)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return recordAttempt(ctx, delivery, failedAttempt(err, 0))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Awanku-Webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	startedAt := time.Now()
+	resp, err := w.client.Do(req)
+	duration := time.Since(startedAt).Milliseconds()
+	if err != nil {
+		return recordAttempt(ctx, delivery, failedAttempt(err, duration))
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBodyLength))
+	status := int64(resp.StatusCode)
+	// postgres text can not hold invalid utf-8 nor null bytes
+	bodyText := strings.ReplaceAll(strings.ToValidUTF8(string(responseBody), ""), "\x00", "")
+	attempt := core.WebhookDeliveryAttempt{
+		ResponseStatus: &status,
+		ResponseBody:   &bodyText,
+		DurationMs:     duration,
+	}
+	return recordAttempt(ctx, delivery, &attempt)
+}
+
+func failedAttempt(err error, duration int64) *core.WebhookDeliveryAttempt {
+	message := err.Error()
+	return &core.WebhookDeliveryAttempt{
+		Error:      &message,
+		DurationMs: duration,
+	}
+}
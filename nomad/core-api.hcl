@@ -51,11 +51,16 @@ job "awanku-stack-core-api" {
                 GITHUB_APP_ID = 73537
                 GITHUB_APP_PRIVATE_KEY_PATH = "/local/github.private-key.pem"
                 GITHUB_APP_INSTALL_URL = "https://github.com/apps/awanku-development/installations/new"
+                SECRETS_KEY_FILE = "/local/secrets.keys"
             }
             template {
                 data = "{{ key \"awanku/credentials/github/dev.private-key.pem\" }}"
                 destination = "local/github.private-key.pem"
             }
+            template {
+                data = "{{ key \"awanku/credentials/secrets/master.keys\" }}"
+                destination = "local/secrets.keys"
+            }
             resources {
                 network {
                     port "http" {}
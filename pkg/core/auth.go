@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
+	"math/big"
 	"time"
 )
 
@@ -35,6 +37,8 @@ func BuildOauthToken(secretKey []byte, length int) (*OauthToken, error) {
 		RefreshTokenHash: refreshTokenHash,
 		ExpiresAt:        time.Now().Add(60 * time.Minute),
 	}
+	// refresh token is unusable unless issuer extends it
+	token.RefreshExpiresAt = token.ExpiresAt
 	return token, nil
 }
 
@@ -47,6 +51,30 @@ func BuildOauthAuthorizationCode(length int) (string, error) {
 	return code, nil
 }
 
+// BuildWorkspaceInvitationToken generates url safe invitation token together with its hash
+func BuildWorkspaceInvitationToken(secretKey []byte, length int) (string, []byte, error) {
+	buff, err := generateHash(length)
+	if err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buff)
+	tokenHash, err := HashHMAC(secretKey, []byte(token))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, tokenHash, nil
+}
+
+// BuildVerificationCode generates numeric code which is easy to type from an email
+func BuildVerificationCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
 func ValidateHMAC(secretKey, plain, hashed []byte) (bool, error) {
 	computed, err := HashHMAC(secretKey, plain)
 	if err != nil {
@@ -14,14 +14,39 @@ const (
 	OauthProviderGithub               = "github"
 	OauthProviderGoogle               = "google"
 	OauthAuthorizationCodeMaxDuration = 5 * time.Minute
+	ImpersonationTokenMaxDuration     = 15 * time.Minute
 )
 
 // OauthUserData represents user data provided by third party oauth services
 type OauthUserData struct {
-	Provider   string `json:"provider"`
-	Name       string `json:"name"`
-	Email      string `json:"email"`
-	Identifier string `json:"identifier"`
+	Provider      string `json:"provider"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Identifier    string `json:"identifier"`
+}
+
+// email verification purposes
+const (
+	EmailVerificationPurposeSignup      = "signup"
+	EmailVerificationPurposeChangeEmail = "change_email"
+)
+
+// EmailVerificationMaxDuration is how long email verification code stays valid
+const EmailVerificationMaxDuration = 15 * time.Minute
+
+// EmailVerification represents pending email ownership confirmation.
+// Payload holds data needed to continue the flow after email is confirmed.
+type EmailVerification struct {
+	ID         int64             `json:"id"`
+	Purpose    string            `json:"purpose"`
+	Email      string            `json:"email"`
+	CodeHash   []byte            `json:"-"`
+	Payload    map[string]string `json:"-"`
+	Attempts   int               `json:"-"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	VerifiedAt *time.Time        `json:"-"`
+	CreatedAt  time.Time         `json:"created_at"`
 }
 
 // OauthAuthorizationCode represents oauth authorization code
@@ -40,33 +65,73 @@ type OauthToken struct {
 	RefreshToken       []byte
 	RefreshTokenHash   []byte
 	ExpiresAt          time.Time
+	RefreshExpiresAt   time.Time
 	RequesterIP        string
 	RequesterUserAgent string
+	ImpersonatorUserID *int64
 	DeletedAt          *time.Time
 }
 
+// IsImpersonation returns true if token is issued for a platform admin acting as another user
+func (t *OauthToken) IsImpersonation() bool {
+	return t.ImpersonatorUserID != nil
+}
+
+// ImpersonationLog represents a request made using impersonation token
+type ImpersonationLog struct {
+	ID           int64
+	OauthTokenID int64
+	ActorUserID  int64
+	UserID       int64
+	Method       string
+	Path         string
+	RequesterIP  string
+	CreatedAt    time.Time
+}
+
 // Token returns standar token representation
 func (t *OauthToken) Token() *oauth2.Token {
 	encodedAccessToken := base64.URLEncoding.EncodeToString(t.AccessToken)
 	encodedRefreshToken := base64.URLEncoding.EncodeToString(t.RefreshToken)
-	return &oauth2.Token{
+	token := &oauth2.Token{
 		AccessToken:  fmt.Sprintf("%d:%s", t.ID, encodedAccessToken),
 		RefreshToken: fmt.Sprintf("%d:%s", t.ID, encodedRefreshToken),
 		Expiry:       t.ExpiresAt,
 		TokenType:    "bearer",
 	}
+	// impersonation tokens are short-lived and can not be refreshed
+	if t.IsImpersonation() {
+		token.RefreshToken = ""
+	}
+	return token
 }
 
 // User represents User
 type User struct {
-	ID                  int64      `json:"id"`
-	Name                string     `json:"name"`
-	Email               string     `json:"email"`
-	GoogleLoginEmail    *string    `json:"google_login_email"`
-	GithubLoginUsername *string    `json:"github_login_username"`
-	CreatedAt           time.Time  `json:"created_at"`
-	UpdatedAt           *time.Time `json:"updated_at"`
-	DeletedAt           *time.Time `json:"-"`
+	ID                  int64           `json:"id"`
+	Name                string          `json:"name"`
+	Email               string          `json:"email"`
+	GoogleLoginEmail    *string         `json:"google_login_email"`
+	GithubLoginUsername *string         `json:"github_login_username"`
+	IsAdmin             bool            `json:"is_admin"`
+	Preferences         UserPreferences `json:"preferences"`
+	PendingEmail        *string         `json:"pending_email"`
+	SuspendedAt         *time.Time      `json:"suspended_at"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           *time.Time      `json:"updated_at"`
+	DeletedAt           *time.Time      `json:"-"`
+}
+
+// IsSuspended returns true if user is suspended by platform admin
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// UserPreferences represents user display preferences, stored as JSON
+type UserPreferences struct {
+	Theme    string `json:"theme"`
+	Language string `json:"language"`
+	Timezone string `json:"timezone"`
 }
 
 // SetOauth2Identifier sets identifier based on provider
@@ -79,15 +144,456 @@ func (u *User) SetOauth2Identifier(provider string, identifier *string) {
 	}
 }
 
+// data export statuses
+const (
+	DataExportStatusPending   = "pending"
+	DataExportStatusRunning   = "running"
+	DataExportStatusCompleted = "completed"
+	DataExportStatusFailed    = "failed"
+)
+
+// data export archive formats
+const (
+	DataExportFormatJSON = "json"
+	DataExportFormatZip  = "zip"
+)
+
+// DataExportMaxDuration is how long generated personal data archive is kept
+const DataExportMaxDuration = 7 * 24 * time.Hour
+
+// DataExport represents personal data export job
+type DataExport struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"-"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"`
+	Archive     []byte     `json:"-"`
+	Error       *string    `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
 // Workspace represents workspace
 type Workspace struct {
 	ID        int64      `json:"id"`
 	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	Plan      string     `json:"plan"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
 	DeletedAt *time.Time `json:"-"`
 }
 
+// WorkspaceSlugRedirectDuration is how long previous workspace slug keeps redirecting after rename
+const WorkspaceSlugRedirectDuration = 90 * 24 * time.Hour
+
+// workspace access levels
+const (
+	WorkspaceAccessLevelOwner  = "owner"
+	WorkspaceAccessLevelEditor = "editor"
+	WorkspaceAccessLevelViewer = "viewer"
+	// WorkspaceAccessLevelGuest is given to users who are not workspace members
+	// but were granted access to some of its projects, it is never stored
+	WorkspaceAccessLevelGuest = "guest"
+)
+
+var workspaceAccessLevelRanks = map[string]int{
+	WorkspaceAccessLevelGuest:  0,
+	WorkspaceAccessLevelViewer: 1,
+	WorkspaceAccessLevelEditor: 2,
+	WorkspaceAccessLevelOwner:  3,
+}
+
+// WorkspaceAccessLevelAtLeast returns true if level grants at least the same access as required.
+// Owner can do everything editor can, editor can do everything viewer can.
+func WorkspaceAccessLevelAtLeast(level, required string) bool {
+	rank, ok := workspaceAccessLevelRanks[level]
+	if !ok {
+		return false
+	}
+	return rank >= workspaceAccessLevelRanks[required]
+}
+
+// project access levels, they are granted on top of workspace access level
+const (
+	ProjectAccessLevelEditor = "editor"
+	ProjectAccessLevelViewer = "viewer"
+)
+
+// EffectiveProjectAccessLevel combines workspace and project access levels, the higher one wins.
+// It returns empty string when user can not access the project at all.
+func EffectiveProjectAccessLevel(workspaceLevel, projectLevel string) string {
+	effective := ""
+	for _, level := range []string{workspaceLevel, projectLevel} {
+		if level == WorkspaceAccessLevelGuest || !WorkspaceAccessLevelAtLeast(level, WorkspaceAccessLevelViewer) {
+			continue
+		}
+		if effective == "" || workspaceAccessLevelRanks[level] > workspaceAccessLevelRanks[effective] {
+			effective = level
+		}
+	}
+	return effective
+}
+
+// ProjectMember represents user granted access to a single project
+type ProjectMember struct {
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	AccessLevel string    `json:"access_level"`
+	GrantedAt   time.Time `json:"granted_at"`
+}
+
+// WorkspaceMember represents user membership in a workspace
+type WorkspaceMember struct {
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	AccessLevel string    `json:"access_level"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// workspace invitation statuses, pending invitation past its expiry can no longer be accepted
+const (
+	WorkspaceInvitationStatusPending  = "pending"
+	WorkspaceInvitationStatusAccepted = "accepted"
+	WorkspaceInvitationStatusRevoked  = "revoked"
+)
+
+// WorkspaceInvitationMaxDuration is how long workspace invitation can be accepted
+const WorkspaceInvitationMaxDuration = 7 * 24 * time.Hour
+
+// WorkspaceInvitation represents invitation for an email address to join a workspace
+type WorkspaceInvitation struct {
+	ID             int64      `json:"id"`
+	WorkspaceID    int64      `json:"workspace_id"`
+	InviterUserID  int64      `json:"inviter_user_id"`
+	Email          string     `json:"email"`
+	AccessLevel    string     `json:"access_level"`
+	Token          string     `json:"-"`
+	TokenHash      []byte     `json:"-"`
+	Status         string     `json:"status"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedUserID *int64     `json:"accepted_user_id"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+// IsExpired returns true if invitation can no longer be accepted because of its age
+func (i *WorkspaceInvitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// workspace ownership transfer statuses
+const (
+	WorkspaceOwnershipTransferStatusPending   = "pending"
+	WorkspaceOwnershipTransferStatusAccepted  = "accepted"
+	WorkspaceOwnershipTransferStatusCancelled = "cancelled"
+)
+
+// WorkspaceOwnershipTransfer represents ownership proposed by an owner to another member,
+// ownership only changes after the recipient accepts it
+type WorkspaceOwnershipTransfer struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	FromUserID  int64      `json:"from_user_id"`
+	ToUserID    int64      `json:"to_user_id"`
+	Status      string     `json:"status"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// WorkspaceActivity represents an entry of workspace activity feed
+type WorkspaceActivity struct {
+	ID          int64                  `json:"id"`
+	WorkspaceID int64                  `json:"workspace_id"`
+	ActorUserID *int64                 `json:"actor_user_id"`
+	Verb        string                 `json:"verb"`
+	TargetType  string                 `json:"target_type"`
+	TargetID    *int64                 `json:"target_id"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// Plan represents workspace plan, nil limit means unlimited
+type Plan struct {
+	Code                     string     `json:"code"`
+	Name                     string     `json:"name"`
+	MaxMembers               *int64     `json:"max_members"`
+	MaxProjects              *int64     `json:"max_projects"`
+	MaxResourcesPerType      *int64     `json:"max_resources_per_type"`
+	MaxRepositoryConnections *int64     `json:"max_repository_connections"`
+	MaxCPUMillicores         *int64     `json:"max_cpu_millicores"`
+	MaxMemoryMB              *int64     `json:"max_memory_mb"`
+	Currency                 string     `json:"currency"`
+	ResourceHourPrice        int64      `json:"resource_hour_price"`
+	CreatedAt                time.Time  `json:"-"`
+	UpdatedAt                *time.Time `json:"-"`
+}
+
+// QuotaUsage represents consumption of a plan limit, nil limit means unlimited
+type QuotaUsage struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit"`
+}
+
+// WorkspaceUsage represents current workspace consumption against its plan limits
+type WorkspaceUsage struct {
+	Plan                  string                `json:"plan"`
+	Members               QuotaUsage            `json:"members"`
+	PendingInvitations    int64                 `json:"pending_invitations"`
+	Projects              QuotaUsage            `json:"projects"`
+	Resources             map[string]QuotaUsage `json:"resources"`
+	RepositoryConnections QuotaUsage            `json:"repository_connections"`
+	CPUMillicores         QuotaUsage            `json:"cpu_millicores"`
+	MemoryMB              QuotaUsage            `json:"memory_mb"`
+}
+
+// ResourceMeteringEvent records resource moving between states, billable time is derived from these events
+type ResourceMeteringEvent struct {
+	ID            int64     `json:"id"`
+	ResourceID    int64     `json:"resource_id"`
+	WorkspaceID   int64     `json:"workspace_id"`
+	PreviousState string    `json:"previous_state"`
+	State         string    `json:"state"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// Invoice represents workspace usage charges for a calendar month, amounts are in smallest unit of currency
+type Invoice struct {
+	ID          int64             `json:"id"`
+	WorkspaceID int64             `json:"workspace_id"`
+	Number      string            `json:"number"`
+	PeriodStart time.Time         `json:"period_start"`
+	PeriodEnd   time.Time         `json:"period_end"`
+	Plan        string            `json:"plan"`
+	Currency    string            `json:"currency"`
+	LineItems   []InvoiceLineItem `json:"line_items"`
+	Total       int64             `json:"total"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// InvoiceLineItem represents usage of a single resource in invoice period
+type InvoiceLineItem struct {
+	ResourceID   int64  `json:"resource_id"`
+	ResourceName string `json:"resource_name"`
+	ResourceType string `json:"resource_type"`
+	Hours        int64  `json:"hours"`
+	UnitPrice    int64  `json:"unit_price"`
+	Amount       int64  `json:"amount"`
+}
+
+// Project represents group of resources in a workspace.
+// Repository fields are set together when project is linked to a repository of one of workspace repository connections,
+// root directory is relative to repository root and always starts with slash.
+type Project struct {
+	ID                     int64      `json:"id"`
+	WorkspaceID            int64      `json:"workspace_id"`
+	Name                   string     `json:"name"`
+	RepositoryConnectionID *int64     `json:"repository_connection_id"`
+	RepositoryName         *string    `json:"repository_name"`
+	RepositoryBranch       *string    `json:"repository_branch"`
+	RepositoryRootDir      *string    `json:"repository_root_dir"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              *time.Time `json:"updated_at"`
+	DeletedAt              *time.Time `json:"-"`
+}
+
+// project environment types
+const (
+	ProjectEnvironmentTypeProduction = "production"
+	ProjectEnvironmentTypeStaging    = "staging"
+	ProjectEnvironmentTypePreview    = "preview"
+)
+
+// ProjectEnvironmentTypes lists valid project environment types
+var ProjectEnvironmentTypes = []string{
+	ProjectEnvironmentTypeProduction,
+	ProjectEnvironmentTypeStaging,
+	ProjectEnvironmentTypePreview,
+}
+
+// DefaultProjectEnvironmentName is name of protected production environment created together with a project
+const DefaultProjectEnvironmentName = "production"
+
+// ProjectEnvironment represents deployment target of a project, resources belong to an environment.
+// Branch maps repository branch to the environment, preview environments may use * wildcard.
+// Protected environment can only be changed by workspace owners and can not be deleted.
+type ProjectEnvironment struct {
+	ID        int64      `json:"id"`
+	ProjectID int64      `json:"project_id"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Protected bool       `json:"protected"`
+	Branch    *string    `json:"branch"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"-"`
+}
+
+// TrashRetentionDuration is how long soft deleted items can be restored before they are purged
+const TrashRetentionDuration = 30 * 24 * time.Hour
+
+// trash item types
+const (
+	TrashItemTypeProject              = "project"
+	TrashItemTypeResource             = "resource"
+	TrashItemTypeRepositoryConnection = "repository_connection"
+)
+
+// TrashItem represents soft deleted item which can still be restored
+type TrashItem struct {
+	Type            string    `json:"type"`
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	ProjectID       *int64    `json:"project_id"`
+	DeletedAt       time.Time `json:"deleted_at"`
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
+// Secret represents encrypted value scoped to a workspace, or to a project when ProjectID is set.
+// Value is never serialized, it is only returned by explicit reveal.
+type Secret struct {
+	ID              int64      `json:"id"`
+	WorkspaceID     int64      `json:"workspace_id"`
+	ProjectID       *int64     `json:"project_id"`
+	Name            string     `json:"name"`
+	Ciphertext      []byte     `json:"-"`
+	Nonce           []byte     `json:"-"`
+	WrappedKey      []byte     `json:"-"`
+	MasterKeyID     string     `json:"-"`
+	Version         int64      `json:"version"`
+	CreatedByUserID *int64     `json:"created_by_user_id"`
+	UpdatedByUserID *int64     `json:"updated_by_user_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+	DeletedAt       *time.Time `json:"-"`
+}
+
+// resource states
+const (
+	ResourceStateUnknown             = "unknown"
+	ResourceStateProvisioning        = "provisioning"
+	ResourceStateProvisioningFailed  = "provisioning_failed"
+	ResourceStateProvisioningSuccess = "provisioning_success"
+)
+
+// ResourceStates lists valid resource states
+var ResourceStates = []string{
+	ResourceStateUnknown,
+	ResourceStateProvisioning,
+	ResourceStateProvisioningFailed,
+	ResourceStateProvisioningSuccess,
+}
+
+// resource types
+const (
+	ResourceTypeApplication = "application"
+	ResourceTypePostgres    = "postgres"
+	ResourceTypeMySQL       = "mysql"
+	ResourceTypeRedis       = "redis"
+)
+
+// ResourceTypes lists resource types which can be provisioned
+var ResourceTypes = []string{
+	ResourceTypeApplication,
+	ResourceTypePostgres,
+	ResourceTypeMySQL,
+	ResourceTypeRedis,
+}
+
+// Resource represents service provisioned in a project environment, its state is reported by provisioner.
+// Payload holds type specific configuration passed to provisioner.
+type Resource struct {
+	ID            int64                  `json:"id"`
+	ProjectID     int64                  `json:"project_id"`
+	EnvironmentID int64                  `json:"environment_id"`
+	Name          string                 `json:"name"`
+	Type          string                 `json:"type"`
+	Payload       map[string]interface{} `json:"payload"`
+	State         string                 `json:"state"`
+	CPUMillicores int64                  `json:"cpu_millicores" pg:"cpu_millicores"`
+	MemoryMB      int64                  `json:"memory_mb" pg:"memory_mb"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     *time.Time             `json:"updated_at"`
+	DeletedAt     *time.Time             `json:"-"`
+}
+
+// webhook event types
+const (
+	WebhookEventRepositoryConnectionCreated = "repository_connection.created"
+	WebhookEventMemberJoined                = "member.joined"
+	WebhookEventMemberRemoved               = "member.removed"
+	WebhookEventResourceProvisioned         = "resource.provisioned"
+	WebhookEventResourceProvisioningFailed  = "resource.provisioning_failed"
+)
+
+// WebhookEventTypes lists event types webhook can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventRepositoryConnectionCreated,
+	WebhookEventMemberJoined,
+	WebhookEventMemberRemoved,
+	WebhookEventResourceProvisioned,
+	WebhookEventResourceProvisioningFailed,
+}
+
+// Webhook represents workspace endpoint which receives events it subscribes to.
+// Signing secret is encrypted and only shown once when webhook is created.
+type Webhook struct {
+	ID                int64      `json:"id"`
+	WorkspaceID       int64      `json:"workspace_id"`
+	URL               string     `json:"url"`
+	EventTypes        []string   `json:"event_types" pg:",array"`
+	Active            bool       `json:"active"`
+	SecretCiphertext  []byte     `json:"-"`
+	SecretNonce       []byte     `json:"-"`
+	SecretWrappedKey  []byte     `json:"-"`
+	SecretMasterKeyID string     `json:"-"`
+	CreatedByUserID   *int64     `json:"created_by_user_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at"`
+	DeletedAt         *time.Time `json:"-"`
+}
+
+// webhook delivery statuses
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// WebhookDelivery represents an event sent, or to be sent, to a webhook
+type WebhookDelivery struct {
+	ID                 int64                  `json:"id"`
+	WebhookID          int64                  `json:"webhook_id"`
+	EventType          string                 `json:"event_type"`
+	Payload            map[string]interface{} `json:"payload"`
+	Status             string                 `json:"status"`
+	Attempts           int64                  `json:"attempts"`
+	NextAttemptAt      *time.Time             `json:"next_attempt_at"`
+	LastAttemptAt      *time.Time             `json:"last_attempt_at"`
+	LastResponseStatus *int64                 `json:"last_response_status"`
+	RedeliveryOf       *int64                 `json:"redelivery_of"`
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          *time.Time             `json:"updated_at"`
+}
+
+// WebhookDeliveryAttempt represents a single request made for a delivery
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id"`
+	DeliveryID     int64     `json:"delivery_id"`
+	ResponseStatus *int64    `json:"response_status"`
+	ResponseBody   *string   `json:"response_body"`
+	Error          *string   `json:"error"`
+	DurationMs     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 // RepositoryProvider represents repository provider
 type RepositoryProvider string
 
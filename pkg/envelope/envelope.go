@@ -0,0 +1,115 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+const dataKeyLength = 32
+
+// KeyProvider wraps and unwraps data keys with master key
+type KeyProvider interface {
+	// CurrentKeyID returns id of master key used to wrap new data keys
+	CurrentKeyID() string
+	WrapKey(dataKey []byte) (wrapped []byte, keyID string, err error)
+	UnwrapKey(wrapped []byte, keyID string) ([]byte, error)
+}
+
+// Sealed is encrypted value together with its wrapped data key
+type Sealed struct {
+	Ciphertext  []byte
+	Nonce       []byte
+	WrappedKey  []byte
+	MasterKeyID string
+}
+
+// Seal encrypts plaintext with a new data key, data key is wrapped by current master key.
+// aad identifies owner of the value, the same aad must be given to Open so sealed value
+// copied to another owner can not be opened.
+func Seal(keys KeyProvider, plaintext, aad []byte) (*Sealed, error) {
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := encrypt(dataKey, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, keyID, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := Sealed{
+		Ciphertext:  ciphertext,
+		Nonce:       nonce,
+		WrappedKey:  wrapped,
+		MasterKeyID: keyID,
+	}
+	return &sealed, nil
+}
+
+// Open decrypts sealed value, aad must be the one given to Seal
+func Open(keys KeyProvider, sealed *Sealed, aad []byte) ([]byte, error) {
+	dataKey, err := keys.UnwrapKey(sealed.WrappedKey, sealed.MasterKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(dataKey, sealed.Nonce, sealed.Ciphertext, aad)
+}
+
+// Rewrap wraps data key of sealed value with current master key, ciphertext stays the same.
+// It returns false when data key is already wrapped by current master key.
+func Rewrap(keys KeyProvider, sealed *Sealed) (bool, error) {
+	if sealed.MasterKeyID == keys.CurrentKeyID() {
+		return false, nil
+	}
+
+	dataKey, err := keys.UnwrapKey(sealed.WrappedKey, sealed.MasterKeyID)
+	if err != nil {
+		return false, err
+	}
+
+	wrapped, keyID, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return false, err
+	}
+	sealed.WrappedKey = wrapped
+	sealed.MasterKeyID = keyID
+	return true, nil
+}
+
+func encrypt(key, plaintext, aad []byte) (nonce, ciphertext []byte, err error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func decrypt(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
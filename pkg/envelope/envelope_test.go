@@ -0,0 +1,102 @@
+package envelope
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	keys, err := NewLocalKeyProvider(map[string][]byte{"first": make([]byte, 32)}, "first")
+	assert.NoError(t, err)
+
+	aad := []byte("secrets:1")
+	sealed, err := Seal(keys, []byte("database password"), aad)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", sealed.MasterKeyID)
+	assert.NotContains(t, string(sealed.Ciphertext), "database password")
+
+	other, err := Seal(keys, []byte("database password"), aad)
+	assert.NoError(t, err)
+	assert.NotEqual(t, sealed.WrappedKey, other.WrappedKey)
+	assert.NotEqual(t, sealed.Ciphertext, other.Ciphertext)
+
+	opened, err := Open(keys, sealed, aad)
+	assert.NoError(t, err)
+	assert.Equal(t, "database password", string(opened))
+
+	// value copied to another owner does not open
+	_, err = Open(keys, sealed, []byte("secrets:2"))
+	assert.Error(t, err)
+	_, err = Open(keys, sealed, nil)
+	assert.Error(t, err)
+
+	sealed.Ciphertext[0] ^= 1
+	_, err = Open(keys, sealed, aad)
+	assert.Error(t, err)
+}
+
+func TestLocalKeyProviderRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "master.keys")
+
+	firstID, err := GenerateLocalKey(path)
+	assert.NoError(t, err)
+	keys, err := LoadLocalKeyProvider(path)
+	assert.NoError(t, err)
+	assert.Equal(t, firstID, keys.CurrentKeyID())
+
+	aad := []byte("webhooks:1")
+	sealed, err := Seal(keys, []byte("api key"), aad)
+	assert.NoError(t, err)
+
+	secondID, err := GenerateLocalKey(path)
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstID, secondID)
+	keys, err = LoadLocalKeyProvider(path)
+	assert.NoError(t, err)
+	assert.Equal(t, secondID, keys.CurrentKeyID())
+
+	// old master key still opens values until they are rewrapped
+	opened, err := Open(keys, sealed, aad)
+	assert.NoError(t, err)
+	assert.Equal(t, "api key", string(opened))
+
+	ciphertext := sealed.Ciphertext
+	rewrapped, err := Rewrap(keys, sealed)
+	assert.NoError(t, err)
+	assert.True(t, rewrapped)
+	assert.Equal(t, secondID, sealed.MasterKeyID)
+	assert.Equal(t, ciphertext, sealed.Ciphertext)
+
+	rewrapped, err = Rewrap(keys, sealed)
+	assert.NoError(t, err)
+	assert.False(t, rewrapped)
+
+	opened, err = Open(keys, sealed, aad)
+	assert.NoError(t, err)
+	assert.Equal(t, "api key", string(opened))
+
+	_, err = keys.UnwrapKey(sealed.WrappedKey, "unknown")
+	assert.Error(t, err)
+}
+
+func TestLoadLocalKeyProviderInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "master.keys")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("# comment\nshort:c2hvcnQ=\n"), 0600))
+	_, err = LoadLocalKeyProvider(path)
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(""), 0600))
+	_, err = LoadLocalKeyProvider(path)
+	assert.Error(t, err)
+}
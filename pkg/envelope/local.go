@@ -0,0 +1,115 @@
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// LocalKeyProvider keeps master keys in memory, loaded from a key file
+type LocalKeyProvider struct {
+	keys      map[string][]byte
+	currentID string
+}
+
+// NewLocalKeyProvider creates key provider from master keys, currentID is used to wrap new data keys
+func NewLocalKeyProvider(keys map[string][]byte, currentID string) (*LocalKeyProvider, error) {
+	for id, key := range keys {
+		if len(key) != dataKeyLength {
+			return nil, fmt.Errorf("master key %s must be %d bytes long", id, dataKeyLength)
+		}
+	}
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("unknown current master key %s", currentID)
+	}
+	return &LocalKeyProvider{keys: keys, currentID: currentID}, nil
+}
+
+// LoadLocalKeyProvider reads key file where each line is formatted as key_id:base64_key.
+// The last key is current, older keys are kept to unwrap data keys which have not been rotated yet.
+func LoadLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string][]byte{}
+	var currentID string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid key file line, expected key_id:base64_key")
+		}
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key %s: %v", parts[0], err)
+		}
+		keys[parts[0]] = key
+		currentID = parts[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewLocalKeyProvider(keys, currentID)
+}
+
+// GenerateLocalKey appends new random master key to key file, making it the current key
+func GenerateLocalKey(path string) (string, error) {
+	key := make([]byte, dataKeyLength+2)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	// random suffix keeps ids unique when keys are generated within the same second
+	id := time.Now().UTC().Format("20060102150405") + "-" + hex.EncodeToString(key[dataKeyLength:])
+	key = key[:dataKeyLength]
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s:%s\n", id, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return "", err
+	}
+	return id, f.Close()
+}
+
+func (p *LocalKeyProvider) CurrentKeyID() string {
+	return p.currentID
+}
+
+func (p *LocalKeyProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	nonce, ciphertext, err := encrypt(p.keys[p.currentID], dataKey, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(nonce, ciphertext...), p.currentID, nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(wrapped []byte, keyID string) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %s", keyID)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	return decrypt(key, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+}
@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidHeader is returned when header value contains line break, which would allow injecting headers
+var ErrInvalidHeader = errors.New("mail header contains line break")
+
+// Message represents email message
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPMailer sends email through SMTP server
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	host := strings.Split(m.Addr, ":")[0]
+	auth := smtp.PlainAuth("", m.Username, m.Password, host)
+
+	body, err := buildMessage(m.From, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, body)
+}
+
+// buildMessage renders message with headers, subject is encoded so it may contain any unicode text
+func buildMessage(from string, msg *Message) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	subject := mime.QEncoding.Encode("utf-8", msg.Subject)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", from, msg.To, subject, msg.Body)
+	return []byte(body), nil
+}
+
+// LogMailer prints email to log instead of sending it, useful for development
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryMailer keeps sent email in memory, useful for testing
+type MemoryMailer struct {
+	mut      sync.Mutex
+	messages []*Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns all sent email
+func (m *MemoryMailer) Messages() []*Message {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return append([]*Message{}, m.messages...)
+}
+
+// LastMessageTo returns latest email sent to the address, nil if there is none
+func (m *MemoryMailer) LastMessageTo(to string) *Message {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i]
+		}
+	}
+	return nil
+}
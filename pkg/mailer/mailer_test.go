@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	body, err := buildMessage("noreply@awanku.id", &Message{To: "user@example.com", Subject: "Budi invited you to Awan Ku", Body: "hello"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Subject: Budi invited you to Awan Ku\r\n")
+
+	body, err = buildMessage("noreply@awanku.id", &Message{To: "user@example.com", Subject: "Undangan dari Ñoño", Body: "hello"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Subject: =?utf-8?q?")
+	assert.False(t, strings.Contains(string(body), "Ñoño"))
+
+	for _, msg := range []*Message{
+		{To: "user@example.com", Subject: "Budi\r\nBcc: victim@example.com"},
+		{To: "user@example.com", Subject: "Budi\nBcc: victim@example.com"},
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "hello"},
+	} {
+		_, err := buildMessage("noreply@awanku.id", msg)
+		assert.Equal(t, ErrInvalidHeader, err)
+	}
+}
@@ -2,12 +2,15 @@ package oauth2provider
 
 import (
 	"context"
+	"errors"
 
 	"github.com/awanku/awanku/pkg/core"
 	githubService "github.com/google/go-github/v32/github"
 	"golang.org/x/oauth2"
 )
 
+var errNoEmail = errors.New("oauth account has no email address")
+
 type GithubProvider struct {
 	Config *oauth2.Config
 }
@@ -36,24 +39,48 @@ func (p *GithubProvider) ExchangeCode(code string) (*core.OauthUserData, error)
 		return nil, err
 	}
 
-	var primaryEmail string
+	email, verified, err := pickGithubEmail(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	userData := core.OauthUserData{
+		Provider:      core.OauthProviderGithub,
+		Name:          githubUser.GetName(),
+		Email:         email,
+		EmailVerified: verified,
+		Identifier:    githubUser.GetLogin(),
+	}
+	return &userData, nil
+}
+
+// pickGithubEmail prefers verified primary email, then any verified email.
+// Unverified email is only returned when user has no verified email at all.
+func pickGithubEmail(emails []*githubService.UserEmail) (string, bool, error) {
+	var verifiedEmail, unverifiedEmail string
 	for _, email := range emails {
-		if email.GetEmail() != "" && email.GetVerified() {
-			primaryEmail = email.GetEmail()
-			break
+		if email.GetEmail() == "" {
+			continue
+		}
+		if email.GetVerified() {
+			if email.GetPrimary() {
+				return email.GetEmail(), true, nil
+			}
+			if verifiedEmail == "" {
+				verifiedEmail = email.GetEmail()
+			}
+			continue
+		}
+		if unverifiedEmail == "" || email.GetPrimary() {
+			unverifiedEmail = email.GetEmail()
 		}
 	}
 
-	// if we don't get primary email, just get first email
-	if primaryEmail == "" {
-		primaryEmail = emails[0].GetEmail()
+	if verifiedEmail != "" {
+		return verifiedEmail, true, nil
 	}
-
-	userData := core.OauthUserData{
-		Provider:   core.OauthProviderGithub,
-		Name:       githubUser.GetName(),
-		Email:      primaryEmail,
-		Identifier: githubUser.GetLogin(),
+	if unverifiedEmail != "" {
+		return unverifiedEmail, false, nil
 	}
-	return &userData, nil
+	return "", false, errNoEmail
 }
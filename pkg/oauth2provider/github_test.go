@@ -0,0 +1,48 @@
+package oauth2provider
+
+import (
+	"testing"
+
+	githubService "github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func githubEmail(email string, primary, verified bool) *githubService.UserEmail {
+	return &githubService.UserEmail{Email: &email, Primary: &primary, Verified: &verified}
+}
+
+func TestPickGithubEmail(t *testing.T) {
+	t.Run("verified primary email", func(t *testing.T) {
+		email, verified, err := pickGithubEmail([]*githubService.UserEmail{
+			githubEmail("other@example.com", false, true),
+			githubEmail("primary@example.com", true, true),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "primary@example.com", email)
+		assert.True(t, verified)
+	})
+
+	t.Run("verified email is preferred over unverified primary", func(t *testing.T) {
+		email, verified, err := pickGithubEmail([]*githubService.UserEmail{
+			githubEmail("primary@example.com", true, false),
+			githubEmail("other@example.com", false, true),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "other@example.com", email)
+		assert.True(t, verified)
+	})
+
+	t.Run("only unverified email", func(t *testing.T) {
+		email, verified, err := pickGithubEmail([]*githubService.UserEmail{
+			githubEmail("unverified@example.com", false, false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "unverified@example.com", email)
+		assert.False(t, verified)
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		_, _, err := pickGithubEmail([]*githubService.UserEmail{})
+		assert.Equal(t, errNoEmail, err)
+	})
+}
@@ -34,11 +34,16 @@ func (p *GoogleProvider) ExchangeCode(code string) (*core.OauthUserData, error)
 		return nil, err
 	}
 
+	if remoteUserData.Email == "" {
+		return nil, errNoEmail
+	}
+
 	userData := core.OauthUserData{
-		Provider:   core.OauthProviderGoogle,
-		Name:       remoteUserData.Name,
-		Email:      remoteUserData.Email,
-		Identifier: remoteUserData.Email,
+		Provider:      core.OauthProviderGoogle,
+		Name:          remoteUserData.Name,
+		Email:         remoteUserData.Email,
+		EmailVerified: remoteUserData.VerifiedEmail != nil && *remoteUserData.VerifiedEmail,
+		Identifier:    remoteUserData.Email,
 	}
 	return &userData, nil
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/awanku/awanku/internal/coreapi/appctx"
+	"github.com/awanku/awanku/pkg/mailer"
 	"github.com/go-pg/pg/v9"
 )
 
@@ -16,6 +17,7 @@ var ormKey key = "testutil_orm"
 func Context() (context.Context, func()) {
 	db, closeDB := DBCluster()
 	ctx := context.WithValue(context.Background(), appctx.KeyDatabase, db)
+	ctx = context.WithValue(ctx, appctx.KeyMailer, &mailer.MemoryMailer{})
 
 	opts, err := pg.ParseURL(os.Getenv("DATABASE_URL"))
 	if err != nil {
@@ -38,3 +40,12 @@ func orm(ctx context.Context) *pg.DB {
 	}
 	return nil
 }
+
+// Mailer returns in-memory mailer injected by Context
+func Mailer(ctx context.Context) *mailer.MemoryMailer {
+	raw := ctx.Value(appctx.KeyMailer)
+	if val, ok := raw.(*mailer.MemoryMailer); ok {
+		return val
+	}
+	return nil
+}
@@ -48,12 +48,112 @@ func OauthTokenFactory(ctx context.Context, userID int64, secretKey string) *cor
 	token.RequesterIP = "127.0.0.1"
 	token.RequesterUserAgent = "testing"
 	_, err = orm(ctx).Query(token, `
-        insert into oauth_tokens (user_id, access_token_hash, refresh_token_hash, expires_at, requester_ip, requester_user_agent)
-        values (?, ?, ?, ?, ?, ?)
+        insert into oauth_tokens (user_id, access_token_hash, refresh_token_hash, expires_at, refresh_expires_at, requester_ip, requester_user_agent)
+        values (?, ?, ?, ?, ?, ?, ?)
         returning id
-    `, token.UserID, token.AccessTokenHash, token.RefreshTokenHash, token.ExpiresAt, token.RequesterIP, token.RequesterUserAgent)
+    `, token.UserID, token.AccessTokenHash, token.RefreshTokenHash, token.ExpiresAt, token.RefreshExpiresAt, token.RequesterIP, token.RequesterUserAgent)
 	if err != nil {
 		panic(err)
 	}
 	return token
 }
+
+func WorkspaceFactory(ctx context.Context, n int) []*core.Workspace {
+	workspaces := []*core.Workspace{}
+	for i := 0; i < n; i++ {
+		workspace := &core.Workspace{
+			Name: faker.Word() + " " + faker.Word(),
+			Slug: "workspace-" + faker.UUIDDigit(),
+		}
+		if err := orm(ctx).Insert(workspace); err != nil {
+			panic(err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces
+}
+
+func WorkspaceUserFactory(ctx context.Context, workspaceID, userID int64, accessLevel string) {
+	_, err := orm(ctx).Exec(`
+        insert into workspace_users (workspace_id, user_id, access_level)
+        values (?, ?, ?)
+    `, workspaceID, userID, accessLevel)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func WorkspaceInvitationFactory(ctx context.Context, workspaceID, inviterID int64, email string) *core.WorkspaceInvitation {
+	invitation := &core.WorkspaceInvitation{
+		WorkspaceID:   workspaceID,
+		InviterUserID: inviterID,
+		Email:         email,
+		AccessLevel:   core.WorkspaceAccessLevelViewer,
+		TokenHash:     []byte(faker.UUIDHyphenated()),
+		Status:        core.WorkspaceInvitationStatusPending,
+		ExpiresAt:     time.Now().Add(core.WorkspaceInvitationMaxDuration),
+	}
+	_, err := orm(ctx).Query(invitation, `
+        insert into workspace_invitations (workspace_id, inviter_user_id, email, access_level, token_hash, expires_at)
+        values (?, ?, ?, ?, ?, ?)
+        returning id, created_at
+    `, invitation.WorkspaceID, invitation.InviterUserID, invitation.Email, invitation.AccessLevel, invitation.TokenHash, invitation.ExpiresAt)
+	if err != nil {
+		panic(err)
+	}
+	return invitation
+}
+
+func ProjectFactory(ctx context.Context, workspaceID int64) *core.Project {
+	project := &core.Project{
+		WorkspaceID: workspaceID,
+		Name:        "project-" + faker.UUIDDigit(),
+	}
+	if err := orm(ctx).Insert(project); err != nil {
+		panic(err)
+	}
+	_, err := orm(ctx).Exec(`
+        insert into project_environments (project_id, name, type, protected)
+        values (?, ?, ?, true)
+    `, project.ID, core.DefaultProjectEnvironmentName, core.ProjectEnvironmentTypeProduction)
+	if err != nil {
+		panic(err)
+	}
+	return project
+}
+
+func ProjectEnvironmentFactory(ctx context.Context, projectID int64, environmentType string) *core.ProjectEnvironment {
+	environment := &core.ProjectEnvironment{}
+	_, err := orm(ctx).QueryOne(environment, `
+        insert into project_environments (project_id, name, type)
+        values (?, ?, ?)
+        returning *
+    `, projectID, environmentType+"-"+faker.UUIDDigit(), environmentType)
+	if err != nil {
+		panic(err)
+	}
+	return environment
+}
+
+func ProjectUserFactory(ctx context.Context, projectID, userID int64, accessLevel string) {
+	_, err := orm(ctx).Exec(`
+        insert into project_users (project_id, user_id, access_level)
+        values (?, ?, ?)
+    `, projectID, userID, accessLevel)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func RepositoryConnectionFactory(ctx context.Context, workspaceID int64) *core.RepositoryConnection {
+	conn := &core.RepositoryConnection{}
+	_, err := orm(ctx).QueryOne(conn, `
+        insert into workspace_repository_connections (workspace_id, identifier, provider, payload)
+        values (?, ?, 'github-v1', '{"installation_id": "1"}')
+        returning *
+    `, workspaceID, "https://github.com/"+faker.Username())
+	if err != nil {
+		panic(err)
+	}
+	return conn
+}